@@ -39,15 +39,15 @@ func newAuditCmd(c *config) *cobra.Command {
 	auditDescribeCmd.Flags().String("phase", "response", "phase of the audit trace. One of [request, response, single, error, opened, closed]")
 	auditDescribeCmd.Flags().Bool("prettify-body", false, "attempts to interpret the body as json and prettifies it")
 
-	auditDescribeCmd.Flags().String("from", "1h", "start of range of the audit traces. e.g. 1h, 10m, 2006-01-02 15:04:05")
-	auditDescribeCmd.Flags().String("to", "", "end of range of the audit traces. e.g. 1h, 10m, 2006-01-02 15:04:05")
+	auditDescribeCmd.Flags().String("from", "1h", "start of range of the audit traces. e.g. 1h, 10m, yesterday 18:00, 2006-01-02 15:04:05")
+	auditDescribeCmd.Flags().String("to", "", "end of range of the audit traces. e.g. 1h, 10m, yesterday 18:00, 2006-01-02 15:04:05")
 
 	genericcli.Must(auditDescribeCmd.RegisterFlagCompletionFunc("phase", c.comp.AuditPhaseCompletion))
 
 	auditListCmd.Flags().StringP("query", "q", "", "filters audit trace body payloads for the given text.")
 
-	auditListCmd.Flags().String("from", "1h", "start of range of the audit traces. e.g. 1h, 10m, 2006-01-02 15:04:05")
-	auditListCmd.Flags().String("to", "", "end of range of the audit traces. e.g. 1h, 10m, 2006-01-02 15:04:05")
+	auditListCmd.Flags().String("from", "1h", "start of range of the audit traces. e.g. 1h, 10m, yesterday 18:00, 2006-01-02 15:04:05")
+	auditListCmd.Flags().String("to", "", "end of range of the audit traces. e.g. 1h, 10m, yesterday 18:00, 2006-01-02 15:04:05")
 
 	auditListCmd.Flags().String("component", "", "component of the audit trace.")
 	auditListCmd.Flags().String("request-id", "", "request id of the audit trace.")
@@ -160,13 +160,39 @@ func (c *config) auditDescribe(args []string) error {
 	return c.describePrinter.Print(trace)
 }
 
+// eventuallyRelativeDateTime parses an absolute date time or a time relative to now, which is either
+// a duration pointing to the past like 1h or -1h or a day like today or yesterday 18:00.
 func eventuallyRelativeDateTime(s string) (strfmt.DateTime, error) {
+	return eventuallyRelativeDateTimeFrom(s, time.Now())
+}
+
+func eventuallyRelativeDateTimeFrom(s string, now time.Time) (strfmt.DateTime, error) {
 	if s == "" {
 		return strfmt.DateTime{}, nil
 	}
-	duration, err := strfmt.ParseDuration(s)
+	duration, err := strfmt.ParseDuration(strings.TrimPrefix(s, "-"))
 	if err == nil {
-		return strfmt.DateTime(time.Now().Add(-duration)), nil
+		return strfmt.DateTime(now.Add(-duration)), nil
+	}
+
+	day, clock, _ := strings.Cut(strings.TrimSpace(s), " ")
+	var offset int
+	switch day {
+	case "today":
+	case "yesterday":
+		offset = -1
+	default:
+		return strfmt.ParseDateTime(s)
 	}
-	return strfmt.ParseDateTime(s)
+
+	var hour, minute int
+	if clock != "" {
+		t, err := time.Parse("15:04", clock)
+		if err != nil {
+			return strfmt.DateTime{}, fmt.Errorf("time of %q must be given in the form HH:MM", s)
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+
+	return strfmt.DateTime(time.Date(now.Year(), now.Month(), now.Day()+offset, hour, minute, 0, 0, now.Location())), nil
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_eventuallyRelativeDateTimeFrom(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			s:    "",
+			want: time.Time{},
+		},
+		{
+			name: "duration",
+			s:    "2h",
+			want: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "negative duration",
+			s:    "-2h",
+			want: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "today",
+			s:    "today",
+			want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "yesterday with time across month boundary",
+			s:    "yesterday 18:00",
+			want: time.Date(2024, 2, 29, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "yesterday with invalid time",
+			s:       "yesterday 25:00",
+			wantErr: true,
+		},
+		{
+			name: "absolute",
+			s:    "2024-02-01T12:00:00+01:00",
+			want: time.Date(2024, 2, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid",
+			s:       "tomorrow",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eventuallyRelativeDateTimeFrom(tt.s, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("eventuallyRelativeDateTimeFrom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !time.Time(got).Equal(tt.want) {
+				t.Errorf("eventuallyRelativeDateTimeFrom() = %v, want %v", time.Time(got), tt.want)
+			}
+		})
+	}
+}
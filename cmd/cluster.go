@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -39,8 +40,6 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
-	"github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
-	utiltaints "github.com/gardener/machine-controller-manager/pkg/util/taints"
 )
 
 func emojiHelpText() string {
@@ -65,10 +64,38 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterCreateCmd := &cobra.Command{
 		Use:   "create",
 		Short: "create a cluster",
+		Example: `clone the configuration of an existing cluster into another partition:
+cloudctl cluster export <clusterid> > cluster.yaml
+cloudctl cluster create --from-file cluster.yaml --name staging --partition <partition>`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.clusterCreate()
 		},
 	}
+	clusterExportCmd := &cobra.Command{
+		Use:   "export <clusterid>",
+		Short: "export the configuration of a cluster as create request",
+		Long:  "exports the configuration of a cluster as create request, which can be used with cluster create --from-file or cluster apply. cluster specific fields like the id, the status, the seed and the egress rules are not exported.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterExport(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+	clusterApplyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "create/update a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterApply()
+		},
+	}
+	clusterEditCmd := &cobra.Command{
+		Use:   "edit <clusterid>",
+		Short: "edit a cluster",
+		Long:  "edit the workers, maintenance and auto-update settings, kube-apiserver ACL, egress rules and labels of a cluster in the default editor.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterEdit(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
 	clusterListCmd := &cobra.Command{
 		Use:     "list",
 		Short:   "list clusters",
@@ -104,23 +131,46 @@ func newClusterCmd(c *config) *cobra.Command {
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
+	clusterKubeconfigCmd.AddCommand(newClusterKubeconfigCmds(c)...)
 
 	clusterReconcileCmd := &cobra.Command{
-		Use:   "reconcile <clusterid>",
+		Use:   "reconcile [<clusterid>]",
 		Short: "trigger cluster reconciliation",
+		Long:  "triggers the reconciliation of a cluster. when no cluster id is given, all clusters matching the given filters are reconciled (bulk mode).",
+		Example: `reconcile all evaluation clusters in a partition:
+cloudctl cluster reconcile --partition fra-equ01 --purpose evaluation --parallelism 10`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.reconcileCluster(args)
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
 	clusterUpdateCmd := &cobra.Command{
-		Use:   "update <clusterid>",
+		Use:   "update [<clusterid>]",
 		Short: "update a cluster",
+		Long:  "updates a cluster. when no cluster id is given, all clusters matching the given filters are updated (bulk mode). the purpose filter is called --filter-purpose for this command because --purpose updates the purpose of the cluster.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.updateCluster(args)
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
+	clusterWaitCmd := &cobra.Command{
+		Use:   "wait <clusterid>",
+		Short: "wait until a cluster reaches the given condition",
+		Long:  "polls the cluster until it reaches the given condition and prints the progress of the last operation. exits with an error if the last operation fails or the timeout is reached.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterWaitCmd(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+	clusterUpgradePlanCmd := &cobra.Command{
+		Use:   "upgrade-plan <clusterid>",
+		Short: "shows the next valid upgrade steps of a cluster",
+		Long:  "compares the kubernetes version, the worker machine images, the firewall image and the firewall-controller version of a cluster against the cluster inputs and shows the next valid upgrade steps respecting the kubernetes version skew policy. the steps can be executed one after another with --execute --wait.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterUpgradePlan(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
 	clusterInputsCmd := &cobra.Command{
 		Use:   "inputs",
 		Short: "get possible cluster inputs like k8s versions, etc.",
@@ -163,10 +213,18 @@ func newClusterCmd(c *config) *cobra.Command {
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
 	clusterMachineSSHCmd := &cobra.Command{
-		Use:   "ssh <clusterid>",
+		Use:   "ssh <clusterid> [-- <command>]",
 		Short: "ssh access a machine/firewall of the cluster",
+		Long:  "ssh access a machine/firewall of the cluster. worker nodes are reached by hopping through a firewall of the cluster, which is accessed through the vpn if available. if a command is given, it is run non-interactively and cloudctl exits with its result.",
+		Example: `run a command on a worker node:
+cloudctl cluster machine ssh <clusterid> --machineid <machineid> -- uptime`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return c.clusterMachineSSH(args, false)
+			var command []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				command = args[dash:]
+				args = args[:dash]
+			}
+			return c.clusterMachineSSH(args, false, command)
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
@@ -174,7 +232,7 @@ func newClusterCmd(c *config) *cobra.Command {
 		Use:   "console <clusterid>",
 		Short: "console access a machine/firewall of the cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return c.clusterMachineSSH(args, true)
+			return c.clusterMachineSSH(args, true, nil)
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
@@ -187,8 +245,9 @@ func newClusterCmd(c *config) *cobra.Command {
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
 	clusterMachineCycleCmd := &cobra.Command{
-		Use:   "cycle <clusterid>",
+		Use:   "cycle [<clusterid>]",
 		Short: "soft power cycle of a machine/firewall of the cluster",
+		Long:  "soft power cycles a machine or firewall of a cluster. when no cluster id is given, all worker machines of the clusters matching the given filters are cycled (bulk mode).",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.clusterMachineCycle(args)
 		},
@@ -219,10 +278,11 @@ func newClusterCmd(c *config) *cobra.Command {
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 	}
 
-	clusterCreateCmd.Flags().String("name", "", "name of the cluster, max 10 characters. [required]")
+	clusterCreateCmd.Flags().String("name", "", "name of the cluster, max 10 characters. [required unless --from-file is given]")
 	clusterCreateCmd.Flags().String("description", "", "description of the cluster. [optional]")
-	clusterCreateCmd.Flags().String("project", "", "project where this cluster should belong to. [required]")
-	clusterCreateCmd.Flags().String("partition", "", "partition of the cluster. [required]")
+	clusterCreateCmd.Flags().String("project", "", "project where this cluster should belong to. [required unless --from-file is given]")
+	clusterCreateCmd.Flags().String("partition", "", "partition of the cluster. [required unless --from-file is given]")
+	clusterCreateCmd.Flags().String("from-file", "", "create the cluster from a create request file, e.g. from cluster export. only --name, --description, --project, --partition, --seed and --purpose are applied on top of the file. [optional]")
 	clusterCreateCmd.Flags().String("seed", "", "name of seed where this cluster should be scheduled. [optional]")
 	clusterCreateCmd.Flags().String("purpose", "evaluation", fmt.Sprintf("purpose of the cluster, can be one of %s. SLA is only given on production clusters. [optional]", strings.Join(completion.ClusterPurposes, "|")))
 	clusterCreateCmd.Flags().String("version", "", "kubernetes version of the cluster. defaults to latest available, check cluster inputs for possible values. [optional]")
@@ -266,9 +326,9 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterCreateCmd.Flags().Duration("service-account-max-token-expiration", 0, "sets the max token expiration duration for projected service account tokens")
 	clusterCreateCmd.Flags().Int64("kubelet-pod-pid-limit", 0, "controls the maximum number of process IDs per pod allowed by the kubelet")
 
-	genericcli.Must(clusterCreateCmd.MarkFlagRequired("name"))
-	genericcli.Must(clusterCreateCmd.MarkFlagRequired("project"))
-	genericcli.Must(clusterCreateCmd.MarkFlagRequired("partition"))
+	clusterCreateCmd.Flags().Bool("wait", false, "waits until the cluster is ready")
+	clusterCreateCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum duration to wait for the cluster when --wait is given")
+
 	genericcli.Must(clusterCreateCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
 	genericcli.Must(clusterCreateCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
 	genericcli.Must(clusterCreateCmd.RegisterFlagCompletionFunc("seed", c.comp.SeedListCompletion))
@@ -297,6 +357,36 @@ func newClusterCmd(c *config) *cobra.Command {
 
 	clusterDescribeCmd.Flags().Bool("no-machines", false, "does not return in the output")
 
+	// Cluster apply --------------------------------------------------------------------
+	clusterApplyCmd.Flags().StringP("file", "f", "", `filename of the create or update request in yaml format, or - for stdin.
+	A document containing an id is treated as an update request, otherwise the document is treated as a create request
+	and the cluster is looked up by projectid and name. Existing clusters are updated with the fields that differ only.
+	Example cluster create or update:
+
+	# cat cluster1.yaml
+	name: cluster1
+	projectid: 2c7ad9c5-ad91-4d6f-a76a-9b2c1e2d4f10
+	partitionid: partition-a
+	purpose: production
+	kubernetes:
+	  version: 1.32.5
+	workers:
+	- name: group-0
+	  machinetype: c1-xlarge-x86
+	  minimum: 2
+	  maximum: 4
+	## show what would be changed
+	# cloudctl cluster apply -f cluster1.yaml --dry-run
+	## either via stdin
+	# cat cluster1.yaml | cloudctl cluster apply -f -
+	## or via file
+	# cloudctl cluster apply -f cluster1.yaml
+	`)
+	clusterApplyCmd.Flags().Bool("dry-run", false, "only prints the field-level diff between the current and the desired state of the clusters without applying it")
+	genericcli.Must(clusterApplyCmd.MarkFlagRequired("file"))
+
+	clusterExportCmd.Flags().StringP("file", "f", "", "write the create request to this file instead of stdout. [optional]")
+
 	// Cluster list --------------------------------------------------------------------
 	clusterListCmd.Flags().String("id", "", "show clusters of given id")
 	clusterListCmd.Flags().String("name", "", "show clusters of given name")
@@ -365,6 +455,10 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterUpdateCmd.Flags().Int64("kubelet-pod-pid-limit", 0, "controls the maximum number of process IDs per pod allowed by the kubelet")
 	clusterUpdateCmd.Flags().Bool("enable-calico-ebpf", false, "enables calico cni to use eBPF data plane and DSR configuration, for increased performance and preserving source IP addresses. [optional]")
 
+	clusterUpdateCmd.Flags().Bool("wait", false, "waits until the update operation of the cluster succeeded")
+	clusterUpdateCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum duration to wait for the cluster when --wait is given")
+	c.addClusterBulkFlags(clusterUpdateCmd, "filter-purpose")
+
 	genericcli.Must(clusterUpdateCmd.RegisterFlagCompletionFunc("version", c.comp.VersionListCompletion))
 	genericcli.Must(clusterUpdateCmd.RegisterFlagCompletionFunc("workerversion", c.comp.VersionListCompletion))
 	genericcli.Must(clusterUpdateCmd.RegisterFlagCompletionFunc("firewalltype", c.comp.FirewallTypeListCompletion))
@@ -377,6 +471,12 @@ func newClusterCmd(c *config) *cobra.Command {
 	genericcli.Must(clusterUpdateCmd.RegisterFlagCompletionFunc("default-pod-security-standard", c.comp.PodSecurityListCompletion))
 	genericcli.Must(clusterUpdateCmd.RegisterFlagCompletionFunc("default-storage-class", c.comp.ClusterStorageClassListCompletion))
 
+	clusterUpgradePlanCmd.Flags().Int("expires-within", 14, "flags versions which expire within the given amount of days")
+	clusterUpgradePlanCmd.Flags().Bool("execute", false, "executes the upgrade steps of the plan one after another")
+	clusterUpgradePlanCmd.Flags().IntSlice("steps", nil, "the upgrade steps to execute, defaults to all steps")
+	clusterUpgradePlanCmd.Flags().Bool("wait", false, "waits for each upgrade step to succeed before executing the next one")
+	clusterUpgradePlanCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum duration to wait for each upgrade step when --wait is given")
+
 	clusterInputsCmd.Flags().String("partition", "", "partition of the constraints.")
 	genericcli.Must(clusterInputsCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
 
@@ -394,10 +494,13 @@ func newClusterCmd(c *config) *cobra.Command {
 	// Cluster machine ... --------------------------------------------------------------------
 	clusterMachineSSHCmd.Flags().String("machineid", "", "machine to connect to.")
 	clusterMachineSSHCmd.Flags().String("reason", "", "a short description why ssh access is required")
+	clusterMachineSSHCmd.Flags().String("record", "", "record the session in asciinema format into this directory, defaults to the record_dir of the current context.")
 	genericcli.Must(clusterMachineSSHCmd.MarkFlagRequired("machineid"))
-	genericcli.Must(clusterMachineSSHCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterFirewallListCompletion))
+	genericcli.Must(clusterMachineSSHCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
 
 	clusterMachineConsoleCmd.Flags().String("machineid", "", "machine to connect to.")
+	clusterMachineConsoleCmd.Flags().String("reason", "", "a short description why console access is required")
+	clusterMachineConsoleCmd.Flags().String("record", "", "record the session in asciinema format into this directory, defaults to the record_dir of the current context.")
 	genericcli.Must(clusterMachineConsoleCmd.MarkFlagRequired("machineid"))
 	genericcli.Must(clusterMachineConsoleCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
 
@@ -405,9 +508,11 @@ func newClusterCmd(c *config) *cobra.Command {
 	genericcli.Must(clusterMachineResetCmd.MarkFlagRequired("machineid"))
 	genericcli.Must(clusterMachineResetCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
 
-	clusterMachineCycleCmd.Flags().String("machineid", "", "machine to reset.")
-	genericcli.Must(clusterMachineCycleCmd.MarkFlagRequired("machineid"))
+	clusterMachineCycleCmd.Flags().String("machineid", "", "machine to reset, required when not running in bulk mode.")
 	genericcli.Must(clusterMachineCycleCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
+	clusterMachineCycleCmd.Flags().Bool("all-machines", false, "bulk mode: required to cycle all worker machines of the selected clusters. machines are cycled one after another per cluster, each has to join the cluster again before the next one is cycled.")
+	clusterMachineCycleCmd.Flags().Duration("machine-timeout", clusterMachineRejoinTimeoutDefault, "bulk mode: maximum duration to wait for a cycled machine to join the cluster again")
+	c.addClusterBulkFlags(clusterMachineCycleCmd, "purpose")
 
 	clusterMachinePackagesCmd.Flags().String("machineid", "", "machine to connect to.")
 	genericcli.Must(clusterMachinePackagesCmd.MarkFlagRequired("machineid"))
@@ -419,9 +524,20 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterMachineCmd.AddCommand(clusterMachineResetCmd)
 	clusterMachineCmd.AddCommand(clusterMachineCycleCmd)
 	clusterMachineCmd.AddCommand(clusterMachinePackagesCmd)
+	clusterMachineCmd.AddCommand(newClusterMachineExecCmd(c))
 
 	clusterReconcileCmd.Flags().String("operation", models.V1ClusterReconcileRequestOperationReconcile, fmt.Sprintf("Executes a cluster \"reconcile\" operation, can be one of %s.", strings.Join(completion.ClusterReconcileOperations, "|")))
 	genericcli.Must(clusterReconcileCmd.RegisterFlagCompletionFunc("operation", c.comp.ClusterReconcileOperationCompletion))
+	clusterReconcileCmd.Flags().Bool("wait", false, "waits until the triggered operation of the cluster succeeded")
+	clusterReconcileCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum duration to wait for the cluster when --wait is given")
+	c.addClusterBulkFlags(clusterReconcileCmd, "purpose")
+
+	clusterDeleteCmd.Flags().Bool("wait", false, "waits until the cluster is deleted")
+	clusterDeleteCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum duration to wait for the cluster when --wait is given")
+
+	clusterWaitCmd.Flags().String("for", clusterWaitForReady, fmt.Sprintf("the condition to wait for, can be one of %s|%s|%s", clusterWaitForReady, clusterWaitForDeleted, clusterWaitForOperationSucceeded))
+	clusterWaitCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum duration to wait for the condition")
+	genericcli.Must(clusterWaitCmd.RegisterFlagCompletionFunc("for", cobra.FixedCompletions(clusterWaitConditions, cobra.ShellCompDirectiveNoFileComp)))
 
 	clusterIssuesCmd.Flags().String("id", "", "show clusters of given id")
 	clusterIssuesCmd.Flags().String("name", "", "show clusters of given name")
@@ -436,14 +552,20 @@ func newClusterCmd(c *config) *cobra.Command {
 
 	clusterKubeconfigCmd.Flags().Bool("merge", false, "merges the cluster's kubeconfig into the current active kubeconfig, otherwise an individual kubeconfig is printed to console only")
 	clusterKubeconfigCmd.Flags().Bool("set-context", false, "when setting the merge parameter to true, immediately activates the cluster's context")
+	clusterKubeconfigCmd.Flags().Bool("exec-plugin", false, "use \"cloudctl exec-credential\" to obtain a fresh token instead of embedding the current token, which expires")
 
 	clusterCmd.AddCommand(clusterCreateCmd)
+	clusterCmd.AddCommand(clusterApplyCmd)
+	clusterCmd.AddCommand(clusterExportCmd)
+	clusterCmd.AddCommand(clusterEditCmd)
 	clusterCmd.AddCommand(clusterListCmd)
 	clusterCmd.AddCommand(clusterKubeconfigCmd)
 	clusterCmd.AddCommand(clusterDeleteCmd)
 	clusterCmd.AddCommand(clusterDescribeCmd)
 	clusterCmd.AddCommand(clusterInputsCmd)
 	clusterCmd.AddCommand(clusterReconcileCmd)
+	clusterCmd.AddCommand(clusterWaitCmd)
+	clusterCmd.AddCommand(clusterUpgradePlanCmd)
 	clusterCmd.AddCommand(clusterUpdateCmd)
 	clusterCmd.AddCommand(clusterMachineCmd)
 	clusterCmd.AddCommand(clusterLogsCmd)
@@ -452,11 +574,18 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterCmd.AddCommand(clusterMonitoringSecretCmd)
 	clusterCmd.AddCommand(newClusterAuditCmd(c))
 	clusterCmd.AddCommand(newClusterXdrCmd(c))
+	clusterCmd.AddCommand(newClusterWorkerGroupCmd(c))
+	clusterCmd.AddCommand(newClusterReportCmd(c))
+	clusterCmd.AddCommand(newClusterFirewallCmd(c))
 
 	return clusterCmd
 }
 
 func (c *config) clusterCreate() error {
+	if viper.IsSet("from-file") {
+		return c.clusterCreateFromFile()
+	}
+
 	name := viper.GetString("name")
 	desc := viper.GetString("description")
 	partition := viper.GetString("partition")
@@ -478,6 +607,10 @@ func (c *config) clusterCreate() error {
 	podpidLimit := viper.GetInt64("kubelet-pod-pid-limit")
 	calicoEbpf := strconv.FormatBool(viper.GetBool("enable-calico-ebpf"))
 
+	if name == "" || project == "" || partition == "" {
+		return fmt.Errorf("--name, --project and --partition are required when not creating from file")
+	}
+
 	var cni string
 	if viper.IsSet("cni") {
 		cni = viper.GetString("cni")
@@ -788,6 +921,9 @@ WARNING: You are going to create a cluster that has no default internet access w
 	if err != nil {
 		return err
 	}
+	if viper.GetBool("wait") {
+		return c.clusterWaitAndDescribe(*shoot.Payload.ID, clusterWaitForReady, time.Time{})
+	}
 	return c.describePrinter.Print(shoot.Payload)
 }
 
@@ -874,6 +1010,21 @@ func (c *config) clusterKubeconfig(args []string) error {
 			return err
 		}
 
+		if viper.GetBool("exec-plugin") {
+			contexts, err := helper.KubeconfigContexts(mergedKubeconfig)
+			if err != nil {
+				return err
+			}
+			plugin, err := clusterKubeconfigExecPlugin()
+			if err != nil {
+				return err
+			}
+			mergedKubeconfig, err = helper.SetKubeconfigExecUser(mergedKubeconfig, contexts[0].Name, authContext.User, plugin)
+			if err != nil {
+				return err
+			}
+		}
+
 		fmt.Println(string(mergedKubeconfig))
 		return nil
 	}
@@ -899,6 +1050,26 @@ func (c *config) clusterKubeconfig(args []string) error {
 		return err
 	}
 
+	if viper.GetBool("exec-plugin") {
+		plugin, err := clusterKubeconfigExecPlugin()
+		if err != nil {
+			return err
+		}
+		// the oidc user is still required by cloudctl, so the plugin gets a user of its own
+		mergedKubeconfig, err = helper.SetKubeconfigExecUser(mergedKubeconfig, contextName, authContext.User+"-exec", plugin)
+		if err != nil {
+			return err
+		}
+	}
+
+	// track the context such that it can be pruned when the cluster is gone
+	mergedKubeconfig, err = helper.SetKubeconfigContextExtensions(mergedKubeconfig, map[string]helper.KubeconfigContextExtension{
+		contextName: {ClusterID: id, ProjectID: pointer.SafeDeref(clusterResp.Payload.ProjectID)},
+	})
+	if err != nil {
+		return err
+	}
+
 	err = os.WriteFile(filename, mergedKubeconfig, 0600)
 	if err != nil {
 		return err
@@ -941,6 +1112,42 @@ func (c *config) sshKeyPair(clusterID string, omitVPN bool, reason *string) (*ss
 }
 
 func (c *config) reconcileCluster(args []string) error {
+	operation := viper.GetString("operation")
+
+	cfr, err := clusterBulkFindRequest("purpose")
+	if err != nil {
+		return err
+	}
+	if cfr != nil && len(args) == 0 {
+		targets, err := c.clusterBulkTargets(cfr, false)
+		if err != nil {
+			return err
+		}
+		err = c.clusterBulkConfirm(operation, targets)
+		if err != nil {
+			return err
+		}
+		return c.clusterBulk(targets, func(ctx context.Context, target *models.V1ClusterResponse) (string, error) {
+			request := cluster.NewReconcileClusterParams().WithContext(ctx)
+			request.SetID(*target.ID)
+			request.Body = &models.V1ClusterReconcileRequest{Operation: &operation}
+
+			since := time.Now()
+			_, err := c.cloud.Cluster.ReconcileCluster(request, nil)
+			if err != nil {
+				return "", err
+			}
+			if viper.GetBool("wait") {
+				_, err = c.clusterWait(*target.ID, clusterWaitForOperationSucceeded, since, viper.GetDuration("timeout"))
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s succeeded", operation), nil
+			}
+			return fmt.Sprintf("%s triggered", operation), nil
+		})
+	}
+
 	ci, err := c.clusterID("reconcile", args)
 	if err != nil {
 		return err
@@ -948,23 +1155,106 @@ func (c *config) reconcileCluster(args []string) error {
 
 	request := cluster.NewReconcileClusterParams()
 	request.SetID(ci)
-
-	operation := viper.GetString("operation")
 	request.Body = &models.V1ClusterReconcileRequest{Operation: &operation}
 
+	since := time.Now()
 	shoot, err := c.cloud.Cluster.ReconcileCluster(request, nil)
 	if err != nil {
 		return err
 	}
+	if viper.GetBool("wait") {
+		return c.clusterWaitAndDescribe(ci, clusterWaitForOperationSucceeded, since)
+	}
 	return c.describePrinter.Print(shoot.Payload)
 }
 
 func (c *config) updateCluster(args []string) error {
+	cfr, err := clusterBulkFindRequest("filter-purpose")
+	if err != nil {
+		return err
+	}
+	if cfr != nil && len(args) == 0 {
+		return c.updateClusters(cfr)
+	}
+
 	ci, err := c.clusterID("update", args)
 	if err != nil {
 		return err
 	}
 
+	findRequest := cluster.NewFindClusterParams()
+	findRequest.SetID(ci)
+	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return err
+	}
+	current := resp.Payload
+
+	cur, err := c.clusterUpdateRequest(current)
+	if err != nil {
+		return err
+	}
+
+	request := cluster.NewUpdateClusterParams()
+	request.SetBody(cur)
+	since := time.Now()
+	shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
+	if err != nil {
+		return err
+	}
+	if viper.GetBool("wait") {
+		return c.clusterWaitAndDescribe(ci, clusterWaitForOperationSucceeded, since)
+	}
+	return c.describePrinter.Print(shoot.Payload)
+}
+
+// updateClusters updates all clusters matching the given find request.
+// the update requests are built (and confirmed) for every cluster upfront, such that no cluster gets updated in case of an error.
+func (c *config) updateClusters(cfr *models.V1ClusterFindRequest) error {
+	targets, err := c.clusterBulkTargets(cfr, true)
+	if err != nil {
+		return err
+	}
+
+	requests := map[string]*models.V1ClusterUpdateRequest{}
+	for _, target := range targets {
+		cur, err := c.clusterUpdateRequest(target)
+		if err != nil {
+			return fmt.Errorf("unable to update cluster %q: %w", pointer.SafeDeref(target.Name), err)
+		}
+		requests[*target.ID] = cur
+	}
+
+	err = c.clusterBulkConfirm("update", targets)
+	if err != nil {
+		return err
+	}
+
+	return c.clusterBulk(targets, func(ctx context.Context, target *models.V1ClusterResponse) (string, error) {
+		request := cluster.NewUpdateClusterParams().WithContext(ctx)
+		request.SetBody(requests[*target.ID])
+
+		since := time.Now()
+		_, err := c.cloud.Cluster.UpdateCluster(request, nil)
+		if err != nil {
+			return "", err
+		}
+		if viper.GetBool("wait") {
+			_, err = c.clusterWait(*target.ID, clusterWaitForOperationSucceeded, since, viper.GetDuration("timeout"))
+			if err != nil {
+				return "", err
+			}
+			return "update succeeded", nil
+		}
+		return "update triggered", nil
+	})
+}
+
+// clusterUpdateRequest builds the update request for the given cluster from the update flags.
+// depending on the flags, the user is asked for confirmation.
+func (c *config) clusterUpdateRequest(current *models.V1ClusterResponse) (*models.V1ClusterUpdateRequest, error) {
+	ci := pointer.SafeDeref(current.ID)
+
 	workergroupname := viper.GetString("workergroup")
 	removeworkergroup := viper.GetBool("remove-workergroup")
 	workerlabelslice := viper.GetStringSlice("workerlabels")
@@ -1004,33 +1294,16 @@ func (c *config) updateCluster(args []string) error {
 
 	workerlabels, err := helper.LabelsToMap(workerlabelslice)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	workerannotations, err := helper.LabelsToMap(workerannotationsslice)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	coreworkertaints, _, err := utiltaints.ParseTaints(workertaintsslice)
+	workertaints, err := parseWorkerTaints(workertaintsslice)
 	if err != nil {
-		return fmt.Errorf("specified taints are invalid: %w", err)
-	}
-
-	var workertaints []*models.V1Taint
-	for _, t := range coreworkertaints {
-		workertaints = append(workertaints, &models.V1Taint{
-			Key:    &t.Key,
-			Value:  t.Value,
-			Effect: (*string)(&t.Effect),
-		})
-	}
-
-	findRequest := cluster.NewFindClusterParams()
-	findRequest.SetID(ci)
-	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	current := resp.Payload
 
 	healthtimeout := viper.GetDuration("healthtimeout")
 	draintimeout := viper.GetDuration("draintimeout")
@@ -1039,7 +1312,7 @@ func (c *config) updateCluster(args []string) error {
 
 	customDefaultStorageClass := current.CustomDefaultStorageClass
 	if viper.IsSet("default-storage-class") && disableDefaultStorageClass {
-		return fmt.Errorf("either default-storage-class or disable-custom-default-storage-class may be specified, not both")
+		return nil, fmt.Errorf("either default-storage-class or disable-custom-default-storage-class may be specified, not both")
 	}
 
 	if disableDefaultStorageClass {
@@ -1066,7 +1339,7 @@ func (c *config) updateCluster(args []string) error {
 				ShowAnswers: true,
 				Out:         c.out,
 			}); err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -1082,7 +1355,7 @@ func (c *config) updateCluster(args []string) error {
 				ShowAnswers: true,
 				Out:         c.out,
 			}); err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -1094,7 +1367,6 @@ func (c *config) updateCluster(args []string) error {
 
 	workergroupKubernetesVersion := viper.GetString("workerversion")
 
-	request := cluster.NewUpdateClusterParams()
 	cur := &models.V1ClusterUpdateRequest{
 		ID: &ci,
 		Maintenance: &models.V1Maintenance{
@@ -1128,7 +1400,7 @@ func (c *config) updateCluster(args []string) error {
 				fmt.Printf("Adding a new worker group to cluster:%q. Please note that running multiple worker groups leads to higher basic costs of the cluster!\n", *current.Name)
 				err = helper.Prompt("Are you sure? (y/n)", "y")
 				if err != nil {
-					return err
+					return nil, err
 				}
 
 				worker = &models.V1Worker{
@@ -1147,18 +1419,18 @@ func (c *config) updateCluster(args []string) error {
 		} else if len(workers) == 1 {
 			worker = workers[0]
 		} else {
-			return fmt.Errorf("there are multiple worker groups, please specify the worker group you want to update with --workergroup")
+			return nil, fmt.Errorf("there are multiple worker groups, please specify the worker group you want to update with --workergroup")
 		}
 
 		if removeworkergroup {
 			if worker == nil {
-				return fmt.Errorf("worker group %s not found", workergroupname)
+				return nil, fmt.Errorf("worker group %s not found", workergroupname)
 			}
 
 			fmt.Printf("WARNING. Removing a worker group from cluster:%q cannot be undone and causes the loss of local data on the deleted nodes.\n", *current.Name)
 			err = helper.Prompt("Are you sure? (y/n)", "y")
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			var newWorkers []*models.V1Worker
@@ -1175,19 +1447,11 @@ func (c *config) updateCluster(args []string) error {
 				worker.Minimum = &minsize
 			}
 			if maxsize != 0 {
-				c := 0
-				for _, m := range current.Machines {
-					for _, t := range m.Tags {
-						if t == fmt.Sprintf("%s=%s", string(constants.LabelWorkerPool), *worker.Name) {
-							c++
-						}
-					}
-				}
-				if int(maxsize) < c {
+				if int(maxsize) < workerGroupMachineCount(current, *worker.Name) {
 					fmt.Printf("WARNING. New maxsize of cluster:%q is lower than currently active machines. A random worker node which is still in use will be removed.\n", *current.Name)
 					err = helper.Prompt("Are you sure? (y/n)", "y")
 					if err != nil {
-						return err
+						return nil, err
 					}
 				}
 				worker.Maximum = &maxsize
@@ -1236,7 +1500,7 @@ func (c *config) updateCluster(args []string) error {
 					fmt.Printf("WARNING. Removing the worker version override of cluster:%q may update your worker nodes to the version of the api server.\n", *current.Name)
 					err = helper.Prompt("Are you sure? (y/n)", "y")
 					if err != nil {
-						return err
+						return nil, err
 					}
 				}
 				worker.KubernetesVersion = new(workergroupKubernetesVersion)
@@ -1325,7 +1589,7 @@ func (c *config) updateCluster(args []string) error {
 
 		if viper.IsSet("enable-kube-apiserver-acl") {
 			if !viper.GetBool("yes-i-really-mean-it") {
-				return fmt.Errorf("--enable-kube-apiserver-acl is set but you forgot to add --yes-i-really-mean-it")
+				return nil, fmt.Errorf("--enable-kube-apiserver-acl is set but you forgot to add --yes-i-really-mean-it")
 			}
 			newACL.Disabled = new(!viper.GetBool("enable-kube-apiserver-acl"))
 		}
@@ -1334,7 +1598,7 @@ func (c *config) updateCluster(args []string) error {
 			fmt.Printf("WARNING: Restricting access of cluster:%q to the kube-apiserver prevents FI-TS operators from helping you in case of any issues in your cluster.\n", *current.Name)
 			err = helper.Prompt("Are you sure? (y/n)", "y")
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -1390,14 +1654,14 @@ func (c *config) updateCluster(args []string) error {
 	}
 	if viper.IsSet("default-pod-security-standard") {
 		if !viper.GetBool("yes-i-really-mean-it") {
-			return fmt.Errorf("--default-pod-security-standard is set but you forgot to add --yes-i-really-mean-it")
+			return nil, fmt.Errorf("--default-pod-security-standard is set but you forgot to add --yes-i-really-mean-it")
 		}
 		k8s.DefaultPodSecurityStandard = new(viper.GetString("default-pod-security-standard"))
 	}
 
 	if viper.IsSet("kubelet-pod-pid-limit") {
 		if !viper.GetBool("yes-i-really-mean-it") {
-			return fmt.Errorf("--kubelet-pod-pid-limit can only be changed in combination with --yes-i-really-mean-it because this change can lead to pods not starting anymore in the cluster")
+			return nil, fmt.Errorf("--kubelet-pod-pid-limit can only be changed in combination with --yes-i-really-mean-it because this change can lead to pods not starting anymore in the cluster")
 		}
 		k8s.PodPIDsLimit = &podpidLimit
 	}
@@ -1414,7 +1678,7 @@ func (c *config) updateCluster(args []string) error {
 
 	if viper.IsSet("enable-node-local-dns") {
 		if !viper.GetBool("yes-i-really-mean-it") {
-			return fmt.Errorf("setting --enable-node-local-dns will lead to rolling of worker nodes. Please add --yes-i-really-mean-it")
+			return nil, fmt.Errorf("setting --enable-node-local-dns will lead to rolling of worker nodes. Please add --yes-i-really-mean-it")
 		}
 
 		if cur.SystemComponents == nil {
@@ -1440,16 +1704,11 @@ func (c *config) updateCluster(args []string) error {
 		fmt.Printf("This update of cluster:%q will cause downtime.\n", *current.Name)
 		err = helper.Prompt("Are you sure? (y/n)", "y")
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	request.SetBody(cur)
-	shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
-	if err != nil {
-		return err
-	}
-	return c.describePrinter.Print(shoot.Payload)
+	return cur, nil
 }
 
 func (c *config) clusterDelete(args []string) error {
@@ -1487,6 +1746,9 @@ func (c *config) clusterDelete(args []string) error {
 	if err != nil {
 		return err
 	}
+	if viper.GetBool("wait") {
+		return c.clusterWaitAndDescribe(ci, clusterWaitForDeleted, time.Time{})
+	}
 	return c.describePrinter.Print(cl.Payload)
 }
 
@@ -1803,11 +2065,43 @@ func (c *config) clusterMachineReset(args []string) error {
 }
 
 func (c *config) clusterMachineCycle(args []string) error {
+	cfr, err := clusterBulkFindRequest("purpose")
+	if err != nil {
+		return err
+	}
+	if cfr != nil && len(args) == 0 {
+		if !viper.GetBool("all-machines") {
+			return fmt.Errorf("bulk mode cycles every worker machine of the selected clusters, pass --all-machines to confirm this or give a cluster id and --machineid")
+		}
+		targets, err := c.clusterBulkTargets(cfr, true)
+		if err != nil {
+			return err
+		}
+		err = c.clusterBulkConfirm("cycle all worker machines, one machine per cluster at a time, of", targets)
+		if err != nil {
+			return err
+		}
+		if !viper.GetBool("yes-i-really-mean-it") {
+			machines := 0
+			for _, target := range targets {
+				machines += len(target.Machines)
+			}
+			err = helper.Prompt(fmt.Sprintf("Please type the number of machines to cycle (%d) to continue:", machines), strconv.Itoa(machines))
+			if err != nil {
+				return err
+			}
+		}
+		return c.clusterBulk(targets, c.clusterCycleMachinesRolling)
+	}
+
 	cid, err := c.clusterID("reset", args)
 	if err != nil {
 		return err
 	}
 	mid := viper.GetString("machineid")
+	if mid == "" {
+		return fmt.Errorf("--machineid is required")
+	}
 
 	request := cluster.NewCycleMachineParams()
 	request.SetID(cid)
@@ -1892,7 +2186,7 @@ func (c *config) clusterMonitoringSecret(args []string) error {
 	return c.describePrinter.Print(secret.Payload)
 }
 
-func (c *config) clusterMachineSSH(args []string, console bool) error {
+func (c *config) clusterMachineSSH(args []string, console bool, command []string) error {
 	cid, err := c.clusterID("ssh", args)
 	if err != nil {
 		return err
@@ -1916,6 +2210,23 @@ func (c *config) clusterMachineSSH(args []string, console bool) error {
 		if *m.ID != mid {
 			continue
 		}
+
+		access := "ssh"
+		if console {
+			access = "console"
+		}
+		recorder, err := sessionRecorder(access, cid, mid, viper.GetString("reason"))
+		if err != nil {
+			return fmt.Errorf("unable to record session: %w", err)
+		}
+		var record io.Writer
+		if recorder != nil {
+			record = recorder
+			defer func() {
+				_ = recorder.Close()
+			}()
+		}
+
 		if console {
 			fmt.Printf("access console via ssh\n")
 			authContext, err := api.GetAuthContext(viper.GetString("kubeconfig"))
@@ -1923,36 +2234,37 @@ func (c *config) clusterMachineSSH(args []string, console bool) error {
 				return err
 			}
 			bmcConsolePort := 5222
-			err = c.sshClient(mid, c.consoleHost, keypair.privatekey, bmcConsolePort, &authContext.IDToken)
+			err = c.sshClient(mid, c.consoleHost, keypair.privatekey, bmcConsolePort, &authContext.IDToken, record)
 			return err
 		}
-		networks := m.Allocation.Networks
-		switch *m.Allocation.Role {
-		case "firewall":
-			if keypair.vpn != nil {
-				return c.firewallSSHViaVPN(*m.ID, keypair.privatekey, keypair.vpn)
-			}
 
-			for _, nw := range networks {
-				if *nw.Underlay || *nw.Private {
-					continue
-				}
-				for _, ip := range nw.Ips {
-					if portOpen(ip, "22", time.Second) {
-						err := c.sshClient("metal", ip, keypair.privatekey, 22, nil)
-						return err
-					}
-				}
+		// the output of a remote command must not be mixed with the connection progress
+		out := io.Writer(os.Stdout)
+		if len(command) > 0 {
+			out = os.Stderr
+		}
+
+		s, err := c.machineSSH(context.Background(), shoot.Payload, m, keypair, out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = s.Close()
+		}()
+
+		if len(command) > 0 {
+			stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+			if record != nil {
+				stdout, stderr = io.MultiWriter(stdout, record), io.MultiWriter(stderr, record)
 			}
-			return fmt.Errorf("no ip with a open ssh port found")
-		case "machine":
-			// FIXME metal user is not allowed to execute
-			// ip vrf exec <tenantvrf> ssh <machineip>
-			return fmt.Errorf("machine access via ssh not implemented")
-		default:
-			return fmt.Errorf("unknown machine role:%s", *m.Allocation.Role)
+			return s.Run(command, stdout, stderr)
+		}
+
+		if record != nil {
+			return s.Shell(nil, record)
 		}
 
+		return s.Connect(nil)
 	}
 
 	return fmt.Errorf("machine:%s not found in cluster:%s", mid, cid)
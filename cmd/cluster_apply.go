@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/go-openapi/strfmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// clusterApplyDocument is a single document of a cluster apply file, which
+// either contains a create request (identified by project and name) or an
+// update request (identified by id).
+type clusterApplyDocument struct {
+	create *models.V1ClusterCreateRequest
+	update *models.V1ClusterUpdateRequest
+}
+
+// clusterFieldDiff describes the change of a single field of a cluster spec.
+type clusterFieldDiff struct {
+	field string
+	diff  string
+}
+
+type clusterDiffer struct {
+	diffs []clusterFieldDiff
+}
+
+var clusterDiffOpts = []cmp.Option{
+	cmpopts.EquateEmpty(),
+	cmp.Comparer(func(a, b strfmt.DateTime) bool {
+		return time.Time(a).Equal(time.Time(b))
+	}),
+}
+
+// changed returns true if current and desired differ and records the difference.
+func (d *clusterDiffer) changed(field string, current, desired any) bool {
+	if cmp.Equal(current, desired, clusterDiffOpts...) {
+		return false
+	}
+	d.diffs = append(d.diffs, clusterFieldDiff{
+		field: field,
+		diff:  cmp.Diff(current, desired, clusterDiffOpts...),
+	})
+	return true
+}
+
+func (c *config) clusterApply() error {
+	docs, err := readClusterApplyDocuments(viper.GetString("file"))
+	if err != nil {
+		return err
+	}
+
+	dryRun := viper.GetBool("dry-run")
+
+	var response []*models.V1ClusterResponse
+	for _, doc := range docs {
+		var (
+			current *models.V1ClusterResponse
+			desired *models.V1ClusterUpdateRequest
+			err     error
+		)
+
+		switch {
+		case doc.update != nil:
+			if doc.update.ID == nil || *doc.update.ID == "" {
+				return fmt.Errorf("cluster update request requires an id")
+			}
+			resp, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(*doc.update.ID).WithReturnMachines(new(false)), nil)
+			if err != nil {
+				return err
+			}
+			current = resp.Payload
+			desired = doc.update
+		default:
+			current, err = c.findClusterForCreateRequest(doc.create)
+			if err != nil {
+				return err
+			}
+
+			if current == nil {
+				if dryRun {
+					fmt.Fprintf(c.out, "cluster %q in project %q does not exist and would be created\n", pointer.SafeDeref(doc.create.Name), pointer.SafeDeref(doc.create.ProjectID))
+					continue
+				}
+
+				resp, err := c.cloud.Cluster.CreateCluster(cluster.NewCreateClusterParams().WithBody(doc.create), nil)
+				if err != nil {
+					return err
+				}
+				response = append(response, resp.Payload)
+				continue
+			}
+
+			if err := checkClusterImmutableFields(current, doc.create); err != nil {
+				return err
+			}
+
+			desired = clusterUpdateRequestFromCreate(*current.ID, doc.create)
+		}
+
+		update, diffs := minimalClusterUpdate(current, desired)
+
+		if dryRun {
+			c.printClusterDiff(current, diffs)
+			continue
+		}
+
+		if len(diffs) == 0 {
+			response = append(response, current)
+			continue
+		}
+
+		resp, err := c.cloud.Cluster.UpdateCluster(cluster.NewUpdateClusterParams().WithBody(update), nil)
+		if err != nil {
+			return err
+		}
+		response = append(response, resp.Payload)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	return c.listPrinter.Print(response)
+}
+
+// readClusterApplyDocuments reads all documents of the given file, or stdin for -.
+func readClusterApplyDocuments(from string) ([]clusterApplyDocument, error) {
+	var (
+		docs      []clusterApplyDocument
+		node      yaml.Node
+		decodeErr error
+	)
+	err := helper.ReadFrom(from, &node, func(data any) {
+		doc, err := decodeClusterApplyDocument(data.(*yaml.Node))
+		if err != nil {
+			decodeErr = errors.Join(decodeErr, err)
+			return
+		}
+		docs = append(docs, doc)
+		node = yaml.Node{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return docs, nil
+}
+
+// decodeClusterApplyDocument decodes a create request unless the document carries an id,
+// in which case it is treated as an update request.
+func decodeClusterApplyDocument(n *yaml.Node) (clusterApplyDocument, error) {
+	var fields struct {
+		ID string `yaml:"id"`
+	}
+	if err := n.Decode(&fields); err != nil {
+		return clusterApplyDocument{}, fmt.Errorf("decode error: %w", err)
+	}
+
+	if fields.ID != "" {
+		ur := &models.V1ClusterUpdateRequest{}
+		if err := n.Decode(ur); err != nil {
+			return clusterApplyDocument{}, fmt.Errorf("decode error: %w", err)
+		}
+		return clusterApplyDocument{update: ur}, nil
+	}
+
+	cr := &models.V1ClusterCreateRequest{}
+	if err := n.Decode(cr); err != nil {
+		return clusterApplyDocument{}, fmt.Errorf("decode error: %w", err)
+	}
+	return clusterApplyDocument{create: cr}, nil
+}
+
+// findClusterForCreateRequest returns the existing cluster with the project and name of the given create request
+// or nil if there is no such cluster.
+func (c *config) findClusterForCreateRequest(scr *models.V1ClusterCreateRequest) (*models.V1ClusterResponse, error) {
+	if pointer.SafeDeref(scr.ProjectID) == "" || pointer.SafeDeref(scr.Name) == "" {
+		return nil, fmt.Errorf("cluster create request requires projectid and name")
+	}
+
+	resp, err := c.cloud.Cluster.FindClusters(cluster.NewFindClustersParams().WithBody(&models.V1ClusterFindRequest{
+		ProjectID: scr.ProjectID,
+		Name:      scr.Name,
+	}), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(resp.Payload) {
+	case 0:
+		return nil, nil
+	case 1:
+		return resp.Payload[0], nil
+	default:
+		return nil, fmt.Errorf("found %d clusters with name %q in project %q", len(resp.Payload), *scr.Name, *scr.ProjectID)
+	}
+}
+
+func checkClusterImmutableFields(current *models.V1ClusterResponse, scr *models.V1ClusterCreateRequest) error {
+	if scr.PartitionID != nil && pointer.SafeDeref(current.PartitionID) != *scr.PartitionID {
+		return fmt.Errorf("partition of cluster %q cannot be changed from %q to %q", *current.Name, pointer.SafeDeref(current.PartitionID), *scr.PartitionID)
+	}
+	if scr.NetworkAccessType != nil && pointer.SafeDeref(current.NetworkAccessType) != *scr.NetworkAccessType {
+		return fmt.Errorf("network access type of cluster %q cannot be changed from %q to %q", *current.Name, pointer.SafeDeref(current.NetworkAccessType), *scr.NetworkAccessType)
+	}
+	return nil
+}
+
+// clusterUpdateRequestFromCreate converts the updatable fields of a create request into an update request.
+func clusterUpdateRequestFromCreate(id string, scr *models.V1ClusterCreateRequest) *models.V1ClusterUpdateRequest {
+	return &models.V1ClusterUpdateRequest{
+		ID:                        &id,
+		Purpose:                   scr.Purpose,
+		Labels:                    scr.Labels,
+		Workers:                   scr.Workers,
+		Maintenance:               scr.Maintenance,
+		Kubernetes:                scr.Kubernetes,
+		FirewallImage:             scr.FirewallImage,
+		FirewallSize:              scr.FirewallSize,
+		FirewallControllerVersion: scr.FirewallControllerVersion,
+		FirewallHealthTimeout:     scr.FirewallHealthTimeout,
+		FirewallCreateTimeout:     scr.FirewallCreateTimeout,
+		AdditionalNetworks:        scr.AdditionalNetworks,
+		KubeAPIServerACL:          scr.KubeAPIServerACL,
+		EgressRules:               scr.EgressRules,
+		ClusterFeatures:           scr.ClusterFeatures,
+		CustomDefaultStorageClass: scr.CustomDefaultStorageClass,
+		SystemComponents:          scr.SystemComponents,
+		SeedName:                  pointer.PointerOrNil(scr.SeedName),
+		XDRConfig:                 scr.XDRConfig,
+	}
+}
+
+// minimalClusterUpdate returns an update request only containing the fields of desired which
+// differ from the current state of the cluster. Fields which are not set in desired are left untouched.
+func minimalClusterUpdate(current *models.V1ClusterResponse, desired *models.V1ClusterUpdateRequest) (*models.V1ClusterUpdateRequest, []clusterFieldDiff) {
+	var (
+		d   = &clusterDiffer{}
+		cur = &models.V1ClusterUpdateRequest{
+			ID: current.ID,
+		}
+	)
+
+	if desired.Purpose != nil && d.changed("purpose", current.Purpose, desired.Purpose) {
+		cur.Purpose = desired.Purpose
+	}
+	if desired.Labels != nil && d.changed("labels", current.Labels, desired.Labels) {
+		cur.Labels = desired.Labels
+	}
+	if desired.Workers != nil && d.changed("workers", current.Workers, desired.Workers) {
+		cur.Workers = desired.Workers
+	}
+	if desired.Maintenance != nil && d.changed("maintenance", current.Maintenance, desired.Maintenance) {
+		cur.Maintenance = desired.Maintenance
+	}
+	if desired.Kubernetes != nil {
+		// the expiration date is reported by the api and cannot be set by the user
+		k8s := pointer.SafeDeref(current.Kubernetes)
+		k8s.ExpirationDate = desired.Kubernetes.ExpirationDate
+		if d.changed("kubernetes", &k8s, desired.Kubernetes) {
+			cur.Kubernetes = desired.Kubernetes
+		}
+	}
+	if desired.FirewallImage != nil && d.changed("firewallImage", current.FirewallImage, desired.FirewallImage) {
+		cur.FirewallImage = desired.FirewallImage
+	}
+	if desired.FirewallSize != nil && d.changed("firewallSize", current.FirewallSize, desired.FirewallSize) {
+		cur.FirewallSize = desired.FirewallSize
+	}
+	if desired.FirewallControllerVersion != nil && d.changed("firewallControllerVersion", current.FirewallControllerVersion, desired.FirewallControllerVersion) {
+		cur.FirewallControllerVersion = desired.FirewallControllerVersion
+	}
+	if desired.AdditionalNetworks != nil {
+		currentNetworks := slices.Sorted(slices.Values(current.AdditionalNetworks))
+		desiredNetworks := slices.Sorted(slices.Values(desired.AdditionalNetworks))
+		if d.changed("additionalNetworks", currentNetworks, desiredNetworks) {
+			cur.AdditionalNetworks = desired.AdditionalNetworks
+		}
+	}
+	if desired.KubeAPIServerACL != nil && d.changed("kubeAPIServerACL", current.KubeAPIServerACL, desired.KubeAPIServerACL) {
+		cur.KubeAPIServerACL = desired.KubeAPIServerACL
+	}
+	if desired.EgressRules != nil && d.changed("egressRules", current.EgressRules, desired.EgressRules) {
+		cur.EgressRules = desired.EgressRules
+	}
+	if desired.ClusterFeatures != nil && d.changed("clusterFeatures", current.ClusterFeatures, desired.ClusterFeatures) {
+		cur.ClusterFeatures = desired.ClusterFeatures
+	}
+	if desired.CustomDefaultStorageClass != nil && d.changed("customDefaultStorageClass", current.CustomDefaultStorageClass, desired.CustomDefaultStorageClass) {
+		cur.CustomDefaultStorageClass = desired.CustomDefaultStorageClass
+	}
+	if desired.SeedName != nil && d.changed("seedName", pointer.SafeDeref(current.Status).SeedName, *desired.SeedName) {
+		cur.SeedName = desired.SeedName
+	}
+	if desired.XDRConfig != nil && d.changed("xdrConfig", current.XDRConfig, desired.XDRConfig) {
+		cur.XDRConfig = desired.XDRConfig
+	}
+
+	// the following fields are not part of the cluster response, so they are always
+	// sent when they are specified
+	if desired.FirewallHealthTimeout != nil && d.changed("firewallHealthTimeout", nil, desired.FirewallHealthTimeout) {
+		cur.FirewallHealthTimeout = desired.FirewallHealthTimeout
+	}
+	if desired.FirewallCreateTimeout != nil && d.changed("firewallCreateTimeout", nil, desired.FirewallCreateTimeout) {
+		cur.FirewallCreateTimeout = desired.FirewallCreateTimeout
+	}
+	if desired.SystemComponents != nil && d.changed("systemComponents", nil, desired.SystemComponents) {
+		cur.SystemComponents = desired.SystemComponents
+	}
+
+	return cur, d.diffs
+}
+
+func (c *config) printClusterDiff(current *models.V1ClusterResponse, diffs []clusterFieldDiff) {
+	if len(diffs) == 0 {
+		fmt.Fprintf(c.out, "cluster %q (%s) is up-to-date\n", pointer.SafeDeref(current.Name), pointer.SafeDeref(current.ID))
+		return
+	}
+
+	fmt.Fprintf(c.out, "cluster %q (%s) would be updated (-current +desired):\n", pointer.SafeDeref(current.Name), pointer.SafeDeref(current.ID))
+	for _, diff := range diffs {
+		fmt.Fprintf(c.out, "  %s:\n", diff.field)
+		for line := range strings.SplitSeq(strings.TrimRight(diff.diff, "\n"), "\n") {
+			fmt.Fprintf(c.out, "    %s\n", line)
+		}
+	}
+}
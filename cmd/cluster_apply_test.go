@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_minimalClusterUpdate(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		ID:      new("c1"),
+		Name:    new("test"),
+		Purpose: new("production"),
+		Labels:  map[string]string{"team": "a"},
+		XDRConfig: &models.V1XDR{
+			Disabled: new(false),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		desired    *models.V1ClusterUpdateRequest
+		want       *models.V1ClusterUpdateRequest
+		wantFields []string
+	}{
+		{
+			name: "no-op",
+			desired: &models.V1ClusterUpdateRequest{
+				ID:      new("c1"),
+				Purpose: new("production"),
+				Labels:  map[string]string{"team": "a"},
+			},
+			want: &models.V1ClusterUpdateRequest{ID: new("c1")},
+		},
+		{
+			name: "single field",
+			desired: &models.V1ClusterUpdateRequest{
+				ID:      new("c1"),
+				Purpose: new("evaluation"),
+				Labels:  map[string]string{"team": "a"},
+			},
+			want:       &models.V1ClusterUpdateRequest{ID: new("c1"), Purpose: new("evaluation")},
+			wantFields: []string{"purpose"},
+		},
+		{
+			name: "xdr and labels",
+			desired: &models.V1ClusterUpdateRequest{
+				ID:        new("c1"),
+				Labels:    map[string]string{"team": "b"},
+				XDRConfig: &models.V1XDR{Disabled: new(true)},
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:        new("c1"),
+				Labels:    map[string]string{"team": "b"},
+				XDRConfig: &models.V1XDR{Disabled: new(true)},
+			},
+			wantFields: []string{"labels", "xdrConfig"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diffs := minimalClusterUpdate(current, tt.desired)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+
+			var fields []string
+			for _, d := range diffs {
+				fields = append(fields, d.field)
+			}
+			require.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func Test_clusterUpdateRequestFromCreate(t *testing.T) {
+	xdr := &models.V1XDR{Disabled: new(true)}
+
+	got := clusterUpdateRequestFromCreate("c1", &models.V1ClusterCreateRequest{
+		Name:      new("test"),
+		Purpose:   new("production"),
+		XDRConfig: xdr,
+	})
+
+	require.Equal(t, "c1", *got.ID)
+	require.Equal(t, "production", *got.Purpose)
+	require.Equal(t, xdr, got.XDRConfig)
+}
+
+func Test_readClusterApplyDocuments(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "clusters.yaml")
+	err := os.WriteFile(file, []byte(`projectid: p1
+name: create-me
+purpose: evaluation
+---
+id: c2
+purpose: production
+`), 0o600)
+	require.NoError(t, err)
+
+	docs, err := readClusterApplyDocuments(file)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	require.NotNil(t, docs[0].create)
+	require.Nil(t, docs[0].update)
+	require.Equal(t, "create-me", *docs[0].create.Name)
+	require.Equal(t, "evaluation", *docs[0].create.Purpose)
+
+	require.Nil(t, docs[1].create)
+	require.NotNil(t, docs[1].update)
+	require.Equal(t, "c2", *docs[1].update.ID)
+	require.Equal(t, "production", *docs[1].update.Purpose)
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	clusterBulkParallelismDefault      = 4
+	clusterMachineRejoinTimeoutDefault = 20 * time.Minute
+	clusterMachinePhonedHomeEvent      = "Phoned Home"
+	clusterMachineEventTimeFormat      = "2006-01-02T15:04:05.999Z"
+)
+
+// clusterBulkOperation is executed for every cluster selected in bulk mode and returns a short result message.
+type clusterBulkOperation func(ctx context.Context, target *models.V1ClusterResponse) (string, error)
+
+// addClusterBulkFlags adds the filters of the cluster list command to a command, which then selects
+// the target clusters when no cluster id is given as argument.
+// purposeFlag is the name of the purpose filter, which is configurable as some commands already use --purpose otherwise.
+func (c *config) addClusterBulkFlags(cmd *cobra.Command, purposeFlag string) {
+	cmd.Flags().String("project", "", "bulk mode: act on clusters of given project")
+	cmd.Flags().String("partition", "", "bulk mode: act on clusters in partition")
+	cmd.Flags().String("tenant", "", "bulk mode: act on clusters of given tenant")
+	cmd.Flags().StringSlice("labels", nil, "bulk mode: act on clusters of given labels")
+	cmd.Flags().String(purposeFlag, "", "bulk mode: act on clusters of given purpose")
+	cmd.Flags().Int("parallelism", clusterBulkParallelismDefault, "bulk mode: the amount of clusters processed at the same time")
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("tenant", c.comp.TenantListCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc(purposeFlag, c.comp.ClusterPurposeListCompletion))
+}
+
+// clusterBulkFindRequest returns the find request for the bulk mode filters.
+// if no filter is given, nil is returned and the command acts on a single cluster.
+func clusterBulkFindRequest(purposeFlag string) (*models.V1ClusterFindRequest, error) {
+	var (
+		cfr      = &models.V1ClusterFindRequest{}
+		filtered bool
+	)
+
+	if project := viper.GetString("project"); project != "" {
+		cfr.ProjectID = &project
+		filtered = true
+	}
+	if partition := viper.GetString("partition"); partition != "" {
+		cfr.PartitionID = &partition
+		filtered = true
+	}
+	if tenant := viper.GetString("tenant"); tenant != "" {
+		cfr.Tenant = &tenant
+		filtered = true
+	}
+	if purpose := viper.GetString(purposeFlag); purpose != "" {
+		cfr.Purpose = &purpose
+		filtered = true
+	}
+	if labels := viper.GetStringSlice("labels"); len(labels) > 0 {
+		labelMap, err := helper.LabelsToMap(labels)
+		if err != nil {
+			return nil, err
+		}
+		cfr.Labels = labelMap
+		filtered = true
+	}
+
+	if !filtered {
+		return nil, nil
+	}
+
+	return cfr, nil
+}
+
+// clusterBulkTargets returns the clusters matching the bulk mode filters.
+func (c *config) clusterBulkTargets(cfr *models.V1ClusterFindRequest, withMachines bool) ([]*models.V1ClusterResponse, error) {
+	fcp := cluster.NewFindClustersParams()
+	if withMachines {
+		fcp.WithReturnMachines(new(true))
+	}
+	fcp.SetBody(cfr)
+
+	resp, err := c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Payload) == 0 {
+		return nil, fmt.Errorf("no clusters match the given filters")
+	}
+
+	return resp.Payload, nil
+}
+
+// clusterBulkConfirm prints a summary of the target clusters and asks the user for confirmation.
+func (c *config) clusterBulkConfirm(verb string, targets []*models.V1ClusterResponse) error {
+	err := c.listPrinter.Print(targets)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("yes-i-really-mean-it") {
+		return nil
+	}
+
+	fmt.Fprintf(c.out, "\nthis will %s the %d cluster(s) listed above with a parallelism of %d.\n", verb, len(targets), viper.GetInt("parallelism"))
+
+	return helper.Prompt("Are you sure? (y/n)", "y")
+}
+
+// clusterBulk runs the given operation on all target clusters, prints the result for each cluster
+// and returns an error if the operation failed for any of them.
+func (c *config) clusterBulk(targets []*models.V1ClusterResponse, operation clusterBulkOperation) error {
+	var (
+		ctx         = context.Background()
+		parallelism = int64(max(viper.GetInt("parallelism"), 1))
+		sem         = semaphore.NewWeighted(parallelism)
+		wg          sync.WaitGroup
+		results     = make([]*tableprinters.ClusterBulkResult, len(targets))
+	)
+
+	for i, target := range targets {
+		results[i] = &tableprinters.ClusterBulkResult{
+			ID:        pointer.SafeDeref(target.ID),
+			Name:      pointer.SafeDeref(target.Name),
+			Project:   pointer.SafeDeref(target.ProjectID),
+			Partition: pointer.SafeDeref(target.PartitionID),
+		}
+
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer sem.Release(1)
+			defer wg.Done()
+
+			msg, err := operation(ctx, target)
+			if err != nil {
+				results[i].Message = err.Error()
+				return
+			}
+
+			results[i].Succeeded = true
+			results[i].Message = msg
+		}()
+	}
+
+	wg.Wait()
+
+	err := c.listPrinter.Print(results)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Succeeded {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("operation failed for %d of %d cluster(s)", failed, len(results))
+	}
+
+	return nil
+}
+
+// clusterCycleMachinesRolling cycles the worker machines of a cluster one after another and waits for each machine
+// to join the cluster again before the next one is cycled. it stops at the first machine that does not come back.
+func (c *config) clusterCycleMachinesRolling(ctx context.Context, target *models.V1ClusterResponse) (string, error) {
+	timeout := viper.GetDuration("machine-timeout")
+
+	for i, m := range target.Machines {
+		since := time.Now()
+
+		request := cluster.NewCycleMachineParams().WithContext(ctx)
+		request.SetID(*target.ID)
+		request.Body = &models.V1ClusterMachineCycleRequest{Machineid: m.ID}
+
+		_, err := c.cloud.Cluster.CycleMachine(request, nil)
+		if err == nil {
+			err = c.clusterWaitForMachineRejoin(ctx, *target.ID, pointer.SafeDeref(m.ID), since, timeout)
+		}
+		if err != nil {
+			return "", fmt.Errorf("machine %s: %w, %d of %d machine(s) were not cycled", pointer.SafeDeref(m.ID), err, len(target.Machines)-i-1, len(target.Machines))
+		}
+	}
+
+	return fmt.Sprintf("cycled %d machine(s)", len(target.Machines)), nil
+}
+
+// clusterWaitForMachineRejoin polls the machines of the cluster until the given machine joined again after since.
+func (c *config) clusterWaitForMachineRejoin(ctx context.Context, clusterID, machineID string, since time.Time, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resp, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithContext(ctx).WithID(clusterID).WithReturnMachines(new(true)), nil)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range resp.Payload.Machines {
+			if pointer.SafeDeref(m.ID) == machineID && clusterMachineRejoined(m, since) {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %s while waiting for the machine to join the cluster again", timeout)
+		}
+
+		time.Sleep(clusterWaitPollInterval)
+	}
+}
+
+// clusterMachineRebootEvents are provisioning events, which show that a machine went down after it was cycled.
+var clusterMachineRebootEvents = []string{"Planned Reboot", "PXE Booting", "Waiting"}
+
+// clusterMachineRejoined returns true if the machine is alive and phoned home after it rebooted since the given time.
+// an alive machine phones home all the time, so phoning home alone does not show that the machine was cycled.
+func clusterMachineRejoined(m *models.ModelsV1MachineResponse, since time.Time) bool {
+	if m == nil || pointer.SafeDeref(m.Liveliness) != "Alive" || m.Events == nil || len(m.Events.Log) == 0 {
+		return false
+	}
+	// the log starts with the most recent event
+	if pointer.SafeDeref(m.Events.Log[0].Event) != clusterMachinePhonedHomeEvent {
+		return false
+	}
+	for _, e := range m.Events.Log[1:] {
+		if !slices.Contains(clusterMachineRebootEvents, pointer.SafeDeref(e.Event)) {
+			continue
+		}
+		eventTime, err := time.Parse(clusterMachineEventTimeFormat, e.Time)
+		if err == nil && !eventTime.Before(since) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_clusterBulkFindRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   map[string]any
+		want    *models.V1ClusterFindRequest
+		wantErr string
+	}{
+		{
+			name: "no filters",
+		},
+		{
+			name:  "parallelism is no filter",
+			flags: map[string]any{"parallelism": 8},
+		},
+		{
+			name: "all filters",
+			flags: map[string]any{
+				"project":         "p1",
+				"partition":       "dc1",
+				"tenant":          "fits",
+				"cluster-purpose": "evaluation",
+				"labels":          []string{"team=a", "env=test"},
+			},
+			want: &models.V1ClusterFindRequest{
+				ProjectID:   new("p1"),
+				PartitionID: new("dc1"),
+				Tenant:      new("fits"),
+				Purpose:     new("evaluation"),
+				Labels:      map[string]string{"team": "a", "env": "test"},
+			},
+		},
+		{
+			name:  "purpose is read from the given flag",
+			flags: map[string]any{"purpose": "production"},
+		},
+		{
+			name:    "invalid labels",
+			flags:   map[string]any{"labels": []string{"team"}},
+			wantErr: "provided labels must be in the form <key>=<value>, found: team",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			for k, v := range tt.flags {
+				viper.Set(k, v)
+			}
+
+			got, err := clusterBulkFindRequest("cluster-purpose")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_clusterBulk(t *testing.T) {
+	targets := []*models.V1ClusterResponse{
+		{ID: new("c1"), Name: new("one"), ProjectID: new("p1"), PartitionID: new("dc1")},
+		{ID: new("c2"), Name: new("two"), ProjectID: new("p1"), PartitionID: new("dc1")},
+		{ID: new("c3"), Name: new("three"), ProjectID: new("p2"), PartitionID: new("dc2")},
+	}
+
+	tests := []struct {
+		name    string
+		failing []string
+		want    []*tableprinters.ClusterBulkResult
+		wantErr string
+	}{
+		{
+			name: "all succeeded",
+			want: []*tableprinters.ClusterBulkResult{
+				{ID: "c1", Name: "one", Project: "p1", Partition: "dc1", Succeeded: true, Message: "done c1"},
+				{ID: "c2", Name: "two", Project: "p1", Partition: "dc1", Succeeded: true, Message: "done c2"},
+				{ID: "c3", Name: "three", Project: "p2", Partition: "dc2", Succeeded: true, Message: "done c3"},
+			},
+		},
+		{
+			name:    "some failed",
+			failing: []string{"c1", "c3"},
+			want: []*tableprinters.ClusterBulkResult{
+				{ID: "c1", Name: "one", Project: "p1", Partition: "dc1", Message: "failed c1"},
+				{ID: "c2", Name: "two", Project: "p1", Partition: "dc1", Succeeded: true, Message: "done c2"},
+				{ID: "c3", Name: "three", Project: "p2", Partition: "dc2", Message: "failed c3"},
+			},
+			wantErr: "operation failed for 2 of 3 cluster(s)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("parallelism", 2)
+
+			var out bytes.Buffer
+			c := &config{
+				out:         &out,
+				listPrinter: printers.NewJSONPrinter().WithOut(&out),
+			}
+
+			err := c.clusterBulk(targets, func(ctx context.Context, target *models.V1ClusterResponse) (string, error) {
+				for _, id := range tt.failing {
+					if id == *target.ID {
+						return "", fmt.Errorf("failed %s", id)
+					}
+				}
+				return "done " + *target.ID, nil
+			})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var got []*tableprinters.ClusterBulkResult
+			require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_clusterMachineRejoined(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type event struct {
+		event string
+		time  string
+	}
+
+	// events are given with the most recent event first like in the machine log
+	machine := func(liveliness string, events ...event) *models.ModelsV1MachineResponse {
+		m := &models.ModelsV1MachineResponse{
+			ID:         new("m1"),
+			Liveliness: new(liveliness),
+			Events:     &models.ModelsV1MachineRecentProvisioningEvents{},
+		}
+		for _, e := range events {
+			m.Events.Log = append(m.Events.Log, &models.ModelsV1MachineProvisioningEvent{Event: new(e.event), Time: e.time})
+		}
+		return m
+	}
+
+	var (
+		phonedHome    = event{event: "Phoned Home", time: "2024-05-01T12:08:00Z"}
+		bootingKernel = event{event: "Booting New Kernel", time: "2024-05-01T12:07:00Z"}
+		plannedReboot = event{event: "Planned Reboot", time: "2024-05-01T12:01:00Z"}
+		pxeBooting    = event{event: "PXE Booting", time: "2024-05-01T12:02:00Z"}
+		oldReboot     = event{event: "Planned Reboot", time: "2024-04-01T12:01:00Z"}
+	)
+
+	tests := []struct {
+		name    string
+		machine *models.ModelsV1MachineResponse
+		want    bool
+	}{
+		{name: "phoned home after reboot", machine: machine("Alive", phonedHome, bootingKernel, pxeBooting, plannedReboot), want: true},
+		{name: "phoned home after pxe boot", machine: machine("Alive", phonedHome, bootingKernel, pxeBooting), want: true},
+		{name: "keeps phoning home without reboot", machine: machine("Alive", phonedHome, bootingKernel, oldReboot)},
+		{name: "phones home only", machine: machine("Alive", phonedHome)},
+		{name: "still booting", machine: machine("Alive", bootingKernel, pxeBooting, plannedReboot)},
+		{name: "rebooting", machine: machine("Alive", plannedReboot, phonedHome)},
+		{name: "dead", machine: machine("Dead", phonedHome, bootingKernel, plannedReboot)},
+		{name: "unparsable event time", machine: machine("Alive", phonedHome, event{event: "Planned Reboot", time: "now"})},
+		{name: "no events", machine: &models.ModelsV1MachineResponse{Liveliness: new("Alive")}},
+		{name: "nil machine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, clusterMachineRejoined(tt.machine, since))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"gopkg.in/yaml.v3"
+)
+
+// clusterEditSpec is the subset of a cluster which can be modified with cluster edit.
+// The id, name, project, partition and network isolation are only shown for reference
+// and cannot be changed.
+type clusterEditSpec struct {
+	ID               string                     `yaml:"id"`
+	Name             string                     `yaml:"name"`
+	ProjectID        string                     `yaml:"projectid"`
+	PartitionID      string                     `yaml:"partitionid"`
+	NetworkIsolation string                     `yaml:"networkisolation,omitempty"`
+	Labels           map[string]string          `yaml:"labels"`
+	Workers          []*models.V1Worker         `yaml:"workers"`
+	Maintenance      *models.V1Maintenance      `yaml:"maintenance"`
+	KubeAPIServerACL *models.V1KubeAPIServerACL `yaml:"kubeapiserveracl"`
+	EgressRules      []*models.V1EgressRule     `yaml:"egressrules"`
+}
+
+func newClusterEditSpec(current *models.V1ClusterResponse) *clusterEditSpec {
+	return &clusterEditSpec{
+		ID:               pointer.SafeDeref(current.ID),
+		Name:             pointer.SafeDeref(current.Name),
+		ProjectID:        pointer.SafeDeref(current.ProjectID),
+		PartitionID:      pointer.SafeDeref(current.PartitionID),
+		NetworkIsolation: pointer.SafeDeref(current.NetworkAccessType),
+		Labels:           current.Labels,
+		Workers:          current.Workers,
+		Maintenance:      current.Maintenance,
+		KubeAPIServerACL: current.KubeAPIServerACL,
+		EgressRules:      current.EgressRules,
+	}
+}
+
+func (c *config) clusterEdit(args []string) error {
+	id, err := c.clusterID("edit", args)
+	if err != nil {
+		return err
+	}
+
+	findRequest := cluster.NewFindClusterParams().WithID(id).WithReturnMachines(new(false))
+	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return fmt.Errorf("cluster describe error:%w", err)
+	}
+	current := resp.Payload
+
+	getFunc := func(id string) ([]byte, error) {
+		content, err := yaml.Marshal(newClusterEditSpec(current))
+		if err != nil {
+			return nil, err
+		}
+		return content, nil
+	}
+	updateFunc := func(filename string) error {
+		var specs []clusterEditSpec
+		var spec clusterEditSpec
+		err := helper.ReadFrom(filename, &spec, func(data any) {
+			doc := data.(*clusterEditSpec)
+			specs = append(specs, *doc)
+			spec = clusterEditSpec{}
+		})
+		if err != nil {
+			return err
+		}
+		if len(specs) != 1 {
+			return fmt.Errorf("cluster update error more or less than one cluster given:%d", len(specs))
+		}
+
+		cur, err := clusterUpdateRequestFromEditSpec(current, &specs[0])
+		if err != nil {
+			return err
+		}
+
+		update, diffs := minimalClusterUpdate(current, cur)
+		if len(diffs) == 0 {
+			fmt.Fprintf(c.out, "cluster %q was not modified\n", pointer.SafeDeref(current.Name))
+			return nil
+		}
+		if err := clusterUpdateVerifyClear(update); err != nil {
+			return err
+		}
+
+		uresp, err := c.cloud.Cluster.UpdateCluster(cluster.NewUpdateClusterParams().WithBody(update), nil)
+		if err != nil {
+			return err
+		}
+		return c.describePrinter.Print(uresp.Payload)
+	}
+
+	return helper.Edit(id, getFunc, updateFunc)
+}
+
+// clusterUpdateRequestFromEditSpec validates that no immutable fields were touched and
+// converts the edited spec into an update request.
+func clusterUpdateRequestFromEditSpec(current *models.V1ClusterResponse, spec *clusterEditSpec) (*models.V1ClusterUpdateRequest, error) {
+	original := newClusterEditSpec(current)
+
+	for _, f := range []struct {
+		field           string
+		original, given string
+	}{
+		{field: "id", original: original.ID, given: spec.ID},
+		{field: "name", original: original.Name, given: spec.Name},
+		{field: "projectid", original: original.ProjectID, given: spec.ProjectID},
+		{field: "partitionid", original: original.PartitionID, given: spec.PartitionID},
+		{field: "networkisolation", original: original.NetworkIsolation, given: spec.NetworkIsolation},
+	} {
+		if f.original != f.given {
+			return nil, fmt.Errorf("field %q is immutable and cannot be changed from %q to %q", f.field, f.original, f.given)
+		}
+	}
+
+	labels := spec.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	egressRules := spec.EgressRules
+	if egressRules == nil {
+		egressRules = []*models.V1EgressRule{}
+	}
+
+	return &models.V1ClusterUpdateRequest{
+		ID:               current.ID,
+		Labels:           labels,
+		Workers:          spec.Workers,
+		Maintenance:      spec.Maintenance,
+		KubeAPIServerACL: spec.KubeAPIServerACL,
+		EgressRules:      egressRules,
+	}, nil
+}
+
+// clusterUpdateVerifyClear ensures that removing all labels or egress rules actually
+// reaches the api. An empty map or slice is dropped from the request body if the
+// field is marshalled with omitempty, which would silently keep the old values.
+func clusterUpdateVerifyClear(update *models.V1ClusterUpdateRequest) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return err
+	}
+	sent := func(field string) bool {
+		for k := range fields {
+			if strings.EqualFold(k, field) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if update.Labels != nil && len(update.Labels) == 0 && !sent("labels") {
+		return fmt.Errorf("removing all labels of a cluster is not supported by the api, keep at least one label")
+	}
+	if update.EgressRules != nil && len(update.EgressRules) == 0 && !sent("egressrules") {
+		return fmt.Errorf("removing all egress rules of a cluster is not supported by the api, keep at least one egress rule")
+	}
+	return nil
+}
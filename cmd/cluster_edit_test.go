@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_clusterUpdateRequestFromEditSpec(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		ID:                new("c1"),
+		Name:              new("test"),
+		ProjectID:         new("p1"),
+		PartitionID:       new("dc1"),
+		NetworkAccessType: new("baseline"),
+		Labels:            map[string]string{"team": "a"},
+		Maintenance:       &models.V1Maintenance{},
+		EgressRules:       []*models.V1EgressRule{{NetworkID: new("internet"), IPs: []string{"1.2.3.4"}}},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *clusterEditSpec)
+		want    *models.V1ClusterUpdateRequest
+		wantErr string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(s *clusterEditSpec) {},
+			want: &models.V1ClusterUpdateRequest{
+				ID:          new("c1"),
+				Labels:      map[string]string{"team": "a"},
+				Maintenance: &models.V1Maintenance{},
+				EgressRules: []*models.V1EgressRule{{NetworkID: new("internet"), IPs: []string{"1.2.3.4"}}},
+			},
+		},
+		{
+			name: "labels and egress rules are changed",
+			modify: func(s *clusterEditSpec) {
+				s.Labels = map[string]string{"team": "b"}
+				s.EgressRules = []*models.V1EgressRule{{NetworkID: new("internet"), IPs: []string{"1.2.3.5"}}}
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:          new("c1"),
+				Labels:      map[string]string{"team": "b"},
+				Maintenance: &models.V1Maintenance{},
+				EgressRules: []*models.V1EgressRule{{NetworkID: new("internet"), IPs: []string{"1.2.3.5"}}},
+			},
+		},
+		{
+			name: "labels and egress rules are removed",
+			modify: func(s *clusterEditSpec) {
+				s.Labels = nil
+				s.EgressRules = nil
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:          new("c1"),
+				Labels:      map[string]string{},
+				Maintenance: &models.V1Maintenance{},
+				EgressRules: []*models.V1EgressRule{},
+			},
+		},
+		{
+			name:    "id is immutable",
+			modify:  func(s *clusterEditSpec) { s.ID = "c2" },
+			wantErr: `field "id" is immutable and cannot be changed from "c1" to "c2"`,
+		},
+		{
+			name:    "name is immutable",
+			modify:  func(s *clusterEditSpec) { s.Name = "other" },
+			wantErr: `field "name" is immutable and cannot be changed from "test" to "other"`,
+		},
+		{
+			name:    "project is immutable",
+			modify:  func(s *clusterEditSpec) { s.ProjectID = "p2" },
+			wantErr: `field "projectid" is immutable and cannot be changed from "p1" to "p2"`,
+		},
+		{
+			name:    "partition is immutable",
+			modify:  func(s *clusterEditSpec) { s.PartitionID = "dc2" },
+			wantErr: `field "partitionid" is immutable and cannot be changed from "dc1" to "dc2"`,
+		},
+		{
+			name:    "network isolation is immutable",
+			modify:  func(s *clusterEditSpec) { s.NetworkIsolation = "forbidden" },
+			wantErr: `field "networkisolation" is immutable and cannot be changed from "baseline" to "forbidden"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newClusterEditSpec(current)
+			tt.modify(spec)
+
+			got, err := clusterUpdateRequestFromEditSpec(current, spec)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_clusterEditClear(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		ID:          new("c1"),
+		Name:        new("test"),
+		Labels:      map[string]string{"team": "a"},
+		EgressRules: []*models.V1EgressRule{{NetworkID: new("internet"), IPs: []string{"1.2.3.4"}}},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *clusterEditSpec)
+		field  string
+		want   string
+	}{
+		{
+			name:   "labels",
+			modify: func(s *clusterEditSpec) { s.Labels = nil },
+			field:  "labels",
+			want:   `{}`,
+		},
+		{
+			name:   "egress rules",
+			modify: func(s *clusterEditSpec) { s.EgressRules = nil },
+			field:  "egressrules",
+			want:   `[]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newClusterEditSpec(current)
+			tt.modify(spec)
+
+			cur, err := clusterUpdateRequestFromEditSpec(current, spec)
+			require.NoError(t, err)
+
+			update, diffs := minimalClusterUpdate(current, cur)
+			require.Len(t, diffs, 1)
+
+			body, err := json.Marshal(update)
+			require.NoError(t, err)
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(body, &fields))
+
+			// the clear must either reach the api or be rejected, never be dropped silently
+			err = clusterUpdateVerifyClear(update)
+			for k, v := range fields {
+				if strings.EqualFold(k, tt.field) {
+					require.NoError(t, err)
+					require.JSONEq(t, tt.want, string(v))
+					return
+				}
+			}
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	clusterMachineExecTimeoutDefault = time.Minute
+)
+
+func newClusterMachineExecCmd(c *config) *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:   "exec <clusterid> -- <command>",
+		Short: "run a command on many worker nodes of the cluster",
+		Long:  "runs a command concurrently on the selected worker nodes of the cluster. the worker nodes are reached by hopping through a firewall of the cluster, which is accessed through the vpn if available.",
+		Example: `show the uptime of all worker nodes:
+cloudctl cluster machine exec <clusterid> --all -- uptime
+
+show the kernel version of a worker group as json:
+cloudctl cluster machine exec <clusterid> --workergroup group-0 -o json -- uname -r`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return fmt.Errorf("no command given, specify it after --")
+			}
+			return c.clusterMachineExec(args[:dash], args[dash:])
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+
+	execCmd.Flags().Bool("all", false, "run the command on all worker nodes of the cluster")
+	execCmd.Flags().StringSlice("workergroup", nil, "run the command on the worker nodes of the given worker groups")
+	execCmd.Flags().StringSlice("machineid", nil, "run the command on the given machines")
+	execCmd.Flags().String("reason", "", "a short description why ssh access is required")
+	execCmd.Flags().Int("parallelism", clusterBulkParallelismDefault, "the amount of machines the command runs on at the same time")
+	execCmd.Flags().Duration("timeout", clusterMachineExecTimeoutDefault, "the timeout of the command on a single machine, including the time to connect")
+	execCmd.MarkFlagsMutuallyExclusive("all", "workergroup", "machineid")
+	execCmd.MarkFlagsOneRequired("all", "workergroup", "machineid")
+	genericcli.Must(execCmd.RegisterFlagCompletionFunc("workergroup", c.comp.ClusterWorkerGroupListCompletion))
+	genericcli.Must(execCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
+
+	return execCmd
+}
+
+func (c *config) clusterMachineExec(args []string, command []string) error {
+	cid, err := c.clusterID("exec", args)
+	if err != nil {
+		return err
+	}
+
+	shoot, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(cid), nil)
+	if err != nil {
+		return err
+	}
+
+	targets, err := clusterMachineExecTargets(shoot.Payload, viper.GetBool("all"), viper.GetStringSlice("workergroup"), viper.GetStringSlice("machineid"))
+	if err != nil {
+		return err
+	}
+
+	keypair, err := c.sshKeyPair(cid, false, pointer.PointerOrNil(viper.GetString("reason")))
+	if err != nil {
+		return err
+	}
+
+	// stdout is reserved for the results
+	fw, err := c.clusterFirewallSSH(context.Background(), shoot.Payload, keypair, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fw.Close()
+	}()
+
+	var (
+		ctx         = context.Background()
+		parallelism = int64(max(viper.GetInt("parallelism"), 1))
+		timeout     = viper.GetDuration("timeout")
+		sem         = semaphore.NewWeighted(parallelism)
+		wg          sync.WaitGroup
+		results     = make([]*tableprinters.ClusterMachineExecResult, len(targets))
+	)
+
+	for i, m := range targets {
+		results[i] = &tableprinters.ClusterMachineExecResult{
+			MachineID: pointer.SafeDeref(m.ID),
+			Hostname:  pointer.SafeDeref(m.Allocation.Hostname),
+		}
+
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer sem.Release(1)
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			ip, vrf := machinePrivateNetwork(m)
+			stdout, stderr, err := fw.execOnMachine(ctx, ip, vrf, keypair, command)
+
+			results[i].Stdout = stdout
+			results[i].Stderr = stderr
+			results[i].ExitCode, results[i].Error = clusterMachineExecStatus(err)
+		}()
+	}
+
+	wg.Wait()
+
+	err = c.listPrinter.Print(results)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 || r.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d machine(s)", failed, len(results))
+	}
+
+	return nil
+}
+
+// execOnMachine runs the command on the machine with the given ip in the tenant network and returns its output.
+// the connection to the machine is closed when the context is done.
+func (s *sshConnection) execOnMachine(ctx context.Context, ip string, vrf int64, keypair *sshkeypair, command []string) (string, string, error) {
+	if ip == "" {
+		return "", "", fmt.Errorf("machine has no ip in the tenant network")
+	}
+
+	type result struct {
+		stdout, stderr string
+		err            error
+	}
+
+	var (
+		mu     sync.Mutex
+		client *sshConnection
+		done   = make(chan result, 1)
+	)
+
+	go func() {
+		m, err := s.jump(ip, vrf, keypair, io.Discard)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+
+		mu.Lock()
+		if ctx.Err() != nil {
+			mu.Unlock()
+			_ = m.Close()
+			return
+		}
+		client = m
+		mu.Unlock()
+
+		var stdout, stderr bytes.Buffer
+		err = m.Run(command, &stdout, &stderr)
+		done <- result{stdout: stdout.String(), stderr: stderr.String(), err: err}
+	}()
+
+	select {
+	case r := <-done:
+		if client != nil {
+			_ = client.Close()
+		}
+		return r.stdout, r.stderr, r.err
+	case <-ctx.Done():
+		mu.Lock()
+		defer mu.Unlock()
+		if client != nil {
+			_ = client.Close()
+		}
+		return "", "", ctx.Err()
+	}
+}
+
+// clusterMachineExecTargets returns the worker nodes of the cluster which are selected by the given flags.
+func clusterMachineExecTargets(shoot *models.V1ClusterResponse, all bool, workerGroups, machineIDs []string) ([]*models.ModelsV1MachineResponse, error) {
+	for _, name := range workerGroups {
+		if !slices.ContainsFunc(shoot.Workers, func(w *models.V1Worker) bool { return pointer.SafeDeref(w.Name) == name }) {
+			return nil, fmt.Errorf("worker group %q not found in cluster", name)
+		}
+	}
+
+	var targets []*models.ModelsV1MachineResponse
+	for _, m := range shoot.Machines {
+		if m.Allocation == nil {
+			continue
+		}
+
+		switch {
+		case all:
+		case len(workerGroups) > 0:
+			if !slices.ContainsFunc(workerGroups, func(name string) bool {
+				return slices.Contains(m.Tags, fmt.Sprintf("%s=%s", constants.LabelWorkerPool, name))
+			}) {
+				continue
+			}
+		case len(machineIDs) > 0:
+			if !slices.Contains(machineIDs, pointer.SafeDeref(m.ID)) {
+				continue
+			}
+		}
+
+		targets = append(targets, m)
+	}
+
+	for _, id := range machineIDs {
+		if !slices.ContainsFunc(targets, func(m *models.ModelsV1MachineResponse) bool { return pointer.SafeDeref(m.ID) == id }) {
+			return nil, fmt.Errorf("worker node %q not found in cluster", id)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no worker nodes selected")
+	}
+
+	return targets, nil
+}
+
+// clusterMachineExecStatus returns the exit code of a remote command and an error message
+// if the command could not be run at all.
+func clusterMachineExecStatus(err error) (int, string) {
+	if err == nil {
+		return 0, ""
+	}
+
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), ""
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return -1, "timeout exceeded"
+	}
+
+	return -1, err.Error()
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testExitError struct {
+	status int
+}
+
+func (e *testExitError) Error() string {
+	return fmt.Sprintf("Process exited with status %d", e.status)
+}
+
+func (e *testExitError) ExitStatus() int {
+	return e.status
+}
+
+func Test_clusterMachineExecStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantExitCode int
+		wantMessage  string
+	}{
+		{
+			name: "succeeded",
+		},
+		{
+			name:         "command failed",
+			err:          &testExitError{status: 3},
+			wantExitCode: 3,
+		},
+		{
+			name:         "timeout",
+			err:          fmt.Errorf("wrapped: %w", context.DeadlineExceeded),
+			wantExitCode: -1,
+			wantMessage:  "timeout exceeded",
+		},
+		{
+			name:         "connection failed",
+			err:          errors.New("connection refused"),
+			wantExitCode: -1,
+			wantMessage:  "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode, message := clusterMachineExecStatus(tt.err)
+			if exitCode != tt.wantExitCode {
+				t.Errorf("clusterMachineExecStatus() exit code = %d, want %d", exitCode, tt.wantExitCode)
+			}
+			if message != tt.wantMessage {
+				t.Errorf("clusterMachineExecStatus() message = %q, want %q", message, tt.wantMessage)
+			}
+		})
+	}
+}
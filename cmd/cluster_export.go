@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+func (c *config) clusterExport(args []string) error {
+	id, err := c.clusterID("export", args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(id).WithReturnMachines(new(false)), nil)
+	if err != nil {
+		return err
+	}
+
+	scr := clusterCreateRequestFromResponse(resp.Payload)
+
+	var node yaml.Node
+	err = node.Encode(scr)
+	if err != nil {
+		return err
+	}
+	pruneNullYamlValues(&node)
+
+	filename := viper.GetString("file")
+	if filename == "" {
+		return encodeYaml(c.out, &node)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = encodeYaml(f, &node)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// clusterCreateRequestFromResponse converts a cluster into a create request, which can be used to create a
+// cluster with the same configuration. fields which are specific to the existing cluster are omitted:
+// egress ips are bound to the cluster, the seed is chosen by the scheduler and the kubernetes expiration
+// date is set by the api.
+func clusterCreateRequestFromResponse(current *models.V1ClusterResponse) *models.V1ClusterCreateRequest {
+	var k8s *models.V1Kubernetes
+	if current.Kubernetes != nil {
+		k := *current.Kubernetes
+		k.ExpirationDate = nil
+		k8s = &k
+	}
+
+	return &models.V1ClusterCreateRequest{
+		Name:                      current.Name,
+		Description:               current.Description,
+		ProjectID:                 current.ProjectID,
+		PartitionID:               current.PartitionID,
+		Purpose:                   current.Purpose,
+		Labels:                    current.Labels,
+		Workers:                   current.Workers,
+		Maintenance:               current.Maintenance,
+		Kubernetes:                k8s,
+		FirewallImage:             current.FirewallImage,
+		FirewallSize:              current.FirewallSize,
+		FirewallControllerVersion: current.FirewallControllerVersion,
+		AdditionalNetworks:        current.AdditionalNetworks,
+		KubeAPIServerACL:          current.KubeAPIServerACL,
+		ClusterFeatures:           current.ClusterFeatures,
+		CustomDefaultStorageClass: current.CustomDefaultStorageClass,
+		NetworkAccessType:         current.NetworkAccessType,
+		XDRConfig:                 current.XDRConfig,
+	}
+}
+
+func (c *config) clusterCreateFromFile() error {
+	var (
+		scr   *models.V1ClusterCreateRequest
+		count int
+	)
+	err := helper.ReadFrom(viper.GetString("from-file"), &models.V1ClusterCreateRequest{}, func(data any) {
+		count++
+		scr = data.(*models.V1ClusterCreateRequest)
+	})
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("%s must contain exactly one cluster create request, found %d", viper.GetString("from-file"), count)
+	}
+
+	if viper.IsSet("name") {
+		scr.Name = new(viper.GetString("name"))
+	}
+	if viper.IsSet("description") {
+		scr.Description = new(viper.GetString("description"))
+	}
+	if viper.IsSet("project") {
+		scr.ProjectID = new(viper.GetString("project"))
+	}
+	if viper.IsSet("partition") {
+		scr.PartitionID = new(viper.GetString("partition"))
+	}
+	if viper.IsSet("purpose") {
+		scr.Purpose = new(viper.GetString("purpose"))
+	}
+	if viper.IsSet("seed") {
+		scr.SeedName = viper.GetString("seed")
+	}
+
+	var errs []error
+	if scr.Name == nil || *scr.Name == "" {
+		errs = append(errs, errors.New("cluster name is missing, specify it in the file or with --name"))
+	}
+	if scr.ProjectID == nil || *scr.ProjectID == "" {
+		errs = append(errs, errors.New("cluster project is missing, specify it in the file or with --project"))
+	}
+	if scr.PartitionID == nil || *scr.PartitionID == "" {
+		errs = append(errs, errors.New("cluster partition is missing, specify it in the file or with --partition"))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	shoot, err := c.cloud.Cluster.CreateCluster(cluster.NewCreateClusterParams().WithBody(scr), nil)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("wait") {
+		return c.clusterWaitAndDescribe(*shoot.Payload.ID, clusterWaitForReady, time.Time{})
+	}
+
+	return c.describePrinter.Print(shoot.Payload)
+}
+
+// pruneNullYamlValues removes all mapping entries with null values, such that only set fields are exported.
+func pruneNullYamlValues(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		var content []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+				continue
+			}
+			content = append(content, key, value)
+		}
+		n.Content = content
+	}
+
+	for _, child := range n.Content {
+		pruneNullYamlValues(child)
+	}
+}
+
+func encodeYaml(w io.Writer, v any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	testclient "github.com/fi-ts/cloud-go/test/client"
+	"github.com/go-openapi/strfmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_pruneNullYamlValues(t *testing.T) {
+	var node yaml.Node
+	err := yaml.Unmarshal([]byte(`name: test
+description: null
+kubernetes:
+  version: 1.32.1
+  expirationdate: null
+workers:
+- name: group-0
+  labels: null
+`), &node)
+	require.NoError(t, err)
+
+	pruneNullYamlValues(&node)
+
+	var buf bytes.Buffer
+	err = encodeYaml(&buf, &node)
+	require.NoError(t, err)
+
+	want := `name: test
+kubernetes:
+  version: 1.32.1
+workers:
+  - name: group-0
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("pruneNullYamlValues() diff = %s", diff)
+	}
+}
+
+func Test_clusterExportRoundTrip(t *testing.T) {
+	var (
+		expiration = strfmt.DateTime(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+		file       = filepath.Join(t.TempDir(), "cluster.yaml")
+		workers    = []*models.V1Worker{
+			{
+				Name:           new("group-0"),
+				Minimum:        new(int32(1)),
+				Maximum:        new(int32(3)),
+				MaxSurge:       new("1"),
+				MaxUnavailable: new("0"),
+				MachineType:    new("c1-xlarge-x86"),
+				MachineImage:   &models.V1MachineImage{Name: new("ubuntu"), Version: new("24.04")},
+			},
+		}
+		current = &models.V1ClusterResponse{
+			ID:          new("c1"),
+			Name:        new("test"),
+			Description: new("a test cluster"),
+			ProjectID:   new("p1"),
+			PartitionID: new("dc1"),
+			Purpose:     new("production"),
+			Labels:      map[string]string{"team": "a"},
+			Workers:     workers,
+			Maintenance: &models.V1Maintenance{
+				TimeWindow: &models.V1MaintenanceTimeWindow{Begin: new("220000+0100"), End: new("233000+0100")},
+				AutoUpdate: &models.V1MaintenanceAutoUpdate{KubernetesVersion: new(true), MachineImage: new(false)},
+			},
+			Kubernetes: &models.V1Kubernetes{
+				Version:        new("1.32.1"),
+				ExpirationDate: &expiration,
+			},
+			FirewallImage:             new("firewall-ubuntu-3.0"),
+			FirewallSize:              new("n1-medium-x86"),
+			FirewallControllerVersion: new("v2.3.0"),
+			AdditionalNetworks:        []string{"internet"},
+			KubeAPIServerACL:          &models.V1KubeAPIServerACL{CIDRs: []string{"10.0.0.0/8"}, Disabled: new(false)},
+			EgressRules:               []*models.V1EgressRule{{NetworkID: new("internet"), IPs: []string{"1.2.3.4"}}},
+			ClusterFeatures:           &models.V1ClusterFeatures{LogAcceptedConnections: new("true"), DurosStorageEncryption: new("false")},
+			CustomDefaultStorageClass: &models.V1CustomDefaultStorageClass{ClassName: new("csi-lvm")},
+			NetworkAccessType:         new(models.V1ClusterCreateRequestNetworkAccessTypeBaseline),
+			XDRConfig:                 &models.V1XDR{Disabled: new(true)},
+		}
+		want = &models.V1ClusterCreateRequest{
+			Name:                      new("test"),
+			Description:               new("a test cluster"),
+			ProjectID:                 new("p1"),
+			PartitionID:               new("dc1"),
+			Purpose:                   new("production"),
+			Labels:                    map[string]string{"team": "a"},
+			Workers:                   workers,
+			Maintenance:               current.Maintenance,
+			Kubernetes:                &models.V1Kubernetes{Version: new("1.32.1")},
+			FirewallImage:             new("firewall-ubuntu-3.0"),
+			FirewallSize:              new("n1-medium-x86"),
+			FirewallControllerVersion: new("v2.3.0"),
+			AdditionalNetworks:        []string{"internet"},
+			KubeAPIServerACL:          current.KubeAPIServerACL,
+			ClusterFeatures:           current.ClusterFeatures,
+			CustomDefaultStorageClass: current.CustomDefaultStorageClass,
+			NetworkAccessType:         new(models.V1ClusterCreateRequestNetworkAccessTypeBaseline),
+			XDRConfig:                 &models.V1XDR{Disabled: new(true)},
+		}
+	)
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	mocks := &testclient.CloudMockFns{
+		Cluster: func(mock *mock.Mock) {
+			mock.On("FindCluster", testcommon.MatchIgnoreContext(t, cluster.NewFindClusterParams().WithID("c1").WithReturnMachines(new(false))), nil).
+				Return(&cluster.FindClusterOK{Payload: current}, nil)
+			mock.On("CreateCluster", testcommon.MatchIgnoreContext(t, cluster.NewCreateClusterParams().WithBody(want)), nil).
+				Return(&cluster.CreateClusterCreated{Payload: current}, nil)
+		},
+	}
+
+	var out bytes.Buffer
+	c := &config{
+		cloud:           testclient.NewCloudMockClient(t, mocks),
+		out:             &out,
+		describePrinter: printers.NewYAMLPrinter().WithOut(&out),
+	}
+
+	viper.Set("file", file)
+	require.NoError(t, c.clusterExport([]string{"c1"}))
+
+	viper.Set("from-file", file)
+	require.NoError(t, c.clusterCreateFromFile())
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type firewallCmd struct {
+	c *config
+}
+
+func newClusterFirewallCmd(c *config) *cobra.Command {
+	f := firewallCmd{
+		c: c,
+	}
+
+	clusterFirewallCmd := &cobra.Command{
+		Use:   "firewall",
+		Short: "tunnel connections through the firewall of a cluster",
+		Long:  "tunnels tcp connections through an ssh session to the firewall of a cluster, which is accessed through the vpn if available. this allows reaching internal services of the tenant network like node exporters or kubelets.",
+	}
+
+	forwardCmd := &cobra.Command{
+		Use:   "forward <clusterid>",
+		Short: "forward local ports to targets behind the firewall",
+		Example: `forward the node exporter of a worker node to localhost:9100:
+cloudctl cluster firewall forward <clusterid> -L 9100:10.0.0.5:9100`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return f.forward(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+
+	socksCmd := &cobra.Command{
+		Use:   "socks <clusterid>",
+		Short: "run a socks5 proxy to the network behind the firewall",
+		Example: `reach the kubelet of a worker node through the proxy:
+cloudctl cluster firewall socks <clusterid> --listen 127.0.0.1:1080
+curl --socks5 127.0.0.1:1080 -k https://10.0.0.5:10250/healthz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return f.socks(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+
+	clusterFirewallCmd.PersistentFlags().String("reason", "", "a short description why ssh access is required")
+
+	forwardCmd.Flags().StringArrayP("local", "L", nil, "local port forwarding in the format [bind_address:]port:host:hostport, can be given multiple times")
+	genericcli.Must(forwardCmd.MarkFlagRequired("local"))
+
+	socksCmd.Flags().String("listen", "127.0.0.1:1080", "the address to listen on for socks5 connections")
+
+	clusterFirewallCmd.AddCommand(forwardCmd, socksCmd)
+
+	return clusterFirewallCmd
+}
+
+func (f *firewallCmd) forward(args []string) error {
+	type forwarding struct {
+		listen string
+		target string
+	}
+
+	var forwardings []forwarding
+	for _, spec := range viper.GetStringSlice("local") {
+		listen, target, err := helper.ParseForwardSpec(spec)
+		if err != nil {
+			return err
+		}
+		forwardings = append(forwardings, forwarding{listen: listen, target: target})
+	}
+
+	return f.tunnel("forward", args, func(ctx context.Context, s *sshConnection) error {
+		var listeners []net.Listener
+		defer func() {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+		}()
+
+		errs := make(chan error, len(forwardings))
+		for _, fw := range forwardings {
+			l, err := net.Listen("tcp", fw.listen)
+			if err != nil {
+				return err
+			}
+			listeners = append(listeners, l)
+
+			fmt.Fprintf(os.Stderr, "forwarding %s to %s\n", l.Addr().String(), fw.target)
+
+			go func() {
+				errs <- helper.Forward(l, fw.target, s.tenantDial, f.c.log)
+			}()
+		}
+
+		return waitTunnel(ctx, s, errs)
+	})
+}
+
+func (f *firewallCmd) socks(args []string) error {
+	return f.tunnel("socks", args, func(ctx context.Context, s *sshConnection) error {
+		l, err := net.Listen("tcp", viper.GetString("listen"))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = l.Close()
+		}()
+
+		fmt.Fprintf(os.Stderr, "socks5 proxy listening on %s\n", l.Addr().String())
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- helper.ServeSOCKS5(l, s.tenantDial, f.c.log)
+		}()
+
+		return waitTunnel(ctx, s, errs)
+	})
+}
+
+// tunnel connects to a firewall of the cluster and calls serve with the connection, which is closed on interrupt.
+func (f *firewallCmd) tunnel(verb string, args []string, serve func(ctx context.Context, s *sshConnection) error) error {
+	cid, err := f.c.clusterID(verb, args)
+	if err != nil {
+		return err
+	}
+
+	shoot, err := f.c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(cid), nil)
+	if err != nil {
+		return err
+	}
+
+	keypair, err := f.c.sshKeyPair(cid, false, pointer.PointerOrNil(viper.GetString("reason")))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s, err := f.c.clusterFirewallSSH(ctx, shoot.Payload, keypair, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = s.Close()
+	}()
+
+	return serve(ctx, s)
+}
+
+// waitTunnel blocks until the tunnel is interrupted, the ssh connection is lost or one of the listeners fails.
+func waitTunnel(ctx context.Context, s *sshConnection, errs chan error) error {
+	closed := make(chan error, 1)
+	go func() {
+		closed <- s.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr, "closing tunnel")
+		return nil
+	case err := <-closed:
+		return errors.Join(errors.New("connection to the firewall was closed"), err)
+	case err := <-errs:
+		return err
+	}
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/fi-ts/cloudctl/pkg/api"
+	"github.com/gosimple/slug"
+	"github.com/metal-stack/metal-lib/auth"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	clusterKubeconfigStatusDeleted   = "deleted"
+	clusterKubeconfigStatusUntracked = "untracked"
+)
+
+type kubeconfigCmd struct {
+	c *config
+}
+
+func newClusterKubeconfigCmds(c *config) []*cobra.Command {
+	k := kubeconfigCmd{
+		c: c,
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "list the cluster contexts merged into the kubeconfig",
+		Long:    "lists the contexts which were merged into the kubeconfig with cloudctl along with the status of their clusters. contexts merged by older versions of cloudctl are matched to the clusters by their name and shown as untracked, or as deleted if no cluster of that name exists anymore. merge them again to track them.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.list()
+		},
+	}
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "remove the contexts of deleted clusters from the kubeconfig",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.prune()
+		},
+	}
+	mergeAllCmd := &cobra.Command{
+		Use:   "merge-all",
+		Short: "merge the kubeconfigs of all clusters of a project",
+		Long:  "merges the kubeconfigs of all clusters of a project into the current active kubeconfig. existing contexts of these clusters are refreshed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.mergeAll()
+		},
+	}
+
+	mergeAllCmd.Flags().String("project", "", "the project of the clusters to merge")
+	genericcli.Must(mergeAllCmd.MarkFlagRequired("project"))
+	genericcli.Must(mergeAllCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+
+	return []*cobra.Command{listCmd, pruneCmd, mergeAllCmd}
+}
+
+func (k *kubeconfigCmd) list() error {
+	contexts, _, _, err := k.contexts()
+	if err != nil {
+		return err
+	}
+
+	return k.c.listPrinter.Print(contexts)
+}
+
+func (k *kubeconfigCmd) prune() error {
+	contexts, kubeconfig, filename, err := k.contexts()
+	if err != nil {
+		return err
+	}
+
+	var (
+		prune   []string
+		deleted []*tableprinters.ClusterKubeconfigContext
+	)
+	for _, ctx := range contexts {
+		if ctx.Status != clusterKubeconfigStatusDeleted {
+			continue
+		}
+		prune = append(prune, ctx.Name)
+		deleted = append(deleted, ctx)
+	}
+
+	if len(prune) == 0 {
+		fmt.Fprintln(k.c.out, "no contexts of deleted clusters found")
+		return nil
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		err = k.c.listPrinter.Print(deleted)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(k.c.out, "\nthe %d context(s) listed above will be removed from %s.\n", len(prune), filename)
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	pruned, err := helper.RemoveKubeconfigContexts(kubeconfig, prune...)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filename, pruned, 0600)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range prune {
+		fmt.Fprintf(k.c.out, "%s removed context %q from %s\n", color.GreenString("✔"), name, filename)
+	}
+
+	return nil
+}
+
+func (k *kubeconfigCmd) mergeAll() error {
+	project := viper.GetString("project")
+
+	kubeconfigFile := viper.GetString("kubeconfig")
+	authContext, err := api.GetAuthContext(kubeconfigFile)
+	if err != nil {
+		return err
+	}
+	if !authContext.AuthProviderOidc {
+		return fmt.Errorf("active user %s has no oidc authProvider, check config", authContext.User)
+	}
+
+	fcp := cluster.NewFindClustersParams()
+	fcp.SetBody(&models.V1ClusterFindRequest{ProjectID: &project})
+	resp, err := k.c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return err
+	}
+	if len(resp.Payload) == 0 {
+		return fmt.Errorf("project %q has no clusters", project)
+	}
+
+	currentCfg, filename, _, err := auth.LoadKubeConfig(kubeconfigFile)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mergedKubeconfig []byte
+		extensions       = map[string]helper.KubeconfigContextExtension{}
+		errs             []error
+	)
+	for _, s := range resp.Payload {
+		request := cluster.NewGetClusterKubeconfigTplParams()
+		request.SetID(*s.ID)
+		credentials, err := k.c.cloud.Cluster.GetClusterKubeconfigTpl(request, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to fetch kubeconfig of cluster %q: %w", *s.Name, err))
+			continue
+		}
+
+		contextName := slug.Make(*s.Name)
+
+		merged, err := helper.MergeKubeconfigTpl(currentCfg, *credentials.Payload.Kubeconfig, contextName, *s.Name, authContext)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to merge kubeconfig of cluster %q: %w", *s.Name, err))
+			continue
+		}
+
+		mergedKubeconfig = merged
+		extensions[contextName] = helper.KubeconfigContextExtension{ClusterID: *s.ID, ProjectID: project}
+	}
+
+	if mergedKubeconfig != nil {
+		mergedKubeconfig, err = helper.SetKubeconfigContextExtensions(mergedKubeconfig, extensions)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filename, mergedKubeconfig, 0600)
+		if err != nil {
+			return err
+		}
+
+		for _, contextName := range slices.Sorted(maps.Keys(extensions)) {
+			fmt.Fprintf(k.c.out, "%s merged context %q into %s\n", color.GreenString("✔"), contextName, filename)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// contexts returns the cloudctl contexts of the kubeconfig with the status of their clusters.
+func (k *kubeconfigCmd) contexts() ([]*tableprinters.ClusterKubeconfigContext, []byte, string, error) {
+	kubeconfigFile := viper.GetString("kubeconfig")
+	authContext, err := api.GetAuthContext(kubeconfigFile)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	cfg, filename, _, err := auth.LoadKubeConfig(kubeconfigFile)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	kubeconfig, err := auth.EncodeKubeconfig(cfg)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	contexts, err := helper.KubeconfigContexts(kubeconfig.Bytes())
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	current, _ := cfg["current-context"].(string)
+
+	var (
+		result   []*tableprinters.ClusterKubeconfigContext
+		clusters []*models.V1ClusterResponse
+	)
+	for _, ctx := range contexts {
+		if ctx.Extension == nil {
+			// contexts merged by older versions of cloudctl use the cloud user
+			if ctx.User != authContext.User || ctx.Name == authContext.Ctx {
+				continue
+			}
+
+			if clusters == nil {
+				resp, err := k.c.cloud.Cluster.ListClusters(cluster.NewListClustersParams().WithReturnMachines(new(false)), nil)
+				if err != nil {
+					return nil, nil, "", err
+				}
+				clusters = resp.Payload
+			}
+
+			legacy := clusterKubeconfigLegacyContext(ctx, clusters)
+			legacy.Current = ctx.Name == current
+			result = append(result, legacy)
+			continue
+		}
+
+		status, err := k.clusterStatus(ctx.Extension.ClusterID)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		result = append(result, &tableprinters.ClusterKubeconfigContext{
+			Name:      ctx.Name,
+			Current:   ctx.Name == current,
+			ClusterID: ctx.Extension.ClusterID,
+			ProjectID: ctx.Extension.ProjectID,
+			Status:    status,
+		})
+	}
+
+	return result, kubeconfig.Bytes(), filename, nil
+}
+
+// clusterKubeconfigLegacyContext matches a context without the cloudctl extension to the clusters by its name.
+// older versions of cloudctl named the cluster entry after the cluster and the context after the slug of its name.
+// the context belongs to a deleted cluster if no cluster matches, it is kept untracked if the match is ambiguous.
+func clusterKubeconfigLegacyContext(ctx helper.KubeconfigContext, clusters []*models.V1ClusterResponse) *tableprinters.ClusterKubeconfigContext {
+	var matches []*models.V1ClusterResponse
+	for _, s := range clusters {
+		name := pointer.SafeDeref(s.Name)
+		if name != "" && (ctx.Cluster == name || ctx.Name == slug.Make(name)) {
+			matches = append(matches, s)
+		}
+	}
+
+	result := &tableprinters.ClusterKubeconfigContext{
+		Name:   ctx.Name,
+		Status: clusterKubeconfigStatusUntracked,
+	}
+
+	switch len(matches) {
+	case 0:
+		result.Status = clusterKubeconfigStatusDeleted
+	case 1:
+		result.ClusterID = pointer.SafeDeref(matches[0].ID)
+		result.ProjectID = pointer.SafeDeref(matches[0].ProjectID)
+	}
+
+	return result
+}
+
+func (k *kubeconfigCmd) clusterStatus(id string) (string, error) {
+	resp, err := k.c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(id).WithReturnMachines(new(false)), nil)
+	if err != nil {
+		var r *cluster.FindClusterDefault
+		if errors.As(err, &r) && r.Code() == http.StatusNotFound {
+			return clusterKubeconfigStatusDeleted, nil
+		}
+		return "", err
+	}
+
+	if resp.Payload.Status == nil || resp.Payload.Status.LastOperation == nil {
+		return "", nil
+	}
+
+	op := resp.Payload.Status.LastOperation
+
+	return fmt.Sprintf("%s %s", pointer.SafeDeref(op.Type), pointer.SafeDeref(op.State)), nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_clusterKubeconfigLegacyContext(t *testing.T) {
+	clusters := []*models.V1ClusterResponse{
+		{ID: new("c1"), Name: new("Prod Cluster"), ProjectID: new("p1")},
+		{ID: new("c2"), Name: new("staging"), ProjectID: new("p1")},
+		{ID: new("c3"), Name: new("staging"), ProjectID: new("p2")},
+	}
+
+	tests := []struct {
+		name string
+		ctx  helper.KubeconfigContext
+		want *tableprinters.ClusterKubeconfigContext
+	}{
+		{
+			name: "matched by the cluster entry",
+			ctx:  helper.KubeconfigContext{Name: "renamed", Cluster: "Prod Cluster"},
+			want: &tableprinters.ClusterKubeconfigContext{Name: "renamed", ClusterID: "c1", ProjectID: "p1", Status: "untracked"},
+		},
+		{
+			name: "matched by the context name",
+			ctx:  helper.KubeconfigContext{Name: "prod-cluster", Cluster: "other"},
+			want: &tableprinters.ClusterKubeconfigContext{Name: "prod-cluster", ClusterID: "c1", ProjectID: "p1", Status: "untracked"},
+		},
+		{
+			name: "ambiguous name",
+			ctx:  helper.KubeconfigContext{Name: "staging", Cluster: "staging"},
+			want: &tableprinters.ClusterKubeconfigContext{Name: "staging", Status: "untracked"},
+		},
+		{
+			name: "cluster was deleted",
+			ctx:  helper.KubeconfigContext{Name: "dev", Cluster: "dev"},
+			want: &tableprinters.ClusterKubeconfigContext{Name: "dev", Status: "deleted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterKubeconfigLegacyContext(tt.ctx, clusters)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type clusterReportCmd struct {
+	c *config
+}
+
+func newClusterReportCmd(c *config) *cobra.Command {
+	r := clusterReportCmd{
+		c: c,
+	}
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "fleet-wide reports over clusters",
+	}
+
+	versionsCmd := &cobra.Command{
+		Use:   "versions",
+		Short: "lists clusters running on expiring or deprecated versions",
+		Long: `lists every cluster component that runs on a kubernetes version, machine image or firewall image which is expired, expires soon or is not offered anymore by the cluster constraints of the partition (deprecated).
+
+the findings are grouped by project, use --csv or --output-format json for further processing.`,
+		Example: `cloudctl cluster report versions --tenant fits --purpose production --csv > report.csv`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return r.versions()
+		},
+	}
+
+	versionsCmd.Flags().String("project", "", "show clusters of given project")
+	versionsCmd.Flags().String("partition", "", "show clusters in partition")
+	versionsCmd.Flags().String("tenant", "", "show clusters of given tenant")
+	versionsCmd.Flags().String("purpose", "", "show clusters of given purpose")
+	versionsCmd.Flags().Int("expires-within", 14, "report versions which expire within the given amount of days")
+	versionsCmd.Flags().Bool("csv", false, "print the report as csv")
+	genericcli.Must(versionsCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+	genericcli.Must(versionsCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
+	genericcli.Must(versionsCmd.RegisterFlagCompletionFunc("tenant", c.comp.TenantListCompletion))
+	genericcli.Must(versionsCmd.RegisterFlagCompletionFunc("purpose", c.comp.ClusterPurposeListCompletion))
+
+	reportCmd.AddCommand(versionsCmd)
+
+	return reportCmd
+}
+
+func (r *clusterReportCmd) versions() error {
+	cfr := &models.V1ClusterFindRequest{}
+	if project := viper.GetString("project"); project != "" {
+		cfr.ProjectID = &project
+	}
+	if partition := viper.GetString("partition"); partition != "" {
+		cfr.PartitionID = &partition
+	}
+	if tenant := viper.GetString("tenant"); tenant != "" {
+		cfr.Tenant = &tenant
+	}
+	if purpose := viper.GetString("purpose"); purpose != "" {
+		cfr.Purpose = &purpose
+	}
+
+	fcp := cluster.NewFindClustersParams().WithReturnMachines(new(true))
+	fcp.SetBody(cfr)
+	resp, err := r.c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return err
+	}
+
+	var (
+		expiresWithin = time.Duration(viper.GetInt("expires-within")) * 24 * time.Hour
+		inputs        = map[string]*clusterUpgradeInputs{}
+		reports       = map[string]*tableprinters.ClusterVersionReport{}
+	)
+
+	for _, s := range resp.Payload {
+		partition := pointer.SafeDeref(s.PartitionID)
+
+		in, ok := inputs[partition]
+		if !ok {
+			in, err = r.c.clusterUpgradeInputs(partition)
+			if err != nil {
+				return fmt.Errorf("unable to fetch cluster constraints of partition %q: %w", partition, err)
+			}
+			inputs[partition] = in
+		}
+
+		findings := clusterVersionFindings(s, in, expiresWithin)
+		if len(findings) == 0 {
+			continue
+		}
+
+		project := pointer.SafeDeref(s.ProjectID)
+		report, ok := reports[project]
+		if !ok {
+			report = &tableprinters.ClusterVersionReport{
+				ProjectID: project,
+				Tenant:    pointer.SafeDeref(s.Tenant),
+			}
+			reports[project] = report
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	var result []*tableprinters.ClusterVersionReport
+	for _, report := range reports {
+		slices.SortStableFunc(report.Findings, func(a, b *tableprinters.ClusterVersionFinding) int {
+			return strings.Compare(a.ClusterName, b.ClusterName)
+		})
+		result = append(result, report)
+	}
+	slices.SortFunc(result, func(a, b *tableprinters.ClusterVersionReport) int {
+		return strings.Compare(a.ProjectID, b.ProjectID)
+	})
+
+	if viper.GetBool("csv") {
+		w := csv.NewWriter(r.c.out)
+		if err := w.Write(tableprinters.ClusterVersionReportHeader); err != nil {
+			return err
+		}
+		return w.WriteAll(tableprinters.ClusterVersionReportRows(result))
+	}
+
+	return r.c.listPrinter.Print(result)
+}
+
+// clusterVersionFindings returns the components of a cluster running on expired, expiring or deprecated versions.
+func clusterVersionFindings(s *models.V1ClusterResponse, inputs *clusterUpgradeInputs, expiresWithin time.Duration) []*tableprinters.ClusterVersionFinding {
+	var (
+		findings []*tableprinters.ClusterVersionFinding
+		add      = func(component, workerGroup, version string, expiration *time.Time, available []string) {
+			if version == "" {
+				return
+			}
+
+			var status []string
+			if expiration != nil {
+				until := time.Until(*expiration)
+				switch {
+				case until <= 0:
+					status = append(status, tableprinters.ClusterVersionStatusExpired)
+				case until < expiresWithin:
+					status = append(status, tableprinters.ClusterVersionStatusExpiring)
+				}
+			}
+			if len(available) > 0 && !slices.Contains(available, version) {
+				status = append(status, tableprinters.ClusterVersionStatusDeprecated)
+			}
+			if len(status) == 0 {
+				return
+			}
+
+			findings = append(findings, &tableprinters.ClusterVersionFinding{
+				ClusterID:   pointer.SafeDeref(s.ID),
+				ClusterName: pointer.SafeDeref(s.Name),
+				Partition:   pointer.SafeDeref(s.PartitionID),
+				Purpose:     pointer.SafeDeref(s.Purpose),
+				Component:   component,
+				WorkerGroup: workerGroup,
+				Version:     version,
+				Expires:     expiration,
+				Status:      status,
+			})
+		}
+	)
+
+	if s.Kubernetes != nil {
+		add(clusterUpgradeComponentKubernetes, "", pointer.SafeDeref(s.Kubernetes.Version), kubernetesExpiration(s), inputs.kubernetesVersions)
+	}
+
+	for _, w := range s.Workers {
+		name := pointer.SafeDeref(w.Name)
+
+		add(clusterUpgradeComponentWorkerKubernetes, name, pointer.SafeDeref(w.KubernetesVersion), nil, inputs.kubernetesVersions)
+
+		if w.MachineImage != nil {
+			var (
+				imageName = pointer.SafeDeref(w.MachineImage.Name)
+				available []string
+			)
+			for _, v := range inputs.machineImages[imageName] {
+				available = append(available, imageName+"-"+v)
+			}
+			add(clusterUpgradeComponentMachineImage, name, imageName+"-"+pointer.SafeDeref(w.MachineImage.Version), workerGroupImageExpiration(s, name), available)
+		}
+	}
+
+	add(clusterUpgradeComponentFirewallImage, "", pointer.SafeDeref(s.FirewallImage), firewallImageExpiration(s), inputs.firewallImages)
+
+	return findings
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	testclient "github.com/fi-ts/cloud-go/test/client"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/go-openapi/strfmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// the dates are far apart from now, so that the tests do not depend on the current time
+	reportExpired  = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reportExpiring = time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reportValid    = time.Date(2200, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	reportExpiresWithin = 100 * 365 * 24 * time.Hour
+)
+
+func Test_clusterVersionFindings(t *testing.T) {
+	inputs := &clusterUpgradeInputs{
+		kubernetesVersions: []string{"1.30.5", "1.31.2"},
+		machineImages:      map[string][]string{"ubuntu": {"22.04"}},
+		firewallImages:     []string{"firewall-ubuntu-3.0"},
+	}
+
+	image := func(expiration time.Time) *models.ModelsV1MachineAllocation {
+		return &models.ModelsV1MachineAllocation{Image: &models.ModelsV1ImageResponse{ExpirationDate: new(expiration.Format(time.RFC3339))}}
+	}
+	current := func(modify func(s *models.V1ClusterResponse)) *models.V1ClusterResponse {
+		s := &models.V1ClusterResponse{
+			ID:          new("c1"),
+			Name:        new("test"),
+			PartitionID: new("dc1"),
+			Purpose:     new("production"),
+			Kubernetes: &models.V1Kubernetes{
+				Version:        new("1.30.5"),
+				ExpirationDate: new(strfmt.DateTime(reportValid)),
+			},
+			Workers: []*models.V1Worker{
+				{Name: new("group-a"), MachineImage: &models.V1MachineImage{Name: new("ubuntu"), Version: new("22.04")}},
+			},
+			FirewallImage: new("firewall-ubuntu-3.0"),
+			Machines: []*models.ModelsV1MachineResponse{
+				{ID: new("m1"), Tags: []string{"worker.gardener.cloud/pool=group-a"}, Allocation: image(reportValid)},
+			},
+			Firewalls: []*models.ModelsV1MachineResponse{
+				{ID: new("fw1"), Allocation: image(reportValid)},
+			},
+		}
+		modify(s)
+		return s
+	}
+	finding := func(component, workerGroup, version string, expires *time.Time, status ...string) *tableprinters.ClusterVersionFinding {
+		return &tableprinters.ClusterVersionFinding{
+			ClusterID:   "c1",
+			ClusterName: "test",
+			Partition:   "dc1",
+			Purpose:     "production",
+			Component:   component,
+			WorkerGroup: workerGroup,
+			Version:     version,
+			Expires:     expires,
+			Status:      status,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		current *models.V1ClusterResponse
+		want    []*tableprinters.ClusterVersionFinding
+	}{
+		{
+			name:    "up to date",
+			current: current(func(s *models.V1ClusterResponse) {}),
+		},
+		{
+			name: "kubernetes expired",
+			current: current(func(s *models.V1ClusterResponse) {
+				s.Kubernetes.ExpirationDate = new(strfmt.DateTime(reportExpired))
+			}),
+			want: []*tableprinters.ClusterVersionFinding{
+				finding(clusterUpgradeComponentKubernetes, "", "1.30.5", &reportExpired, tableprinters.ClusterVersionStatusExpired),
+			},
+		},
+		{
+			name: "kubernetes expiring and deprecated",
+			current: current(func(s *models.V1ClusterResponse) {
+				s.Kubernetes.Version = new("1.29.8")
+				s.Kubernetes.ExpirationDate = new(strfmt.DateTime(reportExpiring))
+			}),
+			want: []*tableprinters.ClusterVersionFinding{
+				finding(clusterUpgradeComponentKubernetes, "", "1.29.8", &reportExpiring, tableprinters.ClusterVersionStatusExpiring, tableprinters.ClusterVersionStatusDeprecated),
+			},
+		},
+		{
+			name: "worker group",
+			current: current(func(s *models.V1ClusterResponse) {
+				s.Workers[0].KubernetesVersion = new("1.29.8")
+				s.Workers[0].MachineImage.Version = new("20.04")
+				s.Machines[0].Allocation = image(reportExpired)
+			}),
+			want: []*tableprinters.ClusterVersionFinding{
+				finding(clusterUpgradeComponentWorkerKubernetes, "group-a", "1.29.8", nil, tableprinters.ClusterVersionStatusDeprecated),
+				finding(clusterUpgradeComponentMachineImage, "group-a", "ubuntu-20.04", &reportExpired, tableprinters.ClusterVersionStatusExpired, tableprinters.ClusterVersionStatusDeprecated),
+			},
+		},
+		{
+			name: "firewall image",
+			current: current(func(s *models.V1ClusterResponse) {
+				s.FirewallImage = new("firewall-ubuntu-2.0")
+			}),
+			want: []*tableprinters.ClusterVersionFinding{
+				finding(clusterUpgradeComponentFirewallImage, "", "firewall-ubuntu-2.0", &reportValid, tableprinters.ClusterVersionStatusDeprecated),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterVersionFindings(tt.current, inputs, reportExpiresWithin)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_clusterReportCmd_versions(t *testing.T) {
+	clusters := []*models.V1ClusterResponse{
+		{
+			ID: new("c1"), Name: new("prod"), ProjectID: new("p2"), Tenant: new("fits"), PartitionID: new("dc1"), Purpose: new("production"),
+			Kubernetes:    &models.V1Kubernetes{Version: new("1.30.5"), ExpirationDate: new(strfmt.DateTime(reportExpired))},
+			FirewallImage: new("firewall-ubuntu-3.0"),
+		},
+		{
+			ID: new("c2"), Name: new("api"), ProjectID: new("p2"), Tenant: new("fits"), PartitionID: new("dc2"), Purpose: new("production"),
+			Kubernetes:    &models.V1Kubernetes{Version: new("1.30.5")},
+			FirewallImage: new("firewall-ubuntu-2.0"),
+		},
+		{
+			ID: new("c3"), Name: new("ok"), ProjectID: new("p1"), Tenant: new("fits"), PartitionID: new("dc1"), Purpose: new("production"),
+			Kubernetes:    &models.V1Kubernetes{Version: new("1.30.5")},
+			FirewallImage: new("firewall-ubuntu-3.0"),
+		},
+		{
+			ID: new("c4"), Name: new("web"), ProjectID: new("p3"), Tenant: new("fits"), PartitionID: new("dc1"), Purpose: new("evaluation"),
+			Kubernetes:    &models.V1Kubernetes{Version: new("1.29.8"), ExpirationDate: new(strfmt.DateTime(reportExpired))},
+			FirewallImage: new("firewall-ubuntu-3.0"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		csv      bool
+		want     []*tableprinters.ClusterVersionReport
+		wantText string
+	}{
+		{
+			name: "grouped by project",
+			want: []*tableprinters.ClusterVersionReport{
+				{
+					ProjectID: "p2",
+					Tenant:    "fits",
+					Findings: []*tableprinters.ClusterVersionFinding{
+						{ClusterID: "c2", ClusterName: "api", Partition: "dc2", Purpose: "production", Component: clusterUpgradeComponentFirewallImage, Version: "firewall-ubuntu-2.0", Status: []string{tableprinters.ClusterVersionStatusDeprecated}},
+						{ClusterID: "c1", ClusterName: "prod", Partition: "dc1", Purpose: "production", Component: clusterUpgradeComponentKubernetes, Version: "1.30.5", Expires: &reportExpired, Status: []string{tableprinters.ClusterVersionStatusExpired}},
+					},
+				},
+				{
+					ProjectID: "p3",
+					Tenant:    "fits",
+					Findings: []*tableprinters.ClusterVersionFinding{
+						{ClusterID: "c4", ClusterName: "web", Partition: "dc1", Purpose: "evaluation", Component: clusterUpgradeComponentKubernetes, Version: "1.29.8", Expires: &reportExpired, Status: []string{tableprinters.ClusterVersionStatusExpired, tableprinters.ClusterVersionStatusDeprecated}},
+					},
+				},
+			},
+		},
+		{
+			name: "csv",
+			csv:  true,
+			wantText: `Project,Tenant,ID,Name,Partition,Purpose,Component,Worker Group,Version,Expires,Status
+p2,fits,c2,api,dc2,production,firewall-image,,firewall-ubuntu-2.0,,deprecated
+p2,fits,c1,prod,dc1,production,kubernetes,,1.30.5,2000-01-01,expired
+p3,fits,c4,web,dc1,evaluation,kubernetes,,1.29.8,2000-01-01,"expired,deprecated"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("tenant", "fits")
+			viper.Set("expires-within", 14)
+			viper.Set("csv", tt.csv)
+
+			var (
+				out         bytes.Buffer
+				clusterMock *mock.Mock
+			)
+			r := clusterReportCmd{c: &config{
+				cloud: testclient.NewCloudMockClient(t, &testclient.CloudMockFns{
+					Cluster: func(m *mock.Mock) {
+						clusterMock = m
+						m.On("FindClusters", testcommon.MatchIgnoreContext(t, cluster.NewFindClustersParams().WithReturnMachines(new(true)).WithBody(&models.V1ClusterFindRequest{
+							Tenant: new("fits"),
+						})), nil).Return(&cluster.FindClustersOK{Payload: clusters}, nil)
+
+						// the constraints are only fetched once per partition
+						for _, partition := range []string{"dc1", "dc2"} {
+							m.On("ListConstraints", testcommon.MatchIgnoreContext(t, cluster.NewListConstraintsParams().WithPartition(new(partition))), nil).
+								Return(&cluster.ListConstraintsOK{Payload: &models.V1ClusterConstraints{
+									KubernetesVersions: []string{"1.30.5"},
+									FirewallImages:     []string{"firewall-ubuntu-3.0"},
+								}}, nil).Once()
+						}
+					},
+				}),
+				out:         &out,
+				listPrinter: printers.NewJSONPrinter().WithOut(&out),
+			}}
+
+			require.NoError(t, r.versions())
+			clusterMock.AssertExpectations(t)
+
+			if tt.csv {
+				if diff := cmp.Diff(tt.wantText, out.String()); diff != "" {
+					t.Errorf("diff (+got -want):\n %s", diff)
+				}
+				return
+			}
+
+			var got []*tableprinters.ClusterVersionReport
+			require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
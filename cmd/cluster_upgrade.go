@@ -0,0 +1,540 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+const (
+	// the worker version may only be two minor versions older than the api server (kubernetes version skew policy)
+	clusterWorkerVersionMaxSkew = 2
+
+	clusterUpgradeComponentKubernetes         = "kubernetes"
+	clusterUpgradeComponentWorkerKubernetes   = "worker-kubernetes"
+	clusterUpgradeComponentMachineImage       = "machine-image"
+	clusterUpgradeComponentFirewallImage      = "firewall-image"
+	clusterUpgradeComponentFirewallController = "firewall-controller"
+)
+
+// clusterUpgradeInputs are the versions from the cluster constraints relevant for an upgrade.
+type clusterUpgradeInputs struct {
+	kubernetesVersions         []string
+	machineImages              map[string][]string
+	firewallImages             []string
+	firewallControllerVersions []string
+}
+
+type clusterUpgradeStep struct {
+	*tableprinters.ClusterUpgradeStep
+	// apply adds the changes of this step to the update request based on the current state of the cluster
+	apply func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse)
+}
+
+func (c *config) clusterUpgradePlan(args []string) error {
+	ci, err := c.clusterID("upgrade-plan", args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(ci), nil)
+	if err != nil {
+		return err
+	}
+	current := resp.Payload
+
+	inputs, err := c.clusterUpgradeInputs(pointer.SafeDeref(current.PartitionID))
+	if err != nil {
+		return err
+	}
+
+	plan := newClusterUpgradePlan(current, inputs, time.Duration(viper.GetInt("expires-within"))*24*time.Hour)
+
+	var rows []*tableprinters.ClusterUpgradeStep
+	for _, step := range plan {
+		rows = append(rows, step.ClusterUpgradeStep)
+	}
+
+	if !viper.GetBool("execute") {
+		return c.listPrinter.Print(rows)
+	}
+
+	var selected []*clusterUpgradeStep
+	for _, step := range plan {
+		if step.apply == nil {
+			continue
+		}
+		if viper.IsSet("steps") && !slices.Contains(viper.GetIntSlice("steps"), step.Step) {
+			continue
+		}
+		selected = append(selected, step)
+	}
+
+	if len(selected) == 0 {
+		fmt.Fprintf(c.out, "cluster %q is up-to-date, nothing to execute\n", *current.Name)
+		return nil
+	}
+	if len(selected) > 1 && !viper.GetBool("wait") {
+		return fmt.Errorf("executing multiple upgrade steps requires --wait as the steps need to be executed one after another")
+	}
+
+	err = c.listPrinter.Print(rows)
+	if err != nil {
+		return err
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		fmt.Printf("\nExecuting %d upgrade step(s) on cluster:%q, be aware that this may roll worker nodes and firewalls.\n", len(selected), *current.Name)
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, step := range selected {
+		resp, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(ci), nil)
+		if err != nil {
+			return err
+		}
+
+		cur := &models.V1ClusterUpdateRequest{
+			ID: resp.Payload.ID,
+		}
+		step.apply(cur, resp.Payload)
+
+		fmt.Fprintf(c.out, "executing step %d: updating %s from %s to %s\n", step.Step, step.Component, step.Current, step.Next)
+
+		since := time.Now()
+		_, err = c.cloud.Cluster.UpdateCluster(cluster.NewUpdateClusterParams().WithBody(cur), nil)
+		if err != nil {
+			return fmt.Errorf("step %d failed: %w", step.Step, err)
+		}
+
+		if viper.GetBool("wait") {
+			_, err = c.clusterWait(ci, clusterWaitForOperationSucceeded, since, viper.GetDuration("timeout"))
+			if err != nil {
+				return fmt.Errorf("step %d failed: %w", step.Step, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *config) clusterUpgradeInputs(partition string) (*clusterUpgradeInputs, error) {
+	request := cluster.NewListConstraintsParams()
+	if partition != "" {
+		request.WithPartition(&partition)
+	}
+	sc, err := c.cloud.Cluster.ListConstraints(request, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := &clusterUpgradeInputs{
+		kubernetesVersions: sc.Payload.KubernetesVersions,
+		machineImages:      map[string][]string{},
+		firewallImages:     sc.Payload.FirewallImages,
+	}
+	for _, i := range sc.Payload.MachineImages {
+		name := pointer.SafeDeref(i.Name)
+		inputs.machineImages[name] = append(inputs.machineImages[name], pointer.SafeDeref(i.Version))
+	}
+	for _, v := range sc.Payload.FirewallControllerVersions {
+		if v.Version == nil {
+			continue
+		}
+		inputs.firewallControllerVersions = append(inputs.firewallControllerVersions, *v.Version)
+	}
+
+	return inputs, nil
+}
+
+// newClusterUpgradePlan returns the next valid upgrade steps of the cluster in the order in which they should be executed.
+// components without an upgrade are only contained in the plan when they expire.
+func newClusterUpgradePlan(current *models.V1ClusterResponse, inputs *clusterUpgradeInputs, expiresWithin time.Duration) []*clusterUpgradeStep {
+	var (
+		plan []*clusterUpgradeStep
+		add  = func(s *tableprinters.ClusterUpgradeStep, apply func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse)) {
+			if s.Next == "" && !s.ExpiresSoon {
+				return
+			}
+			if s.Next == "" {
+				s.Note = "no newer version available"
+				apply = nil
+			}
+			plan = append(plan, &clusterUpgradeStep{ClusterUpgradeStep: s, apply: apply})
+		}
+	)
+
+	// kubernetes control plane
+	var k8sVersion string
+	if current.Kubernetes != nil {
+		k8sVersion = pointer.SafeDeref(current.Kubernetes.Version)
+	}
+	expires, soon := expirationInfo(kubernetesExpiration(current), expiresWithin)
+
+	patch, minor := nextKubernetesVersions(k8sVersion, inputs.kubernetesVersions)
+
+	controlPlaneVersion := k8sVersion
+	if patch != "" {
+		add(&tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentKubernetes,
+			Current:     k8sVersion,
+			Next:        patch,
+			Expires:     expires,
+			ExpiresSoon: soon,
+			Note:        "patch update",
+		}, setKubernetesVersion(patch))
+		controlPlaneVersion = patch
+		expires, soon = "", false
+	}
+
+	// worker groups with a custom kubernetes version need to follow the version skew policy
+	var blockingWorkers []string
+	for _, w := range current.Workers {
+		workerVersion := pointer.SafeDeref(w.KubernetesVersion)
+		if workerVersion == "" {
+			continue
+		}
+
+		next := nextWorkerKubernetesVersion(workerVersion, controlPlaneVersion, inputs.kubernetesVersions)
+		note := "custom worker version"
+		if minor != "" && kubernetesMinorSkew(minor, workerVersion) > clusterWorkerVersionMaxSkew {
+			note = "required before kubernetes minor update"
+			if next == "" || kubernetesMinorSkew(minor, next) > clusterWorkerVersionMaxSkew {
+				blockingWorkers = append(blockingWorkers, pointer.SafeDeref(w.Name))
+			}
+		}
+		if next == "" {
+			continue
+		}
+
+		add(&tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentWorkerKubernetes,
+			WorkerGroup: pointer.SafeDeref(w.Name),
+			Current:     workerVersion,
+			Next:        next,
+			Note:        note,
+		}, setWorkerKubernetesVersion(pointer.SafeDeref(w.Name), next))
+	}
+
+	switch {
+	case minor != "" && len(blockingWorkers) > 0:
+		plan = append(plan, &clusterUpgradeStep{ClusterUpgradeStep: &tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentKubernetes,
+			Current:     controlPlaneVersion,
+			Next:        minor,
+			Expires:     expires,
+			ExpiresSoon: soon,
+			Note:        fmt.Sprintf("blocked by version skew of worker group(s) %s", strings.Join(blockingWorkers, ",")),
+		}})
+	case minor != "":
+		add(&tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentKubernetes,
+			Current:     controlPlaneVersion,
+			Next:        minor,
+			Expires:     expires,
+			ExpiresSoon: soon,
+			Note:        "minor update",
+		}, setKubernetesVersion(minor))
+	case patch == "":
+		add(&tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentKubernetes,
+			Current:     k8sVersion,
+			Expires:     expires,
+			ExpiresSoon: soon,
+		}, nil)
+	}
+
+	// worker machine images
+	for _, w := range current.Workers {
+		if w.MachineImage == nil {
+			continue
+		}
+		var (
+			name    = pointer.SafeDeref(w.MachineImage.Name)
+			version = pointer.SafeDeref(w.MachineImage.Version)
+			next    = latestVersion(version, inputs.machineImages[name])
+		)
+		expires, soon := expirationInfo(workerGroupImageExpiration(current, pointer.SafeDeref(w.Name)), expiresWithin)
+
+		step := &tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentMachineImage,
+			WorkerGroup: pointer.SafeDeref(w.Name),
+			Current:     name + "-" + version,
+			Expires:     expires,
+			ExpiresSoon: soon,
+			Note:        "rolls worker nodes",
+		}
+		if next != "" {
+			step.Next = name + "-" + next
+		}
+		add(step, setWorkerMachineImage(pointer.SafeDeref(w.Name), name, next))
+	}
+
+	// firewall image
+	if firewallImage := pointer.SafeDeref(current.FirewallImage); firewallImage != "" {
+		expires, soon := expirationInfo(firewallImageExpiration(current), expiresWithin)
+
+		name, version := splitImageVersion(firewallImage)
+		var candidates []string
+		for _, image := range inputs.firewallImages {
+			n, v := splitImageVersion(image)
+			if n == name {
+				candidates = append(candidates, v)
+			}
+		}
+
+		step := &tableprinters.ClusterUpgradeStep{
+			Component:   clusterUpgradeComponentFirewallImage,
+			Current:     firewallImage,
+			Expires:     expires,
+			ExpiresSoon: soon,
+			Note:        "causes downtime",
+		}
+		if next := latestVersion(version, candidates); next != "" {
+			step.Next = name + "-" + next
+		}
+		next := step.Next
+		add(step, func(cur *models.V1ClusterUpdateRequest, _ *models.V1ClusterResponse) {
+			cur.FirewallImage = &next
+		})
+	}
+
+	// firewall controller, which is updated automatically when set to auto
+	if fwcv := pointer.SafeDeref(current.FirewallControllerVersion); fwcv != "" && fwcv != "auto" {
+		next := latestVersion(fwcv, inputs.firewallControllerVersions)
+		add(&tableprinters.ClusterUpgradeStep{
+			Component: clusterUpgradeComponentFirewallController,
+			Current:   fwcv,
+			Next:      next,
+		}, func(cur *models.V1ClusterUpdateRequest, _ *models.V1ClusterResponse) {
+			cur.FirewallControllerVersion = &next
+		})
+	}
+
+	step := 0
+	for _, s := range plan {
+		if s.apply == nil {
+			continue
+		}
+		step++
+		s.Step = step
+	}
+
+	return plan
+}
+
+func setKubernetesVersion(version string) func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse) {
+	return func(cur *models.V1ClusterUpdateRequest, _ *models.V1ClusterResponse) {
+		cur.Kubernetes = &models.V1Kubernetes{
+			Version: &version,
+		}
+	}
+}
+
+func setWorkerKubernetesVersion(workerGroup, version string) func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse) {
+	return func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse) {
+		cur.Workers = current.Workers
+		for _, w := range cur.Workers {
+			if pointer.SafeDeref(w.Name) == workerGroup {
+				w.KubernetesVersion = &version
+			}
+		}
+	}
+}
+
+func setWorkerMachineImage(workerGroup, name, version string) func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse) {
+	return func(cur *models.V1ClusterUpdateRequest, current *models.V1ClusterResponse) {
+		cur.Workers = current.Workers
+		for _, w := range cur.Workers {
+			if pointer.SafeDeref(w.Name) == workerGroup {
+				w.MachineImage = &models.V1MachineImage{
+					Name:    &name,
+					Version: &version,
+				}
+			}
+		}
+	}
+}
+
+// nextKubernetesVersions returns the latest patch version of the current minor version and the
+// latest patch version of the next minor version, empty strings if there are no such versions.
+func nextKubernetesVersions(current string, available []string) (patch string, minor string) {
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return "", ""
+	}
+
+	var latestPatch, latestMinor *semver.Version
+	for _, a := range available {
+		v, err := semver.NewVersion(a)
+		if err != nil || v.Major() != cv.Major() {
+			continue
+		}
+		switch v.Minor() {
+		case cv.Minor():
+			if v.GreaterThan(cv) && (latestPatch == nil || v.GreaterThan(latestPatch)) {
+				latestPatch = v
+			}
+		case cv.Minor() + 1:
+			if latestMinor == nil || v.GreaterThan(latestMinor) {
+				latestMinor = v
+			}
+		}
+	}
+
+	if latestPatch != nil {
+		patch = latestPatch.Original()
+	}
+	if latestMinor != nil {
+		minor = latestMinor.Original()
+	}
+	return patch, minor
+}
+
+// nextWorkerKubernetesVersion returns the latest version of the next minor version (or the current minor version
+// if there is no next one) for a worker group, which must not be newer than the control plane.
+func nextWorkerKubernetesVersion(current, controlPlane string, available []string) string {
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return ""
+	}
+	cp, err := semver.NewVersion(controlPlane)
+	if err != nil {
+		return ""
+	}
+
+	var next *semver.Version
+	for _, a := range available {
+		v, err := semver.NewVersion(a)
+		if err != nil || v.Major() != cv.Major() || !v.GreaterThan(cv) || v.GreaterThan(cp) || v.Minor() > cv.Minor()+1 {
+			continue
+		}
+		if next == nil || v.GreaterThan(next) {
+			next = v
+		}
+	}
+
+	if next == nil {
+		return ""
+	}
+	return next.Original()
+}
+
+// kubernetesMinorSkew returns the amount of minor versions the worker version is behind the control plane version.
+func kubernetesMinorSkew(controlPlane, worker string) int {
+	cp, err := semver.NewVersion(controlPlane)
+	if err != nil {
+		return 0
+	}
+	w, err := semver.NewVersion(worker)
+	if err != nil {
+		return 0
+	}
+	return int(cp.Minor()) - int(w.Minor())
+}
+
+// latestVersion returns the latest of the given versions if it is newer than the current version.
+func latestVersion(current string, available []string) string {
+	latest := current
+	for _, a := range available {
+		if compareVersions(a, latest) > 0 {
+			latest = a
+		}
+	}
+	if latest == current {
+		return ""
+	}
+	return latest
+}
+
+func compareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
+
+// splitImageVersion splits an image in the form of <name>-<version> at the last dash.
+func splitImageVersion(image string) (string, string) {
+	i := strings.LastIndex(image, "-")
+	if i < 0 {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+func kubernetesExpiration(current *models.V1ClusterResponse) *time.Time {
+	if current.Kubernetes == nil || current.Kubernetes.ExpirationDate == nil || time.Time(*current.Kubernetes.ExpirationDate).IsZero() {
+		return nil
+	}
+	return new(time.Time(*current.Kubernetes.ExpirationDate))
+}
+
+// workerGroupImageExpiration returns the earliest image expiration of the machines in the given worker group.
+func workerGroupImageExpiration(current *models.V1ClusterResponse, workerGroup string) *time.Time {
+	var dates []*string
+	for _, m := range current.Machines {
+		if m.Allocation == nil || m.Allocation.Image == nil || !slices.Contains(m.Tags, fmt.Sprintf("%s=%s", constants.LabelWorkerPool, workerGroup)) {
+			continue
+		}
+		dates = append(dates, m.Allocation.Image.ExpirationDate)
+	}
+	return earliestExpiration(dates)
+}
+
+// firewallImageExpiration returns the earliest image expiration of the firewalls of the cluster.
+func firewallImageExpiration(current *models.V1ClusterResponse) *time.Time {
+	var dates []*string
+	for _, m := range current.Firewalls {
+		if m.Allocation == nil || m.Allocation.Image == nil {
+			continue
+		}
+		dates = append(dates, m.Allocation.Image.ExpirationDate)
+	}
+	return earliestExpiration(dates)
+}
+
+func earliestExpiration(dates []*string) *time.Time {
+	var earliest *time.Time
+	for _, d := range dates {
+		if d == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, *d)
+		if err != nil || t.IsZero() {
+			continue
+		}
+		if earliest == nil || t.Before(*earliest) {
+			earliest = &t
+		}
+	}
+	return earliest
+}
+
+// expirationInfo returns a human readable expiration and whether the expiration is within the given duration.
+func expirationInfo(expiration *time.Time, within time.Duration) (string, bool) {
+	if expiration == nil || expiration.IsZero() {
+		return "", false
+	}
+
+	until := time.Until(*expiration)
+	if until <= 0 {
+		return fmt.Sprintf("expired %s ago", helper.HumanizeDuration(-until)), true
+	}
+	return fmt.Sprintf("in %s", helper.HumanizeDuration(until)), until < within
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_nextKubernetesVersions(t *testing.T) {
+	available := []string{"1.30.8", "1.30.10", "1.31.1", "1.31.4", "1.32.0", "1.32.2"}
+
+	tests := []struct {
+		name      string
+		current   string
+		wantPatch string
+		wantMinor string
+	}{
+		{
+			name:      "patch and minor update available",
+			current:   "1.30.8",
+			wantPatch: "1.30.10",
+			wantMinor: "1.31.4",
+		},
+		{
+			name:      "only minor update available",
+			current:   "1.31.4",
+			wantPatch: "",
+			wantMinor: "1.32.2",
+		},
+		{
+			name:      "latest version",
+			current:   "1.32.2",
+			wantPatch: "",
+			wantMinor: "",
+		},
+		{
+			name:      "invalid version",
+			current:   "foo",
+			wantPatch: "",
+			wantMinor: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, minor := nextKubernetesVersions(tt.current, available)
+			if diff := cmp.Diff(tt.wantPatch, patch); diff != "" {
+				t.Errorf("patch diff (+got -want):\n %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantMinor, minor); diff != "" {
+				t.Errorf("minor diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_nextWorkerKubernetesVersion(t *testing.T) {
+	available := []string{"1.29.3", "1.30.8", "1.30.10", "1.31.1", "1.31.4", "1.32.0"}
+
+	tests := []struct {
+		name         string
+		current      string
+		controlPlane string
+		want         string
+	}{
+		{
+			name:         "next minor version",
+			current:      "1.29.3",
+			controlPlane: "1.31.4",
+			want:         "1.30.10",
+		},
+		{
+			name:         "not newer than control plane",
+			current:      "1.30.8",
+			controlPlane: "1.31.1",
+			want:         "1.31.1",
+		},
+		{
+			name:         "already at control plane version",
+			current:      "1.31.4",
+			controlPlane: "1.31.4",
+			want:         "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextWorkerKubernetesVersion(tt.current, tt.controlPlane, available)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_latestVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   string
+		available []string
+		want      string
+	}{
+		{
+			name:      "newer machine image",
+			current:   "24.04.20250101",
+			available: []string{"22.04.20250101", "24.04.20250101", "24.04.20250301"},
+			want:      "24.04.20250301",
+		},
+		{
+			name:      "no newer version",
+			current:   "v2.3.4",
+			available: []string{"v2.3.3", "v2.3.4"},
+			want:      "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := latestVersion(tt.current, tt.available)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
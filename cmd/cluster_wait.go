@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+const (
+	clusterWaitForReady              = "ready"
+	clusterWaitForDeleted            = "deleted"
+	clusterWaitForOperationSucceeded = "operation-succeeded"
+	clusterWaitPollInterval          = 10 * time.Second
+	clusterWaitTimeoutDefault        = 60 * time.Minute
+	// clusterWaitClockSkew is tolerated between the local clock and the update times of the server
+	clusterWaitClockSkew = 2 * time.Second
+)
+
+var clusterWaitConditions = []string{
+	clusterWaitForReady + "\tthe last operation succeeded and all cluster conditions are healthy",
+	clusterWaitForDeleted + "\tthe cluster does not exist anymore",
+	clusterWaitForOperationSucceeded + "\tthe last operation of the cluster succeeded",
+}
+
+func (c *config) clusterWaitCmd(args []string) error {
+	ci, err := c.clusterID("wait", args)
+	if err != nil {
+		return err
+	}
+
+	condition := viper.GetString("for")
+	switch condition {
+	case clusterWaitForReady, clusterWaitForDeleted, clusterWaitForOperationSucceeded:
+	default:
+		return fmt.Errorf("unsupported wait condition %q, must be one of %s|%s|%s", condition, clusterWaitForReady, clusterWaitForDeleted, clusterWaitForOperationSucceeded)
+	}
+
+	return c.clusterWaitAndDescribe(ci, condition, time.Time{})
+}
+
+// clusterWaitAndDescribe waits for the condition and prints the cluster afterwards.
+func (c *config) clusterWaitAndDescribe(id, condition string, since time.Time) error {
+	shoot, err := c.clusterWait(id, condition, since, viper.GetDuration("timeout"))
+	if err != nil {
+		return err
+	}
+	if shoot == nil {
+		fmt.Fprintf(c.out, "cluster %s was deleted\n", id)
+		return nil
+	}
+	return c.describePrinter.Print(shoot)
+}
+
+// clusterWait polls the cluster until the given condition is met, the last operation failed or the timeout is reached.
+// operations which last changed before since are not considered, such that a previously succeeded operation
+// is not mistaken for the one that was just triggered.
+func (c *config) clusterWait(id, condition string, since time.Time, timeout time.Duration) (*models.V1ClusterResponse, error) {
+	var (
+		deadline     = time.Now().Add(timeout)
+		lastProgress string
+		state        = &clusterWaitState{condition: condition, since: since}
+	)
+
+	for {
+		resp, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(id).WithReturnMachines(new(false)), nil)
+		if err != nil {
+			var r *cluster.FindClusterDefault
+			if condition == clusterWaitForDeleted && errors.As(err, &r) && r.Code() == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		shoot := resp.Payload
+
+		var (
+			op         *models.V1beta1LastOperation
+			lastErrors []*models.V1beta1LastError
+			conditions []*models.V1beta1Condition
+		)
+		if shoot.Status != nil {
+			op = shoot.Status.LastOperation
+			lastErrors = shoot.Status.LastErrors
+			conditions = shoot.Status.Conditions
+		}
+
+		if op != nil {
+			progress := fmt.Sprintf("%s %d%% [%s] %s", pointer.SafeDeref(op.State), pointer.SafeDeref(op.Progress), pointer.SafeDeref(op.Type), pointer.SafeDeref(op.Description))
+			if progress != lastProgress {
+				fmt.Fprintf(os.Stderr, "%s %s: %s\n", time.Now().Format(time.TimeOnly), pointer.SafeDeref(shoot.Name), progress)
+				lastProgress = progress
+			}
+		}
+
+		atDeadline := time.Now().After(deadline)
+
+		done, err := state.evaluate(pointer.SafeDeref(shoot.Name), op, lastErrors, conditions, atDeadline)
+		if done {
+			return shoot, err
+		}
+
+		if atDeadline {
+			return shoot, fmt.Errorf("timeout after %s while waiting for cluster %s to be %s", timeout, id, condition)
+		}
+
+		time.Sleep(clusterWaitPollInterval)
+	}
+}
+
+// clusterWaitState keeps track of the last operations seen while waiting for a cluster.
+type clusterWaitState struct {
+	condition string
+	since     time.Time
+	// seenProcessing is set once an operation started at or after since was observed,
+	// every following operation state then belongs to that operation.
+	seenProcessing bool
+}
+
+// evaluate returns true if waiting is done, either because the condition is met or the current operation failed.
+func (s *clusterWaitState) evaluate(name string, op *models.V1beta1LastOperation, lastErrors []*models.V1beta1LastError, conditions []*models.V1beta1Condition, atDeadline bool) (bool, error) {
+	if op == nil {
+		return false, nil
+	}
+
+	state := pointer.SafeDeref(op.State)
+
+	switch state {
+	case string(v1beta1.LastOperationStateProcessing), string(v1beta1.LastOperationStatePending):
+		if clusterOperationUpdatedSince(op, s.since) {
+			s.seenProcessing = true
+		}
+	}
+
+	current := clusterOperationIsCurrent(op, s.since, s.seenProcessing)
+
+	switch state {
+	case string(v1beta1.LastOperationStateFailed), string(v1beta1.LastOperationStateAborted):
+		if current {
+			return true, clusterLastErrors(name, op, lastErrors)
+		}
+	case string(v1beta1.LastOperationStateError):
+		// gardener retries operations in this state, so it is only final once errors are reported or the time is up
+		if current && (len(lastErrors) > 0 || atDeadline) {
+			return true, clusterLastErrors(name, op, lastErrors)
+		}
+	case string(v1beta1.LastOperationStateSucceeded):
+		if s.condition == clusterWaitForDeleted || !current {
+			break
+		}
+		if s.condition == clusterWaitForOperationSucceeded {
+			return true, nil
+		}
+		if clusterConditionsHealthy(conditions) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// clusterOperationIsCurrent returns true if the operation is the one triggered at since.
+func clusterOperationIsCurrent(op *models.V1beta1LastOperation, since time.Time, seenProcessing bool) bool {
+	if seenProcessing {
+		return true
+	}
+	return clusterOperationUpdatedSince(op, since)
+}
+
+// clusterOperationUpdatedSince returns true if the operation was updated at or after since. the update time of the server
+// only has a precision of seconds and the local clock might be ahead, so since is truncated and a small skew is tolerated.
+func clusterOperationUpdatedSince(op *models.V1beta1LastOperation, since time.Time) bool {
+	if since.IsZero() {
+		return true
+	}
+	lastUpdate, err := time.Parse(time.RFC3339, pointer.SafeDeref(op.LastUpdateTime))
+	if err != nil {
+		return false
+	}
+	return !lastUpdate.Before(since.Truncate(time.Second).Add(-clusterWaitClockSkew))
+}
+
+func clusterConditionsHealthy(conditions []*models.V1beta1Condition) bool {
+	for _, condition := range conditions {
+		if condition == nil || condition.Status == nil {
+			continue
+		}
+		if *condition.Status != string(v1beta1.ConditionTrue) {
+			return false
+		}
+	}
+	return true
+}
+
+func clusterLastErrors(name string, op *models.V1beta1LastOperation, lastErrors []*models.V1beta1LastError) error {
+	var descriptions []string
+	for _, e := range lastErrors {
+		if e == nil || e.Description == nil {
+			continue
+		}
+		descriptions = append(descriptions, *e.Description)
+	}
+	if len(descriptions) == 0 {
+		descriptions = append(descriptions, pointer.SafeDeref(op.Description))
+	}
+	return fmt.Errorf("%s operation of cluster %s ended in state %s: %s", pointer.SafeDeref(op.Type), name, pointer.SafeDeref(op.State), strings.Join(descriptions, "; "))
+}
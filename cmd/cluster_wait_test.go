@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_clusterOperationIsCurrent(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	op := func(lastUpdate string) *models.V1beta1LastOperation {
+		return &models.V1beta1LastOperation{LastUpdateTime: new(lastUpdate)}
+	}
+
+	tests := []struct {
+		name           string
+		op             *models.V1beta1LastOperation
+		since          time.Time
+		seenProcessing bool
+		want           bool
+	}{
+		{name: "no since", op: op("2024-04-01T00:00:00Z"), want: true},
+		{name: "updated before since", op: op("2024-05-01T11:59:57Z"), since: since, want: false},
+		{name: "updated at since", op: op("2024-05-01T12:00:00Z"), since: since, want: true},
+		{name: "updated less than a second before since", op: op("2024-05-01T12:00:00Z"), since: since.Add(700 * time.Millisecond), want: true},
+		{name: "local clock is slightly ahead", op: op("2024-05-01T11:59:59Z"), since: since, want: true},
+		{name: "updated after since", op: op("2024-05-01T12:00:01Z"), since: since, want: true},
+		{name: "unparsable update time", op: op("yesterday"), since: since, want: false},
+		{name: "processing was seen", op: op("2024-05-01T11:00:00Z"), since: since, seenProcessing: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, clusterOperationIsCurrent(tt.op, tt.since, tt.seenProcessing))
+		})
+	}
+}
+
+func Test_clusterWaitState_evaluate(t *testing.T) {
+	var (
+		since  = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		before = "2024-05-01T11:00:00Z"
+		after  = "2024-05-01T12:05:00Z"
+	)
+
+	op := func(state, lastUpdate string) *models.V1beta1LastOperation {
+		return &models.V1beta1LastOperation{
+			Type:           new("Reconcile"),
+			State:          new(state),
+			LastUpdateTime: new(lastUpdate),
+			Description:    new(state + " description"),
+		}
+	}
+	lastErrors := []*models.V1beta1LastError{{Description: new("infrastructure failed")}}
+	unhealthy := []*models.V1beta1Condition{{Status: new("False")}}
+
+	type poll struct {
+		op         *models.V1beta1LastOperation
+		lastErrors []*models.V1beta1LastError
+		conditions []*models.V1beta1Condition
+		atDeadline bool
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		polls     []poll
+		wantPolls int
+		wantErr   string
+	}{
+		{
+			name:      "stale failed operation is ignored until the new one is picked up",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Failed", before), lastErrors: lastErrors},
+				{op: op("Failed", before), lastErrors: lastErrors},
+				{op: op("Processing", after)},
+				{op: op("Succeeded", after)},
+			},
+			wantPolls: 4,
+		},
+		{
+			name:      "stale succeeded operation is ignored",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Succeeded", before)},
+				{op: op("Pending", after)},
+				{op: op("Succeeded", after)},
+			},
+			wantPolls: 3,
+		},
+		{
+			name:      "stale processing operation does not make a failure current",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Processing", before)},
+				{op: op("Failed", before), lastErrors: lastErrors},
+				{op: op("Succeeded", after)},
+			},
+			wantPolls: 3,
+		},
+		{
+			name:      "current failed operation",
+			condition: clusterWaitForReady,
+			polls: []poll{
+				{op: op("Processing", after)},
+				{op: op("Failed", after), lastErrors: lastErrors},
+			},
+			wantPolls: 2,
+			wantErr:   "Reconcile operation of cluster test ended in state Failed: infrastructure failed",
+		},
+		{
+			name:      "error state without last errors is retried",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Error", after)},
+				{op: op("Processing", after)},
+				{op: op("Succeeded", after)},
+			},
+			wantPolls: 3,
+		},
+		{
+			name:      "error state with last errors",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Processing", after)},
+				{op: op("Error", after), lastErrors: lastErrors},
+			},
+			wantPolls: 2,
+			wantErr:   "Reconcile operation of cluster test ended in state Error: infrastructure failed",
+		},
+		{
+			name:      "error state at the deadline",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Error", after)},
+				{op: op("Error", after), atDeadline: true},
+			},
+			wantPolls: 2,
+			wantErr:   "Reconcile operation of cluster test ended in state Error: Error description",
+		},
+		{
+			name:      "stale error state at the deadline",
+			condition: clusterWaitForOperationSucceeded,
+			polls: []poll{
+				{op: op("Error", before), lastErrors: lastErrors, atDeadline: true},
+			},
+			wantPolls: -1,
+		},
+		{
+			name:      "ready waits for healthy conditions",
+			condition: clusterWaitForReady,
+			polls: []poll{
+				{op: op("Succeeded", after), conditions: unhealthy},
+				{op: op("Succeeded", after)},
+			},
+			wantPolls: 2,
+		},
+		{
+			name:      "deleted is never done by an operation",
+			condition: clusterWaitForDeleted,
+			polls: []poll{
+				{op: op("Succeeded", after)},
+				{},
+			},
+			wantPolls: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &clusterWaitState{condition: tt.condition, since: since}
+
+			gotPolls := -1
+			var err error
+			for i, p := range tt.polls {
+				var done bool
+				done, err = s.evaluate("test", p.op, p.lastErrors, p.conditions, p.atDeadline)
+				if done {
+					gotPolls = i + 1
+					break
+				}
+			}
+
+			require.Equal(t, tt.wantPolls, gotPolls)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	utiltaints "github.com/gardener/machine-controller-manager/pkg/util/taints"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type workerGroupCmd struct {
+	c *config
+}
+
+func newClusterWorkerGroupCmd(c *config) *cobra.Command {
+	w := workerGroupCmd{
+		c: c,
+	}
+
+	workerGroupCmd := &cobra.Command{
+		Use:     "workergroup",
+		Aliases: []string{"workergroups", "wg"},
+		Short:   "manage the worker groups of a cluster",
+		Long:    "please note that running multiple worker groups leads to higher basic costs of the cluster.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list <clusterid>",
+		Aliases: []string{"ls"},
+		Short:   "list the worker groups of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.list(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+	describeCmd := &cobra.Command{
+		Use:   "describe <clusterid> <workergroup>",
+		Short: "describe a worker group of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.describe(args)
+		},
+		ValidArgsFunction: c.comp.ClusterWorkerGroupListCompletion,
+	}
+	addCmd := &cobra.Command{
+		Use:   "add <clusterid>",
+		Short: "add a worker group to a cluster",
+		Long:  "adds a worker group to a cluster. the new worker group is copied from an existing worker group (the first one if --from is not given) and modified with the given flags.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.add(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+	}
+	updateCmd := &cobra.Command{
+		Use:   "update <clusterid> <workergroup>",
+		Short: "update a worker group of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.update(args)
+		},
+		ValidArgsFunction: c.comp.ClusterWorkerGroupListCompletion,
+	}
+	removeCmd := &cobra.Command{
+		Use:     "remove <clusterid> <workergroup>",
+		Aliases: []string{"rm", "delete"},
+		Short:   "remove a worker group from a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return w.remove(args)
+		},
+		ValidArgsFunction: c.comp.ClusterWorkerGroupListCompletion,
+	}
+
+	for _, cmd := range []*cobra.Command{addCmd, updateCmd} {
+		cmd.Flags().String("machinetype", "", "machine type to use for the nodes of the worker group.")
+		cmd.Flags().String("machineimage", "", "machine image to use for the nodes of the worker group, must be in the form of <name>-<version>")
+		cmd.Flags().Int32("minsize", 0, "minimal workers of the worker group.")
+		cmd.Flags().Int32("maxsize", 0, "maximal workers of the worker group.")
+		cmd.Flags().String("maxsurge", "", "max number (e.g. 1) or percentage (e.g. 10%) of workers created during a update of the worker group.")
+		cmd.Flags().String("maxunavailable", "", "max number (e.g. 0) or percentage (e.g. 10%) of workers that can be unavailable during a update of the worker group.")
+		cmd.Flags().StringSlice("labels", []string{}, "labels of the worker group (syncs to kubernetes node resource after some time, too)")
+		cmd.Flags().StringSlice("annotations", []string{}, "annotations of the worker group (syncs to kubernetes node resource after some time, too)")
+		cmd.Flags().StringSlice("taints", []string{}, "list of taints to set for nodes of the worker group. (use empty string to remove previous set taints)")
+		cmd.Flags().String("version", "", "set custom kubernetes version of the worker group independent of the api server. note that the worker version may only be two minor version older than the api server as stated in the official kubernetes version skew policy. (set to \"\" to remove custom kubernetes version)")
+		cmd.Flags().Duration("healthtimeout", 0, "period (e.g. \"24h\") after which an unhealthy node is declared failed and will be replaced. (0 = provider-default)")
+		cmd.Flags().Duration("draintimeout", 0, "period (e.g. \"3h\") after which a draining node will be forcefully deleted. (0 = provider-default)")
+
+		genericcli.Must(cmd.RegisterFlagCompletionFunc("machinetype", c.comp.MachineTypeListCompletion))
+		genericcli.Must(cmd.RegisterFlagCompletionFunc("machineimage", c.comp.MachineImageListCompletion))
+		genericcli.Must(cmd.RegisterFlagCompletionFunc("version", c.comp.VersionListCompletion))
+	}
+
+	addCmd.Flags().String("name", "", "name of the new worker group. [required]")
+	addCmd.Flags().String("from", "", "name of the worker group to use as a template, defaults to the first worker group of the cluster.")
+	genericcli.Must(addCmd.MarkFlagRequired("name"))
+	genericcli.Must(addCmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 1 {
+			return []string{"no clusterid given"}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return c.comp.ClusterWorkerGroupListCompletion(cmd, args, toComplete)
+	}))
+
+	workerGroupCmd.AddCommand(listCmd, describeCmd, addCmd, updateCmd, removeCmd)
+
+	return workerGroupCmd
+}
+
+func (w *workerGroupCmd) list(args []string) error {
+	current, err := w.findCluster(args, 1)
+	if err != nil {
+		return err
+	}
+
+	var groups []*tableprinters.ClusterWorkerGroup
+	for _, worker := range current.Workers {
+		groups = append(groups, newClusterWorkerGroup(current, worker))
+	}
+
+	return w.c.listPrinter.Print(groups)
+}
+
+func (w *workerGroupCmd) describe(args []string) error {
+	current, err := w.findCluster(args, 2)
+	if err != nil {
+		return err
+	}
+
+	worker, err := findWorkerGroup(current, args[1])
+	if err != nil {
+		return err
+	}
+
+	return w.c.describePrinter.Print(newClusterWorkerGroup(current, worker))
+}
+
+func (w *workerGroupCmd) add(args []string) error {
+	current, err := w.findCluster(args, 1)
+	if err != nil {
+		return err
+	}
+
+	name := viper.GetString("name")
+	if _, err := findWorkerGroup(current, name); err == nil {
+		return fmt.Errorf("worker group %s already exists", name)
+	}
+	if len(current.Workers) == 0 {
+		return fmt.Errorf("cluster %s has no worker group which can be used as a template", *current.Name)
+	}
+
+	template := current.Workers[0]
+	if viper.IsSet("from") {
+		template, err = findWorkerGroup(current, viper.GetString("from"))
+		if err != nil {
+			return err
+		}
+	}
+
+	worker := copyWorkerGroup(template)
+	worker.Name = &name
+
+	err = applyWorkerGroupFlags(worker)
+	if err != nil {
+		return err
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		fmt.Printf("Adding a new worker group to cluster:%q. Please note that running multiple worker groups leads to higher basic costs of the cluster!\n", *current.Name)
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.updateWorkers(current, append(current.Workers, worker))
+}
+
+func (w *workerGroupCmd) update(args []string) error {
+	current, err := w.findCluster(args, 2)
+	if err != nil {
+		return err
+	}
+
+	worker, err := findWorkerGroup(current, args[1])
+	if err != nil {
+		return err
+	}
+
+	if viper.IsSet("maxsize") {
+		if machines := workerGroupMachineCount(current, *worker.Name); int(viper.GetInt32("maxsize")) < machines && !viper.GetBool("yes-i-really-mean-it") {
+			fmt.Printf("WARNING. New maxsize of worker group %q is lower than currently active machines. A random worker node which is still in use will be removed.\n", *worker.Name)
+			err = helper.Prompt("Are you sure? (y/n)", "y")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = applyWorkerGroupFlags(worker)
+	if err != nil {
+		return err
+	}
+
+	return w.updateWorkers(current, current.Workers)
+}
+
+func (w *workerGroupCmd) remove(args []string) error {
+	current, err := w.findCluster(args, 2)
+	if err != nil {
+		return err
+	}
+
+	worker, err := findWorkerGroup(current, args[1])
+	if err != nil {
+		return err
+	}
+
+	if len(current.Workers) == 1 {
+		return fmt.Errorf("worker group %s is the last worker group of the cluster and cannot be removed", *worker.Name)
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		fmt.Printf("WARNING. Removing a worker group from cluster:%q cannot be undone and causes the loss of local data on the deleted nodes.\n", *current.Name)
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	workers := slices.DeleteFunc(slices.Clone(current.Workers), func(wg *models.V1Worker) bool {
+		return pointer.SafeDeref(wg.Name) == *worker.Name
+	})
+
+	return w.updateWorkers(current, workers)
+}
+
+func (w *workerGroupCmd) findCluster(args []string, expectedArgs int) (*models.V1ClusterResponse, error) {
+	if len(args) != expectedArgs {
+		if expectedArgs == 1 {
+			return nil, fmt.Errorf("cluster workergroup requires exactly one clusterID as argument")
+		}
+		return nil, fmt.Errorf("cluster workergroup requires clusterID and worker group name as arguments")
+	}
+
+	resp, err := w.c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(args[0]), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Payload, nil
+}
+
+func (w *workerGroupCmd) updateWorkers(current *models.V1ClusterResponse, workers []*models.V1Worker) error {
+	resp, err := w.c.cloud.Cluster.UpdateCluster(cluster.NewUpdateClusterParams().WithBody(&models.V1ClusterUpdateRequest{
+		ID:      current.ID,
+		Workers: workers,
+	}), nil)
+	if err != nil {
+		return err
+	}
+
+	var groups []*tableprinters.ClusterWorkerGroup
+	for _, worker := range resp.Payload.Workers {
+		groups = append(groups, newClusterWorkerGroup(current, worker))
+	}
+
+	return w.c.listPrinter.Print(groups)
+}
+
+func newClusterWorkerGroup(current *models.V1ClusterResponse, worker *models.V1Worker) *tableprinters.ClusterWorkerGroup {
+	version := pointer.SafeDeref(worker.KubernetesVersion)
+	if version == "" && current.Kubernetes != nil {
+		version = pointer.SafeDeref(current.Kubernetes.Version)
+	}
+
+	return &tableprinters.ClusterWorkerGroup{
+		ClusterID:         pointer.SafeDeref(current.ID),
+		KubernetesVersion: version,
+		Machines:          workerGroupMachineCount(current, pointer.SafeDeref(worker.Name)),
+		Worker:            worker,
+	}
+}
+
+func findWorkerGroup(current *models.V1ClusterResponse, name string) (*models.V1Worker, error) {
+	for _, w := range current.Workers {
+		if pointer.SafeDeref(w.Name) == name {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("worker group %s not found", name)
+}
+
+// workerGroupMachineCount returns the amount of machines of the cluster that belong to the given worker group.
+func workerGroupMachineCount(current *models.V1ClusterResponse, name string) int {
+	count := 0
+	for _, m := range current.Machines {
+		if slices.Contains(m.Tags, fmt.Sprintf("%s=%s", constants.LabelWorkerPool, name)) {
+			count++
+		}
+	}
+	return count
+}
+
+func copyWorkerGroup(template *models.V1Worker) *models.V1Worker {
+	worker := *template
+
+	worker.Labels = maps.Clone(template.Labels)
+	worker.Annotations = maps.Clone(template.Annotations)
+	worker.Taints = slices.Clone(template.Taints)
+	if template.MachineImage != nil {
+		worker.MachineImage = &models.V1MachineImage{
+			Name:    template.MachineImage.Name,
+			Version: template.MachineImage.Version,
+		}
+	}
+
+	return &worker
+}
+
+func parseWorkerTaints(taints []string) ([]*models.V1Taint, error) {
+	coretaints, _, err := utiltaints.ParseTaints(taints)
+	if err != nil {
+		return nil, fmt.Errorf("specified taints are invalid: %w", err)
+	}
+
+	var result []*models.V1Taint
+	for _, t := range coretaints {
+		result = append(result, &models.V1Taint{
+			Key:    &t.Key,
+			Value:  t.Value,
+			Effect: (*string)(&t.Effect),
+		})
+	}
+	return result, nil
+}
+
+// applyWorkerGroupFlags modifies the given worker group with the flags given on the command line.
+func applyWorkerGroupFlags(worker *models.V1Worker) error {
+	if viper.IsSet("machinetype") {
+		worker.MachineType = new(viper.GetString("machinetype"))
+	}
+	if viper.IsSet("machineimage") {
+		machineImageAndVersion := viper.GetString("machineimage")
+		parts := strings.Split(machineImageAndVersion, "-")
+		if len(parts) != 2 {
+			return fmt.Errorf("given machineimage:%s is invalid must be in the form <name>-<version>", machineImageAndVersion)
+		}
+		worker.MachineImage = &models.V1MachineImage{
+			Name:    &parts[0],
+			Version: &parts[1],
+		}
+	}
+	if viper.IsSet("minsize") {
+		worker.Minimum = new(viper.GetInt32("minsize"))
+	}
+	if viper.IsSet("maxsize") {
+		worker.Maximum = new(viper.GetInt32("maxsize"))
+	}
+	if pointer.SafeDeref(worker.Minimum) > pointer.SafeDeref(worker.Maximum) {
+		return fmt.Errorf("minsize %d of worker group must not be greater than maxsize %d", pointer.SafeDeref(worker.Minimum), pointer.SafeDeref(worker.Maximum))
+	}
+	if viper.IsSet("maxsurge") {
+		worker.MaxSurge = new(viper.GetString("maxsurge"))
+	}
+	if viper.IsSet("maxunavailable") {
+		worker.MaxUnavailable = new(viper.GetString("maxunavailable"))
+	}
+	if viper.IsSet("labels") {
+		labels, err := helper.LabelsToMap(viper.GetStringSlice("labels"))
+		if err != nil {
+			return err
+		}
+		worker.Labels = labels
+	}
+	if viper.IsSet("annotations") {
+		annotations, err := helper.LabelsToMap(viper.GetStringSlice("annotations"))
+		if err != nil {
+			return err
+		}
+		worker.Annotations = annotations
+	}
+	if viper.IsSet("taints") {
+		taints, err := parseWorkerTaints(viper.GetStringSlice("taints"))
+		if err != nil {
+			return err
+		}
+		worker.Taints = taints
+	}
+	if viper.IsSet("version") {
+		worker.KubernetesVersion = new(viper.GetString("version"))
+	}
+	if viper.IsSet("healthtimeout") {
+		worker.HealthTimeout = int64(viper.GetDuration("healthtimeout"))
+	}
+	if viper.IsSet("draintimeout") {
+		worker.DrainTimeout = int64(viper.GetDuration("draintimeout"))
+	}
+	return nil
+}
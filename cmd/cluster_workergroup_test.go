@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	testclient "github.com/fi-ts/cloud-go/test/client"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func workerGroupTestCluster() *models.V1ClusterResponse {
+	return &models.V1ClusterResponse{
+		ID:         new("c1"),
+		Name:       new("test"),
+		Kubernetes: &models.V1Kubernetes{Version: new("1.30.5")},
+		Workers: []*models.V1Worker{
+			{
+				Name:         new("group-a"),
+				MachineType:  new("c1-large-x86"),
+				MachineImage: &models.V1MachineImage{Name: new("ubuntu"), Version: new("22.04")},
+				Minimum:      new(int32(2)),
+				Maximum:      new(int32(3)),
+			},
+			{
+				Name:              new("group-b"),
+				MachineType:       new("c1-xlarge-x86"),
+				MachineImage:      &models.V1MachineImage{Name: new("ubuntu"), Version: new("22.04")},
+				Minimum:           new(int32(1)),
+				Maximum:           new(int32(1)),
+				KubernetesVersion: new("1.29.8"),
+				Labels:            map[string]string{"role": "db"},
+			},
+		},
+		Machines: []*models.ModelsV1MachineResponse{
+			{ID: new("m1"), Tags: []string{"worker.gardener.cloud/pool=group-a"}},
+			{ID: new("m2"), Tags: []string{"worker.gardener.cloud/pool=group-a"}},
+			{ID: new("m3"), Tags: []string{"worker.gardener.cloud/pool=group-b"}},
+		},
+	}
+}
+
+func Test_ClusterWorkerGroupCmd_MultiResult(t *testing.T) {
+	current := workerGroupTestCluster()
+
+	tests := []*test[[]*tableprinters.ClusterWorkerGroup]{
+		{
+			name: "list",
+			cmd: func(want []*tableprinters.ClusterWorkerGroup) []string {
+				return []string{"cluster", "workergroup", "list", "c1"}
+			},
+			mocks: &testclient.CloudMockFns{
+				Cluster: func(mock *mock.Mock) {
+					mock.On("FindCluster", testcommon.MatchIgnoreContext(t, cluster.NewFindClusterParams().WithID("c1")), nil).
+						Return(&cluster.FindClusterOK{Payload: workerGroupTestCluster()}, nil)
+				},
+			},
+			want: []*tableprinters.ClusterWorkerGroup{
+				{ClusterID: "c1", KubernetesVersion: "1.30.5", Machines: 2, Worker: current.Workers[0]},
+				{ClusterID: "c1", KubernetesVersion: "1.29.8", Machines: 1, Worker: current.Workers[1]},
+			},
+			wantTable: new(`
+NAME     MACHINE TYPE   IMAGE         KUBERNETES  CURRENT  DESIRED
+group-a  c1-large-x86   ubuntu-22.04  1.30.5      2        2-3
+group-b  c1-xlarge-x86  ubuntu-22.04  1.29.8      1        1
+`),
+		},
+	}
+	for _, tt := range tests {
+		tt.testCmd(t)
+	}
+}
+
+func Test_workerGroupCmd_add(t *testing.T) {
+	current := workerGroupTestCluster()
+
+	tests := []struct {
+		name    string
+		flags   map[string]any
+		want    *models.V1Worker
+		wantErr string
+	}{
+		{
+			name:  "copies the first worker group",
+			flags: map[string]any{"name": "group-c"},
+			want: &models.V1Worker{
+				Name:         new("group-c"),
+				MachineType:  new("c1-large-x86"),
+				MachineImage: &models.V1MachineImage{Name: new("ubuntu"), Version: new("22.04")},
+				Minimum:      new(int32(2)),
+				Maximum:      new(int32(3)),
+			},
+		},
+		{
+			name: "copies the given worker group and applies the flags",
+			flags: map[string]any{
+				"name":        "group-c",
+				"from":        "group-b",
+				"machinetype": "c1-medium-x86",
+				"maxsize":     int32(4),
+				"labels":      []string{"role=cache"},
+			},
+			want: &models.V1Worker{
+				Name:              new("group-c"),
+				MachineType:       new("c1-medium-x86"),
+				MachineImage:      &models.V1MachineImage{Name: new("ubuntu"), Version: new("22.04")},
+				Minimum:           new(int32(1)),
+				Maximum:           new(int32(4)),
+				KubernetesVersion: new("1.29.8"),
+				Labels:            map[string]string{"role": "cache"},
+			},
+		},
+		{
+			name:    "worker group exists",
+			flags:   map[string]any{"name": "group-b"},
+			wantErr: "worker group group-b already exists",
+		},
+		{
+			name:    "template does not exist",
+			flags:   map[string]any{"name": "group-c", "from": "group-x"},
+			wantErr: "worker group group-x not found",
+		},
+		{
+			name:    "minsize greater than maxsize of the template",
+			flags:   map[string]any{"name": "group-c", "minsize": int32(4)},
+			wantErr: "minsize 4 of worker group must not be greater than maxsize 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("yes-i-really-mean-it", true)
+			for k, v := range tt.flags {
+				viper.Set(k, v)
+			}
+
+			var (
+				out         bytes.Buffer
+				clusterMock *mock.Mock
+				found       = workerGroupTestCluster()
+			)
+			w := workerGroupCmd{c: &config{
+				cloud: testclient.NewCloudMockClient(t, &testclient.CloudMockFns{
+					Cluster: func(m *mock.Mock) {
+						clusterMock = m
+						m.On("FindCluster", testcommon.MatchIgnoreContext(t, cluster.NewFindClusterParams().WithID("c1")), nil).
+							Return(&cluster.FindClusterOK{Payload: found}, nil)
+						if tt.want == nil {
+							return
+						}
+						desired := workerGroupTestCluster()
+						desired.Workers = append(desired.Workers, tt.want)
+						m.On("UpdateCluster", testcommon.MatchIgnoreContext(t, cluster.NewUpdateClusterParams().WithBody(&models.V1ClusterUpdateRequest{
+							ID:      new("c1"),
+							Workers: desired.Workers,
+						})), nil).Return(&cluster.UpdateClusterOK{Payload: desired}, nil).Once()
+					},
+				}),
+				out:         &out,
+				listPrinter: printers.NewJSONPrinter().WithOut(&out),
+			}}
+
+			err := w.add([]string{"c1"})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			clusterMock.AssertExpectations(t)
+
+			// the template must not be modified by the copy
+			if diff := cmp.Diff(found.Workers, current.Workers); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func Test_applyWorkerGroupFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   map[string]any
+		want    *models.V1Worker
+		wantErr string
+	}{
+		{
+			name:  "no flags",
+			flags: map[string]any{},
+			want:  &models.V1Worker{Minimum: new(int32(1)), Maximum: new(int32(2))},
+		},
+		{
+			name:  "min and max",
+			flags: map[string]any{"minsize": int32(3), "maxsize": int32(5)},
+			want:  &models.V1Worker{Minimum: new(int32(3)), Maximum: new(int32(5))},
+		},
+		{
+			name:  "min equals max",
+			flags: map[string]any{"minsize": int32(2)},
+			want:  &models.V1Worker{Minimum: new(int32(2)), Maximum: new(int32(2))},
+		},
+		{
+			name:    "min greater than the current max",
+			flags:   map[string]any{"minsize": int32(3)},
+			wantErr: "minsize 3 of worker group must not be greater than maxsize 2",
+		},
+		{
+			name:    "max lower than the current min",
+			flags:   map[string]any{"maxsize": int32(0)},
+			wantErr: "minsize 1 of worker group must not be greater than maxsize 0",
+		},
+		{
+			name:    "min greater than max",
+			flags:   map[string]any{"minsize": int32(4), "maxsize": int32(3)},
+			wantErr: "minsize 4 of worker group must not be greater than maxsize 3",
+		},
+		{
+			name:    "invalid machine image",
+			flags:   map[string]any{"machineimage": "ubuntu"},
+			wantErr: "given machineimage:ubuntu is invalid must be in the form <name>-<version>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			for k, v := range tt.flags {
+				viper.Set(k, v)
+			}
+
+			got := &models.V1Worker{Minimum: new(int32(1)), Maximum: new(int32(2))}
+			err := applyWorkerGroupFlags(got)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
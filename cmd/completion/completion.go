@@ -92,6 +92,26 @@ func (c *Completion) ClusterFirewallListCompletion(cmd *cobra.Command, args []st
 	return c.clusterMachineListCompletion(args, false)
 }
 
+func (c *Completion) ClusterWorkerGroupListCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return c.ClusterListCompletion(cmd, args, toComplete)
+	case 1:
+		shoot, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(args[0]).WithReturnMachines(new(false)), nil)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var names []string
+		for _, w := range shoot.Payload.Workers {
+			names = append(names, pointer.SafeDeref(w.Name))
+		}
+		sort.Strings(names)
+		return names, cobra.ShellCompDirectiveNoFileComp
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
 func (c *Completion) ClusterPurposeListCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	return ClusterPurposes, cobra.ShellCompDirectiveNoFileComp
 }
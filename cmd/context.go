@@ -25,6 +25,7 @@ contexts:
     issuer_url: https://dex.metal-stack.io/dex
     client_id: metal_client
     client_secret: 456
+    record_dir: ~/.cloudctl/recordings
   dev:
     url: https://api.metal-stack.dev/cloud
     issuer_url: https://dex.metal-stack.dev/dex
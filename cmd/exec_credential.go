@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/pkg/api"
+	"github.com/metal-stack/metal-lib/auth"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	execCredentialAPIVersion = "client.authentication.k8s.io/v1"
+	execCredentialKind       = "ExecCredential"
+	execCredentialInfoEnv    = "KUBERNETES_EXEC_INFO"
+)
+
+// execCredential implements the client.authentication.k8s.io ExecCredential, which is not vendored to keep client-go out of the dependencies.
+type execCredential struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Status     *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialStatus struct {
+	Token               string       `json:"token"`
+	ExpirationTimestamp *metav1.Time `json:"expirationTimestamp,omitempty"`
+}
+
+func newExecCredentialCmd(c *config) *cobra.Command {
+	execCredentialCmd := &cobra.Command{
+		Use:   "exec-credential",
+		Short: "prints an exec credential for kubectl",
+		Long: `implements the client.authentication.k8s.io exec credential plugin protocol, which is used by kubeconfigs created with "cloudctl cluster kubeconfig --exec-plugin".
+
+the token is taken from the auth context stored by "cloudctl login". the login flow is only started when this token is expired.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.execCredential()
+		},
+	}
+
+	execCredentialCmd.Flags().String("context", "", "the cloudctl context to obtain the token for, defaults to the current context")
+	execCredentialCmd.Flags().Duration("min-validity", time.Minute, "the minimum remaining validity of the stored token, otherwise a new login is started")
+
+	return execCredentialCmd
+}
+
+func (c *config) execCredential() error {
+	ctxs, err := api.GetContexts()
+	if err != nil {
+		return err
+	}
+
+	name := viper.GetString("context")
+	if name == "" {
+		name = ctxs.CurrentContext
+	}
+	ctx, ok := ctxs.Contexts[name]
+	if !ok {
+		return fmt.Errorf("context %q not found", name)
+	}
+
+	kubeconfig := viper.GetString("kubeconfig")
+
+	token, expiry, err := storedToken(kubeconfig, name)
+	if err != nil || time.Until(expiry) < viper.GetDuration("min-validity") {
+		// stdout is reserved for the exec credential
+		handler := auth.NewUpdateKubeConfigHandler(kubeconfig, os.Stderr, auth.WithContextName(api.FormatContextName(api.CloudContext, name)))
+
+		err = oidcFlow(ctx, handler, os.Stderr, slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+		if err != nil {
+			return err
+		}
+
+		token, expiry, err = storedToken(kubeconfig, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	apiVersion := execCredentialAPIVersion
+	if info := os.Getenv(execCredentialInfoEnv); info != "" {
+		var ec execCredential
+		err = json.Unmarshal([]byte(info), &ec)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %w", execCredentialInfoEnv, err)
+		}
+		if ec.APIVersion != "" {
+			apiVersion = ec.APIVersion
+		}
+	}
+
+	return json.NewEncoder(c.out).Encode(execCredential{
+		APIVersion: apiVersion,
+		Kind:       execCredentialKind,
+		Status: &execCredentialStatus{
+			Token:               token,
+			ExpirationTimestamp: &metav1.Time{Time: expiry},
+		},
+	})
+}
+
+// clusterKubeconfigExecPlugin returns the exec plugin which calls cloudctl for the current cloudctl context.
+func clusterKubeconfigExecPlugin() (helper.KubeconfigExecPlugin, error) {
+	ctxs, err := api.GetContexts()
+	if err != nil {
+		return helper.KubeconfigExecPlugin{}, err
+	}
+
+	args := []string{"exec-credential"}
+	if ctxs.CurrentContext != "" {
+		args = append(args, "--context", ctxs.CurrentContext)
+	}
+	if viper.IsSet("kubeconfig") {
+		args = append(args, "--kubeconfig", viper.GetString("kubeconfig"))
+	}
+
+	return helper.KubeconfigExecPlugin{
+		APIVersion: execCredentialAPIVersion,
+		Command:    binaryName,
+		Args:       args,
+	}, nil
+}
+
+func storedToken(kubeconfig, contextName string) (string, time.Time, error) {
+	authContext, err := api.GetAuthContextByName(kubeconfig, contextName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiry, err := tokenExpiry(authContext.IDToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return authContext.IDToken, expiry, nil
+}
+
+// tokenExpiry returns the expiration of a jwt without verifying it, which is done by the api server.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a valid jwt")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to decode token payload: %w", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse token claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("token has no expiration")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_tokenExpiry(t *testing.T) {
+	jwt := func(claims string) string {
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2lnbmF0dXJl"
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "valid token",
+			token: jwt(`{"sub":"user","exp":1767225600}`),
+			want:  time.Unix(1767225600, 0),
+		},
+		{
+			name:    "no expiration",
+			token:   jwt(`{"sub":"user"}`),
+			wantErr: true,
+		},
+		{
+			name:    "no jwt",
+			token:   "abc",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenExpiry(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tokenExpiry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("tokenExpiry() diff = %s", diff)
+			}
+		})
+	}
+}
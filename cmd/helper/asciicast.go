@@ -0,0 +1,118 @@
+package helper
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AsciicastHeader is the header of a recording in asciicast v2 format, see https://docs.asciinema.org/manual/asciicast/v2/
+type AsciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	// Metadata is not part of the asciicast format and ignored by players, it carries information about the recorded session.
+	Metadata any `json:"metadata,omitempty"`
+}
+
+// AsciicastRecorder records the output of a terminal session in asciicast v2 format.
+type AsciicastRecorder struct {
+	mu      sync.Mutex
+	w       io.WriteCloser
+	start   time.Time
+	now     func() time.Time
+	pending []byte
+}
+
+// NewAsciicastRecorder writes the header and returns a recorder, which writes every output as event to w.
+// the timestamp of the header is set to the start of the recording.
+func NewAsciicastRecorder(w io.WriteCloser, header AsciicastHeader) (*AsciicastRecorder, error) {
+	return newAsciicastRecorder(w, header, time.Now)
+}
+
+func newAsciicastRecorder(w io.WriteCloser, header AsciicastHeader, now func() time.Time) (*AsciicastRecorder, error) {
+	r := &AsciicastRecorder{
+		w:     w,
+		start: now(),
+		now:   now,
+	}
+
+	header.Version = 2
+	header.Timestamp = r.start.Unix()
+
+	err := r.writeLine(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Write records p as output event, incomplete utf-8 sequences are held back until the next write.
+func (r *AsciicastRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.pending, p...)
+
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	r.pending = append([]byte(nil), data[cut:]...)
+
+	if cut == 0 {
+		return len(p), nil
+	}
+
+	err := r.event("o", string(data[:cut]))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close records a marker for the end of the session and closes the underlying writer.
+func (r *AsciicastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) > 0 {
+		_ = r.event("o", string(r.pending))
+		r.pending = nil
+	}
+
+	err := r.event("m", "session ended at "+r.now().UTC().Format(time.RFC3339))
+	if err != nil {
+		_ = r.w.Close()
+		return err
+	}
+
+	return r.w.Close()
+}
+
+func (r *AsciicastRecorder) event(code, data string) error {
+	return r.writeLine([]any{r.now().Sub(r.start).Seconds(), code, data})
+}
+
+func (r *AsciicastRecorder) writeLine(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
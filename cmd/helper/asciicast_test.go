@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (w *nopWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestAsciicastRecorder(t *testing.T) {
+	var (
+		out = &nopWriteCloser{}
+		now = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	)
+	clock := func() time.Time {
+		now = now.Add(500 * time.Millisecond)
+		return now
+	}
+
+	r, err := newAsciicastRecorder(out, AsciicastHeader{
+		Width:    80,
+		Height:   24,
+		Metadata: map[string]string{"user": "alice"},
+	}, clock)
+	require.NoError(t, err)
+
+	_, err = r.Write([]byte("hello\r\n"))
+	require.NoError(t, err)
+
+	// the euro sign is split across two writes
+	euro := []byte("€")
+	_, err = r.Write(euro[:1])
+	require.NoError(t, err)
+	_, err = r.Write(euro[1:])
+	require.NoError(t, err)
+
+	require.NoError(t, r.Close())
+	require.True(t, out.closed)
+
+	want := `{"version":2,"width":80,"height":24,"timestamp":1767268800,"metadata":{"user":"alice"}}
+[0.5,"o","hello\r\n"]
+[1,"o","€"]
+[2,"m","session ended at 2026-01-01T12:00:02Z"]
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("AsciicastRecorder diff = %s", diff)
+	}
+}
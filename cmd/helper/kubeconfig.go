@@ -2,6 +2,7 @@ package helper
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/metal-stack/metal-lib/auth"
 	"gopkg.in/yaml.v3"
@@ -80,3 +81,240 @@ func MergeKubeconfigTpl(currentCfg map[any]any, tpl, contextName, clusterName st
 
 	return mergedKubeconfig, nil
 }
+
+// KubeconfigContextExtensionName is the name of the context extension which marks contexts merged by cloudctl.
+const KubeconfigContextExtensionName = "cloudctl"
+
+// KubeconfigContextExtension is stored in the contexts merged by cloudctl to identify the cluster they belong to.
+type KubeconfigContextExtension struct {
+	ClusterID string `yaml:"clusterid" json:"clusterid"`
+	ProjectID string `yaml:"projectid" json:"projectid"`
+}
+
+// KubeconfigContext is a context of a kubeconfig.
+type KubeconfigContext struct {
+	Name    string
+	Cluster string
+	User    string
+	// Extension is only set for contexts merged by cloudctl
+	Extension *KubeconfigContextExtension
+}
+
+// SetKubeconfigContextExtensions adds the cloudctl extension to the given contexts of the kubeconfig.
+func SetKubeconfigContextExtensions(kubeconfig []byte, extensions map[string]KubeconfigContextExtension) ([]byte, error) {
+	cfg, contexts, err := decodeKubeconfigContexts(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, c := range contexts {
+		ctx, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := ctx["name"].(string)
+		ext, ok := extensions[name]
+		if !ok {
+			continue
+		}
+
+		data, ok := ctx["context"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("context %q has no valid context data", name)
+		}
+
+		var result []any
+		if existing, ok := data["extensions"].([]any); ok {
+			for _, e := range existing {
+				if m, ok := e.(map[string]any); ok && m["name"] == KubeconfigContextExtensionName {
+					continue
+				}
+				result = append(result, e)
+			}
+		}
+		result = append(result, map[string]any{
+			"name": KubeconfigContextExtensionName,
+			"extension": map[string]any{
+				"clusterid": ext.ClusterID,
+				"projectid": ext.ProjectID,
+			},
+		})
+		data["extensions"] = result
+
+		found[name] = true
+	}
+
+	for name := range extensions {
+		if !found[name] {
+			return nil, fmt.Errorf("context %q not found in kubeconfig", name)
+		}
+	}
+
+	return yaml.Marshal(cfg)
+}
+
+// KubeconfigContexts returns all contexts of the given kubeconfig.
+func KubeconfigContexts(kubeconfig []byte) ([]KubeconfigContext, error) {
+	_, contexts, err := decodeKubeconfigContexts(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []KubeconfigContext
+	for _, c := range contexts {
+		ctx, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var (
+			name, _ = ctx["name"].(string)
+			data, _ = ctx["context"].(map[string]any)
+			kc      = KubeconfigContext{Name: name}
+		)
+
+		kc.Cluster, _ = data["cluster"].(string)
+		kc.User, _ = data["user"].(string)
+
+		extensions, _ := data["extensions"].([]any)
+		for _, e := range extensions {
+			m, ok := e.(map[string]any)
+			if !ok || m["name"] != KubeconfigContextExtensionName {
+				continue
+			}
+			ext, _ := m["extension"].(map[string]any)
+			kc.Extension = &KubeconfigContextExtension{}
+			kc.Extension.ClusterID, _ = ext["clusterid"].(string)
+			kc.Extension.ProjectID, _ = ext["projectid"].(string)
+		}
+
+		result = append(result, kc)
+	}
+
+	return result, nil
+}
+
+// RemoveKubeconfigContexts removes the given contexts from the kubeconfig.
+// clusters which are not referenced by any other context anymore are removed as well, users are kept.
+func RemoveKubeconfigContexts(kubeconfig []byte, contextNames ...string) ([]byte, error) {
+	cfg, contexts, err := decodeKubeconfigContexts(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		remaining       []any
+		removedClusters = map[string]bool{}
+		usedClusters    = map[string]bool{}
+	)
+	for _, c := range contexts {
+		ctx, _ := c.(map[string]any)
+		name, _ := ctx["name"].(string)
+		data, _ := ctx["context"].(map[string]any)
+		cluster, _ := data["cluster"].(string)
+
+		if slices.Contains(contextNames, name) {
+			removedClusters[cluster] = true
+			continue
+		}
+
+		usedClusters[cluster] = true
+		remaining = append(remaining, c)
+	}
+	if remaining == nil {
+		remaining = []any{}
+	}
+	cfg["contexts"] = remaining
+
+	if clusters, ok := cfg["clusters"].([]any); ok {
+		remaining := []any{}
+		for _, c := range clusters {
+			cluster, _ := c.(map[string]any)
+			name, _ := cluster["name"].(string)
+			if removedClusters[name] && !usedClusters[name] {
+				continue
+			}
+			remaining = append(remaining, c)
+		}
+		cfg["clusters"] = remaining
+	}
+
+	if current, _ := cfg["current-context"].(string); slices.Contains(contextNames, current) {
+		cfg["current-context"] = ""
+	}
+
+	return yaml.Marshal(cfg)
+}
+
+func decodeKubeconfigContexts(kubeconfig []byte) (map[string]any, []any, error) {
+	cfg := map[string]any{}
+	err := yaml.Unmarshal(kubeconfig, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contexts, _ := cfg["contexts"].([]any)
+
+	return cfg, contexts, nil
+}
+
+// KubeconfigExecPlugin is a client-go credential plugin used to obtain a token for a cluster.
+type KubeconfigExecPlugin struct {
+	APIVersion string
+	Command    string
+	Args       []string
+}
+
+// SetKubeconfigExecUser adds or replaces the user with the given name by a user using the given exec plugin
+// and lets the given context use this user.
+func SetKubeconfigExecUser(kubeconfig []byte, contextName, userName string, plugin KubeconfigExecPlugin) ([]byte, error) {
+	cfg, contexts, err := decodeKubeconfigContexts(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, c := range contexts {
+		ctx, ok := c.(map[string]any)
+		if !ok || ctx["name"] != contextName {
+			continue
+		}
+		data, ok := ctx["context"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("context %q has no valid context data", contextName)
+		}
+		data["user"] = userName
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	args := []any{}
+	for _, a := range plugin.Args {
+		args = append(args, a)
+	}
+
+	user := map[string]any{
+		"name": userName,
+		"user": map[string]any{
+			"exec": map[string]any{
+				"apiVersion":         plugin.APIVersion,
+				"command":            plugin.Command,
+				"args":               args,
+				"interactiveMode":    "IfAvailable",
+				"provideClusterInfo": false,
+			},
+		},
+	}
+
+	users, _ := cfg["users"].([]any)
+	users = slices.DeleteFunc(users, func(u any) bool {
+		m, ok := u.(map[string]any)
+		return ok && m["name"] == userName
+	})
+	cfg["users"] = append(users, user)
+
+	return yaml.Marshal(cfg)
+}
@@ -0,0 +1,137 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://api.a.example.com
+  name: cluster-a
+- cluster:
+    server: https://api.b.example.com
+  name: cluster-b
+- cluster:
+    server: https://api.other.example.com
+  name: other
+contexts:
+- context:
+    cluster: cluster-a
+    user: oidc-user
+  name: cluster-a
+- context:
+    cluster: cluster-b
+    user: oidc-user
+  name: cluster-b
+- context:
+    cluster: other
+    user: admin
+  name: other
+current-context: cluster-b
+users: []
+`
+
+func TestKubeconfigContextExtensions(t *testing.T) {
+	kubeconfig, err := SetKubeconfigContextExtensions([]byte(testKubeconfig), map[string]KubeconfigContextExtension{
+		"cluster-a": {ClusterID: "a-id", ProjectID: "p1"},
+		"cluster-b": {ClusterID: "b-id", ProjectID: "p2"},
+	})
+	require.NoError(t, err)
+
+	// setting the extension again must not duplicate it
+	kubeconfig, err = SetKubeconfigContextExtensions(kubeconfig, map[string]KubeconfigContextExtension{
+		"cluster-b": {ClusterID: "b-id", ProjectID: "p3"},
+	})
+	require.NoError(t, err)
+
+	got, err := KubeconfigContexts(kubeconfig)
+	require.NoError(t, err)
+
+	want := []KubeconfigContext{
+		{Name: "cluster-a", Cluster: "cluster-a", User: "oidc-user", Extension: &KubeconfigContextExtension{ClusterID: "a-id", ProjectID: "p1"}},
+		{Name: "cluster-b", Cluster: "cluster-b", User: "oidc-user", Extension: &KubeconfigContextExtension{ClusterID: "b-id", ProjectID: "p3"}},
+		{Name: "other", Cluster: "other", User: "admin"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("KubeconfigContexts() diff = %s", diff)
+	}
+
+	_, err = SetKubeconfigContextExtensions(kubeconfig, map[string]KubeconfigContextExtension{
+		"unknown": {ClusterID: "c-id"},
+	})
+	require.EqualError(t, err, `context "unknown" not found in kubeconfig`)
+}
+
+func TestRemoveKubeconfigContexts(t *testing.T) {
+	kubeconfig, err := RemoveKubeconfigContexts([]byte(testKubeconfig), "cluster-b", "other")
+	require.NoError(t, err)
+
+	want := `apiVersion: v1
+clusters:
+    - cluster:
+        server: https://api.a.example.com
+      name: cluster-a
+contexts:
+    - context:
+        cluster: cluster-a
+        user: oidc-user
+      name: cluster-a
+current-context: ""
+kind: Config
+users: []
+`
+	if diff := cmp.Diff(want, string(kubeconfig)); diff != "" {
+		t.Errorf("RemoveKubeconfigContexts() diff = %s", diff)
+	}
+}
+
+func TestSetKubeconfigExecUser(t *testing.T) {
+	kubeconfig, err := SetKubeconfigExecUser([]byte(testKubeconfig), "cluster-a", "oidc-user-exec", KubeconfigExecPlugin{
+		APIVersion: "client.authentication.k8s.io/v1",
+		Command:    "cloudctl",
+		Args:       []string{"exec-credential"},
+	})
+	require.NoError(t, err)
+
+	contexts, err := KubeconfigContexts(kubeconfig)
+	require.NoError(t, err)
+	require.Equal(t, "oidc-user-exec", contexts[0].User)
+	require.Equal(t, "oidc-user", contexts[1].User)
+
+	want := `apiVersion: v1
+clusters:
+    - cluster:
+        server: https://api.a.example.com
+      name: cluster-a
+contexts:
+    - context:
+        cluster: cluster-a
+        user: oidc-user-exec
+      name: cluster-a
+current-context: ""
+kind: Config
+users:
+    - name: oidc-user-exec
+      user:
+        exec:
+            apiVersion: client.authentication.k8s.io/v1
+            args:
+                - exec-credential
+            command: cloudctl
+            interactiveMode: IfAvailable
+            provideClusterInfo: false
+`
+	kubeconfig, err = RemoveKubeconfigContexts(kubeconfig, "cluster-b", "other")
+	require.NoError(t, err)
+	if diff := cmp.Diff(want, string(kubeconfig)); diff != "" {
+		t.Errorf("SetKubeconfigExecUser() diff = %s", diff)
+	}
+
+	_, err = SetKubeconfigExecUser([]byte(testKubeconfig), "unknown", "oidc-user-exec", KubeconfigExecPlugin{})
+	require.EqualError(t, err, `context "unknown" not found in kubeconfig`)
+}
@@ -0,0 +1,128 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a recurring time window, either on every day or on a specific weekday.
+type MaintenanceWindow struct {
+	// Weekday is nil if the window recurs every day.
+	Weekday *time.Weekday
+	// Begin and End are the minutes since midnight, a window ending before it begins spans midnight.
+	Begin    int
+	End      int
+	Location *time.Location
+}
+
+// TimeRange is a single occurrence of a maintenance window.
+type TimeRange struct {
+	Begin time.Time
+	End   time.Time
+}
+
+// Overlaps returns true if both time ranges share a point in time.
+func (r TimeRange) Overlaps(o TimeRange) bool {
+	return r.Begin.Before(o.End) && o.Begin.Before(r.End)
+}
+
+var weekdays = map[string]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// ParsePostgresMaintenanceWindow parses the maintenance window of a postgres database in the form Weekday:HH:MM-HH:MM
+// or HH:MM-HH:MM for a daily window, the times are given in UTC.
+func ParsePostgresMaintenanceWindow(s string) (*MaintenanceWindow, error) {
+	w := &MaintenanceWindow{Location: time.UTC}
+
+	spec := s
+	if day, rest, ok := strings.Cut(s, ":"); ok {
+		if wd, ok := weekdays[day]; ok {
+			w.Weekday = &wd
+			spec = rest
+		}
+	}
+
+	begin, end, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, fmt.Errorf("maintenance window %q must be given in the form Weekday:HH:MM-HH:MM, e.g. Sun:22:00-23:00", s)
+	}
+
+	var err error
+	w.Begin, err = parseClock(begin)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window %q has an invalid begin, must be given in the form Weekday:HH:MM-HH:MM, e.g. Sun:22:00-23:00", s)
+	}
+	w.End, err = parseClock(end)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window %q has an invalid end, must be given in the form Weekday:HH:MM-HH:MM, e.g. Sun:22:00-23:00", s)
+	}
+	if w.Begin == w.End {
+		return nil, fmt.Errorf("maintenance window %q must not begin and end at the same time", s)
+	}
+
+	return w, nil
+}
+
+// ParseClusterMaintenanceWindow parses the nightly maintenance window of a cluster with begin and end in the form HHMMSS+ZONE.
+func ParseClusterMaintenanceWindow(begin, end string) (*MaintenanceWindow, error) {
+	b, err := time.Parse("150405-0700", begin)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance begin %q must be given in the form HHMMSS+ZONE, e.g. 220000+0100", begin)
+	}
+	e, err := time.Parse("150405-0700", end)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance end %q must be given in the form HHMMSS+ZONE, e.g. 233000+0100", end)
+	}
+
+	// the end is converted to the zone of the begin
+	e = e.In(b.Location())
+
+	return &MaintenanceWindow{
+		Begin:    b.Hour()*60 + b.Minute(),
+		End:      e.Hour()*60 + e.Minute(),
+		Location: b.Location(),
+	}, nil
+}
+
+// Next returns the next n occurrences of the window, which end after from. an ongoing occurrence is included.
+func (w *MaintenanceWindow) Next(from time.Time, n int) []TimeRange {
+	var (
+		result []TimeRange
+		start  = from.In(w.Location)
+	)
+
+	// start a day earlier to include an ongoing occurrence spanning midnight
+	for day := -1; len(result) < n && day <= 7*n; day++ {
+		begin := time.Date(start.Year(), start.Month(), start.Day()+day, 0, w.Begin, 0, 0, w.Location)
+		if w.Weekday != nil && begin.Weekday() != *w.Weekday {
+			continue
+		}
+
+		end := time.Date(start.Year(), start.Month(), start.Day()+day, 0, w.End, 0, 0, w.Location)
+		if w.End < w.Begin {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		if end.After(from) {
+			result = append(result, TimeRange{Begin: begin, End: end})
+		}
+	}
+
+	return result
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
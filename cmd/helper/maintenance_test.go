@@ -0,0 +1,89 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePostgresMaintenanceWindow(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "Sun:22:00-23:00"},
+		{spec: "Sat:23:30-01:00"},
+		{spec: "01:00-06:00"},
+		{spec: "Sun:22:00-22:00", wantErr: true},
+		{spec: "Sunday:22:00-23:00", wantErr: true},
+		{spec: "Sun:22:00-23-00", wantErr: true},
+		{spec: "Sun:22:00", wantErr: true},
+		{spec: "Sun:25:00-23:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParsePostgresMaintenanceWindow(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePostgresMaintenanceWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowNext(t *testing.T) {
+	// a saturday
+	from := time.Date(2024, 3, 2, 23, 45, 0, 0, time.UTC)
+
+	w, err := ParsePostgresMaintenanceWindow("Sat:23:30-01:00")
+	require.NoError(t, err)
+
+	want := []TimeRange{
+		{Begin: time.Date(2024, 3, 2, 23, 30, 0, 0, time.UTC), End: time.Date(2024, 3, 3, 1, 0, 0, 0, time.UTC)},
+		{Begin: time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC), End: time.Date(2024, 3, 10, 1, 0, 0, 0, time.UTC)},
+	}
+	if diff := cmp.Diff(want, w.Next(from, 2)); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+
+	w, err = ParsePostgresMaintenanceWindow("01:00-06:00")
+	require.NoError(t, err)
+
+	want = []TimeRange{
+		{Begin: time.Date(2024, 3, 3, 1, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 3, 6, 0, 0, 0, time.UTC)},
+		{Begin: time.Date(2024, 3, 4, 1, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC)},
+	}
+	if diff := cmp.Diff(want, w.Next(from, 2)); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
+
+func TestMaintenanceWindowOverlap(t *testing.T) {
+	pg, err := ParsePostgresMaintenanceWindow("Sun:22:00-23:00")
+	require.NoError(t, err)
+
+	// 22:30-23:30 in UTC
+	cluster, err := ParseClusterMaintenanceWindow("233000+0100", "003000+0100")
+	require.NoError(t, err)
+
+	// 23:00-23:30 in UTC
+	adjacent, err := ParseClusterMaintenanceWindow("000000+0100", "003000+0100")
+	require.NoError(t, err)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	next := pg.Next(from, 1)
+	require.Len(t, next, 1)
+
+	overlaps := func(w *MaintenanceWindow) bool {
+		for _, r := range w.Next(next[0].Begin, 2) {
+			if r.Overlaps(next[0]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	require.True(t, overlaps(cluster))
+	require.False(t, overlaps(adjacent))
+}
@@ -0,0 +1,136 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PgpassEntry is a line of a postgres password file, see https://www.postgresql.org/docs/current/libpq-pgpass.html
+type PgpassEntry struct {
+	Host     string
+	Port     string
+	Database string
+	Username string
+	Password string
+}
+
+func (e PgpassEntry) key() string {
+	return strings.Join([]string{pgpassEscape(e.Host), pgpassEscape(e.Port), pgpassEscape(e.Database), pgpassEscape(e.Username)}, ":")
+}
+
+func (e PgpassEntry) String() string {
+	return e.key() + ":" + pgpassEscape(e.Password)
+}
+
+// PgpassFile returns the location of the postgres password file, which is either given by PGPASSFILE or ~/.pgpass.
+func PgpassFile() (string, error) {
+	if f := os.Getenv("PGPASSFILE"); f != "" {
+		return f, nil
+	}
+	return ExpandHomeDir("~/.pgpass")
+}
+
+// UpsertPgpass adds the entries to the postgres password file. existing entries for the same host, port, database and user
+// are replaced, all other lines are preserved. the file is created with permissions 0600 if it does not exist
+// and replaced atomically, such that a concurrent reader never sees a partially written file.
+func UpsertPgpass(filename string, entries ...PgpassEntry) error {
+	content, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			return fmt.Errorf("%s has permissions %s, which must not allow access for group or others, otherwise it is ignored by postgres clients", filename, info.Mode().Perm())
+		}
+	}
+
+	replace := map[string]PgpassEntry{}
+	for _, e := range entries {
+		replace[e.key()] = e
+	}
+
+	var (
+		buf     bytes.Buffer
+		written = map[string]bool{}
+		scanner = bufio.NewScanner(bytes.NewReader(content))
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if e, ok := replace[pgpassLineKey(line)]; ok {
+			if !written[e.key()] {
+				buf.WriteString(e.String() + "\n")
+				written[e.key()] = true
+			}
+			continue
+		}
+		buf.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if written[e.key()] {
+			continue
+		}
+		buf.WriteString(e.String() + "\n")
+		written[e.key()] = true
+	}
+
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, ".pgpass-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	// the temporary file is created with permissions 0600
+	_, err = tmp.Write(buf.Bytes())
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// pgpassLineKey returns the host, port, database and user fields of a line, such that it can be compared with the key of an entry.
+func pgpassLineKey(line string) string {
+	var (
+		fields  int
+		escaped bool
+	)
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields++
+			if fields == 4 {
+				return line[:i]
+			}
+		}
+	}
+	return ""
+}
+
+func pgpassEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `:`, `\:`).Replace(s)
+}
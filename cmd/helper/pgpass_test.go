@@ -0,0 +1,61 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertPgpass(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".pgpass")
+
+	err := os.WriteFile(filename, []byte(`# my databases
+db.example.com:5432:*:admin:secret
+1.2.3.4:32004:*:postgres:old\:password
+`), 0600)
+	require.NoError(t, err)
+
+	err = UpsertPgpass(filename,
+		PgpassEntry{Host: "1.2.3.4", Port: "32004", Database: "*", Username: "postgres", Password: "new"},
+		PgpassEntry{Host: "1.2.3.4", Port: "32004", Database: "*", Username: "standby", Password: `pass:with\colon`},
+	)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	want := `# my databases
+db.example.com:5432:*:admin:secret
+1.2.3.4:32004:*:postgres:new
+1.2.3.4:32004:*:standby:pass\:with\\colon
+`
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("UpsertPgpass() diff = %s", diff)
+	}
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, os.Chmod(filename, 0644))
+	err = UpsertPgpass(filename, PgpassEntry{Host: "1.2.3.4", Port: "32004", Database: "*", Username: "postgres", Password: "new"})
+	require.ErrorContains(t, err, "must not allow access for group or others")
+}
+
+func TestUpsertPgpassCreatesFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".pgpass")
+
+	err := UpsertPgpass(filename, PgpassEntry{Host: "1.2.3.4", Port: "32004", Database: "*", Username: "postgres", Password: "secret"})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, "1.2.3.4:32004:*:postgres:secret\n", string(content))
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
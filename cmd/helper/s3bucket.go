@@ -0,0 +1,381 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	S3VersioningEnabled   = "Enabled"
+	S3VersioningSuspended = "Suspended"
+
+	S3LifecycleRuleEnabled  = "Enabled"
+	S3LifecycleRuleDisabled = "Disabled"
+)
+
+// S3LifecycleConfiguration contains the lifecycle rules of a bucket.
+type S3LifecycleConfiguration struct {
+	XMLName xml.Name           `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LifecycleConfiguration" json:"-" yaml:"-"`
+	Rules   []*S3LifecycleRule `xml:"Rule" json:"rules" yaml:"rules"`
+}
+
+// S3LifecycleRule applies its actions to all objects matching the filter.
+type S3LifecycleRule struct {
+	ID                             string                           `xml:"ID,omitempty" json:"id,omitempty" yaml:"id,omitempty"`
+	Status                         string                           `xml:"Status" json:"status" yaml:"status"`
+	Filter                         *S3LifecycleFilter               `xml:"Filter" json:"filter,omitempty" yaml:"filter,omitempty"`
+	Expiration                     *S3LifecycleExpiration           `xml:"Expiration,omitempty" json:"expiration,omitempty" yaml:"expiration,omitempty"`
+	NoncurrentVersionExpiration    *S3LifecycleNoncurrentExpiration `xml:"NoncurrentVersionExpiration,omitempty" json:"noncurrent_version_expiration,omitempty" yaml:"noncurrent_version_expiration,omitempty"`
+	AbortIncompleteMultipartUpload *S3LifecycleAbortMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty" json:"abort_incomplete_multipart_upload,omitempty" yaml:"abort_incomplete_multipart_upload,omitempty"`
+}
+
+// S3LifecycleFilter selects the objects of a rule by key prefix, an empty prefix selects all objects.
+type S3LifecycleFilter struct {
+	Prefix string `xml:"Prefix" json:"prefix" yaml:"prefix"`
+}
+
+// S3LifecycleExpiration expires objects after a number of days or at a date.
+type S3LifecycleExpiration struct {
+	Days                      int    `xml:"Days,omitempty" json:"days,omitempty" yaml:"days,omitempty"`
+	Date                      string `xml:"Date,omitempty" json:"date,omitempty" yaml:"date,omitempty"`
+	ExpiredObjectDeleteMarker bool   `xml:"ExpiredObjectDeleteMarker,omitempty" json:"expired_object_delete_marker,omitempty" yaml:"expired_object_delete_marker,omitempty"`
+}
+
+// S3LifecycleNoncurrentExpiration expires versions of objects a number of days after they became noncurrent.
+type S3LifecycleNoncurrentExpiration struct {
+	NoncurrentDays int `xml:"NoncurrentDays" json:"noncurrent_days" yaml:"noncurrent_days"`
+}
+
+// S3LifecycleAbortMultipartUpload aborts multipart uploads which were not completed within a number of days.
+type S3LifecycleAbortMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation" json:"days_after_initiation" yaml:"days_after_initiation"`
+}
+
+// Validate checks the rules before they are sent to the endpoint, rules without status are enabled.
+func (l *S3LifecycleConfiguration) Validate() error {
+	if len(l.Rules) == 0 {
+		return fmt.Errorf("at least one lifecycle rule must be given")
+	}
+
+	ids := map[string]bool{}
+	for i, r := range l.Rules {
+		if r == nil {
+			return fmt.Errorf("rule %d is empty", i+1)
+		}
+
+		name := fmt.Sprintf("rule %d", i+1)
+		if r.ID != "" {
+			name = fmt.Sprintf("rule %q", r.ID)
+			if ids[r.ID] {
+				return fmt.Errorf("%s is given more than once", name)
+			}
+			ids[r.ID] = true
+		}
+
+		switch r.Status {
+		case "":
+			r.Status = S3LifecycleRuleEnabled
+		case S3LifecycleRuleEnabled, S3LifecycleRuleDisabled:
+		default:
+			return fmt.Errorf("%s has invalid status %q, must be %s or %s", name, r.Status, S3LifecycleRuleEnabled, S3LifecycleRuleDisabled)
+		}
+
+		if r.Filter == nil {
+			r.Filter = &S3LifecycleFilter{}
+		}
+
+		if r.Expiration == nil && r.NoncurrentVersionExpiration == nil && r.AbortIncompleteMultipartUpload == nil {
+			return fmt.Errorf("%s has no action, at least one of expiration, noncurrent_version_expiration or abort_incomplete_multipart_upload must be given", name)
+		}
+
+		if e := r.Expiration; e != nil {
+			given := 0
+			if e.Days != 0 {
+				given++
+			}
+			if e.Date != "" {
+				given++
+			}
+			if e.ExpiredObjectDeleteMarker {
+				given++
+			}
+			if given != 1 {
+				return fmt.Errorf("%s: expiration must have exactly one of days, date or expired_object_delete_marker", name)
+			}
+			if e.Days < 0 {
+				return fmt.Errorf("%s: expiration days must be positive", name)
+			}
+			if e.Date != "" {
+				d, err := time.Parse(time.RFC3339, e.Date)
+				if err != nil {
+					d, err = time.Parse(time.DateOnly, e.Date)
+					if err != nil {
+						return fmt.Errorf("%s: expiration date must be in the form YYYY-MM-DD: %w", name, err)
+					}
+				}
+				if !d.Equal(d.Truncate(24 * time.Hour)) {
+					return fmt.Errorf("%s: expiration date must be at midnight UTC", name)
+				}
+				e.Date = d.UTC().Format(time.RFC3339)
+			}
+		}
+
+		if n := r.NoncurrentVersionExpiration; n != nil && n.NoncurrentDays <= 0 {
+			return fmt.Errorf("%s: noncurrent_days must be positive", name)
+		}
+
+		if a := r.AbortIncompleteMultipartUpload; a != nil && a.DaysAfterInitiation <= 0 {
+			return fmt.Errorf("%s: days_after_initiation must be positive", name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateS3BucketPolicy checks that the policy is a valid policy document for the bucket.
+// only the structure is checked, whether principals and actions exist is up to the endpoint.
+func ValidateS3BucketPolicy(policy []byte, bucket string) error {
+	var doc map[string]json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(policy))
+	err := dec.Decode(&doc)
+	if err != nil {
+		return fmt.Errorf("policy is not a valid json document: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("policy must contain a single json document")
+	}
+
+	for k := range doc {
+		switch k {
+		case "Version", "Id", "Statement":
+		default:
+			return fmt.Errorf("unknown policy element %q", k)
+		}
+	}
+
+	var version string
+	err = json.Unmarshal(doc["Version"], &version)
+	if err != nil || (version != "2012-10-17" && version != "2008-10-17") {
+		return fmt.Errorf(`policy must have a version of "2012-10-17"`)
+	}
+
+	raw, ok := doc["Statement"]
+	if !ok {
+		return fmt.Errorf("policy has no statement")
+	}
+	var statements []map[string]json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		var statement map[string]json.RawMessage
+		err = json.Unmarshal(raw, &statement)
+		statements = append(statements, statement)
+	} else {
+		err = json.Unmarshal(raw, &statements)
+	}
+	if err != nil {
+		return fmt.Errorf("statement must be an object or a list of objects: %w", err)
+	}
+	if len(statements) == 0 {
+		return fmt.Errorf("policy has no statement")
+	}
+
+	sids := map[string]bool{}
+	for i, s := range statements {
+		name := fmt.Sprintf("statement %d", i+1)
+		if raw, ok := s["Sid"]; ok {
+			var sid string
+			err = json.Unmarshal(raw, &sid)
+			if err != nil {
+				return fmt.Errorf("%s: sid must be a string", name)
+			}
+			name = fmt.Sprintf("statement %q", sid)
+			if sids[sid] {
+				return fmt.Errorf("%s is given more than once", name)
+			}
+			sids[sid] = true
+		}
+
+		err = validateS3PolicyStatement(s, bucket)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateS3PolicyStatement(s map[string]json.RawMessage, bucket string) error {
+	for k := range s {
+		switch k {
+		case "Sid", "Effect", "Principal", "NotPrincipal", "Action", "NotAction", "Resource", "NotResource", "Condition":
+		default:
+			return fmt.Errorf("unknown element %q", k)
+		}
+	}
+
+	var effect string
+	err := json.Unmarshal(s["Effect"], &effect)
+	if err != nil || (effect != "Allow" && effect != "Deny") {
+		return fmt.Errorf(`effect must be "Allow" or "Deny"`)
+	}
+
+	_, principal := s["Principal"]
+	_, notPrincipal := s["NotPrincipal"]
+	if principal == notPrincipal {
+		return fmt.Errorf("exactly one of principal or notprincipal must be given")
+	}
+
+	actions, err := s3PolicyElement(s, "Action", "NotAction")
+	if err != nil {
+		return err
+	}
+	for _, a := range actions {
+		if a != "*" && !strings.HasPrefix(a, "s3:") {
+			return fmt.Errorf("action %q is not an s3 action", a)
+		}
+	}
+
+	resources, err := s3PolicyElement(s, "Resource", "NotResource")
+	if err != nil {
+		return err
+	}
+	arn := "arn:aws:s3:::" + bucket
+	for _, r := range resources {
+		if r != arn && !strings.HasPrefix(r, arn+"/") {
+			return fmt.Errorf("resource %q does not belong to bucket %s, use %s or %s/*", r, bucket, arn, arn)
+		}
+	}
+
+	if raw, ok := s["Condition"]; ok {
+		var condition map[string]map[string]json.RawMessage
+		err = json.Unmarshal(raw, &condition)
+		if err != nil {
+			return fmt.Errorf("condition must map operators to keys and values: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// s3PolicyElement returns the values of exactly one of the given elements, which can be a string or a list of strings.
+func s3PolicyElement(s map[string]json.RawMessage, key, notKey string) ([]string, error) {
+	raw, ok := s[key]
+	notRaw, notOk := s[notKey]
+	if ok == notOk {
+		return nil, fmt.Errorf("exactly one of %s or %s must be given", strings.ToLower(key), strings.ToLower(notKey))
+	}
+	if notOk {
+		raw = notRaw
+	}
+
+	var values []string
+	var value string
+	if json.Unmarshal(raw, &value) == nil {
+		values = []string{value}
+	} else if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("%s must be a string or a list of strings", strings.ToLower(key))
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s must not be empty", strings.ToLower(key))
+	}
+
+	return values, nil
+}
+
+// GetBucketPolicy returns the policy of the bucket, a bucket without policy returns an empty policy.
+func (c *S3Client) GetBucketPolicy(ctx context.Context, bucket string) ([]byte, error) {
+	resp, err := c.Do(ctx, http.MethodGet, bucket, "", url.Values{"policy": {""}}, nil, nil)
+	if err != nil {
+		var s3Err *S3Error
+		if errors.As(err, &s3Err) && s3Err.Code == "NoSuchBucketPolicy" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PutBucketPolicy replaces the policy of the bucket.
+func (c *S3Client) PutBucketPolicy(ctx context.Context, bucket string, policy []byte) error {
+	resp, err := c.Do(ctx, http.MethodPut, bucket, "", url.Values{"policy": {""}}, http.Header{"Content-Type": {"application/json"}}, policy)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// DeleteBucketPolicy removes the policy of the bucket.
+func (c *S3Client) DeleteBucketPolicy(ctx context.Context, bucket string) error {
+	return c.doXML(ctx, http.MethodDelete, bucket, "", url.Values{"policy": {""}}, nil, nil)
+}
+
+// GetBucketLifecycle returns the lifecycle configuration of the bucket, a bucket without rules returns an empty configuration.
+func (c *S3Client) GetBucketLifecycle(ctx context.Context, bucket string) (*S3LifecycleConfiguration, error) {
+	result := &S3LifecycleConfiguration{}
+	err := c.doXML(ctx, http.MethodGet, bucket, "", url.Values{"lifecycle": {""}}, nil, result)
+	if err != nil {
+		var s3Err *S3Error
+		if errors.As(err, &s3Err) && s3Err.Code == "NoSuchLifecycleConfiguration" {
+			return &S3LifecycleConfiguration{}, nil
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// PutBucketLifecycle replaces the lifecycle configuration of the bucket.
+func (c *S3Client) PutBucketLifecycle(ctx context.Context, bucket string, lifecycle *S3LifecycleConfiguration) error {
+	body, err := xml.Marshal(lifecycle)
+	if err != nil {
+		return err
+	}
+
+	// the content md5 is mandatory for lifecycle configurations
+	sum := md5.Sum(body) //nolint:gosec
+	header := http.Header{
+		"Content-Md5":  {base64.StdEncoding.EncodeToString(sum[:])},
+		"Content-Type": {"application/xml"},
+	}
+
+	resp, err := c.Do(ctx, http.MethodPut, bucket, "", url.Values{"lifecycle": {""}}, header, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// GetBucketVersioning returns the versioning status of the bucket, which is empty if versioning was never enabled.
+func (c *S3Client) GetBucketVersioning(ctx context.Context, bucket string) (string, error) {
+	var result struct {
+		Status string `xml:"Status"`
+	}
+	err := c.doXML(ctx, http.MethodGet, bucket, "", url.Values{"versioning": {""}}, nil, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.Status, nil
+}
+
+// PutBucketVersioning sets the versioning status of the bucket to S3VersioningEnabled or S3VersioningSuspended.
+func (c *S3Client) PutBucketVersioning(ctx context.Context, bucket, status string) error {
+	body := fmt.Appendf(nil, `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>%s</Status></VersioningConfiguration>`, status)
+	return c.doXML(ctx, http.MethodPut, bucket, "", url.Values{"versioning": {""}}, body, nil)
+}
@@ -0,0 +1,208 @@
+package helper
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateS3BucketPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			policy: `{
+	"Version": "2012-10-17",
+	"Statement": [{
+		"Sid": "read",
+		"Effect": "Allow",
+		"Principal": {"AWS": ["arn:aws:iam:::user/reader"]},
+		"Action": ["s3:GetObject", "s3:ListBucket"],
+		"Resource": ["arn:aws:s3:::logs", "arn:aws:s3:::logs/*"]
+	}]
+}`,
+		},
+		{
+			name:   "single statement",
+			policy: `{"Version": "2012-10-17", "Statement": {"Effect": "Deny", "Principal": "*", "NotAction": "s3:GetObject", "Resource": "arn:aws:s3:::logs/*"}}`,
+		},
+		{
+			name:    "no json",
+			policy:  `Version: 2012-10-17`,
+			wantErr: "policy is not a valid json document",
+		},
+		{
+			name:    "missing version",
+			policy:  `{"Statement": {"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::logs/*"}}`,
+			wantErr: `policy must have a version of "2012-10-17"`,
+		},
+		{
+			name:    "unknown element",
+			policy:  `{"Version": "2012-10-17", "Statements": []}`,
+			wantErr: `unknown policy element "Statements"`,
+		},
+		{
+			name:    "invalid effect",
+			policy:  `{"Version": "2012-10-17", "Statement": {"Sid": "s", "Effect": "allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::logs/*"}}`,
+			wantErr: `statement "s": effect must be "Allow" or "Deny"`,
+		},
+		{
+			name:    "missing principal",
+			policy:  `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::logs/*"}}`,
+			wantErr: "statement 1: exactly one of principal or notprincipal must be given",
+		},
+		{
+			name:    "foreign action",
+			policy:  `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Principal": "*", "Action": "iam:CreateUser", "Resource": "arn:aws:s3:::logs/*"}}`,
+			wantErr: `statement 1: action "iam:CreateUser" is not an s3 action`,
+		},
+		{
+			name:    "foreign bucket",
+			policy:  `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::logs-archive/*"}}`,
+			wantErr: `statement 1: resource "arn:aws:s3:::logs-archive/*" does not belong to bucket logs`,
+		},
+		{
+			name:    "duplicate sid",
+			policy:  `{"Version": "2012-10-17", "Statement": [{"Sid": "a", "Effect": "Allow", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::logs"}, {"Sid": "a", "Effect": "Allow", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::logs"}]}`,
+			wantErr: `statement "a" is given more than once`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateS3BucketPolicy([]byte(tt.policy), "logs")
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestS3LifecycleConfigurationValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		lifecycle *S3LifecycleConfiguration
+		want      *S3LifecycleConfiguration
+		wantErr   string
+	}{
+		{
+			name: "defaults are applied",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{
+				{ID: "logs", Expiration: &S3LifecycleExpiration{Days: 30}},
+				{Status: S3LifecycleRuleDisabled, Filter: &S3LifecycleFilter{Prefix: "tmp/"}, Expiration: &S3LifecycleExpiration{Date: "2030-01-01"}},
+			}},
+			want: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{
+				{ID: "logs", Status: S3LifecycleRuleEnabled, Filter: &S3LifecycleFilter{}, Expiration: &S3LifecycleExpiration{Days: 30}},
+				{Status: S3LifecycleRuleDisabled, Filter: &S3LifecycleFilter{Prefix: "tmp/"}, Expiration: &S3LifecycleExpiration{Date: "2030-01-01T00:00:00Z"}},
+			}},
+		},
+		{
+			name:      "no rules",
+			lifecycle: &S3LifecycleConfiguration{},
+			wantErr:   "at least one lifecycle rule must be given",
+		},
+		{
+			name:      "no action",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{{ID: "logs"}}},
+			wantErr:   `rule "logs" has no action`,
+		},
+		{
+			name:      "invalid status",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{{Status: "on", Expiration: &S3LifecycleExpiration{Days: 1}}}},
+			wantErr:   `rule 1 has invalid status "on"`,
+		},
+		{
+			name:      "days and date",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{{Expiration: &S3LifecycleExpiration{Days: 1, Date: "2030-01-01"}}}},
+			wantErr:   "rule 1: expiration must have exactly one of days, date or expired_object_delete_marker",
+		},
+		{
+			name:      "date not at midnight",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{{Expiration: &S3LifecycleExpiration{Date: "2030-01-01T12:00:00Z"}}}},
+			wantErr:   "rule 1: expiration date must be at midnight UTC",
+		},
+		{
+			name:      "negative noncurrent days",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{{NoncurrentVersionExpiration: &S3LifecycleNoncurrentExpiration{NoncurrentDays: -1}}}},
+			wantErr:   "rule 1: noncurrent_days must be positive",
+		},
+		{
+			name: "duplicate id",
+			lifecycle: &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{
+				{ID: "logs", Expiration: &S3LifecycleExpiration{Days: 1}},
+				{ID: "logs", Expiration: &S3LifecycleExpiration{Days: 2}},
+			}},
+			wantErr: `rule "logs" is given more than once`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.lifecycle.Validate()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, tt.lifecycle); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+		})
+	}
+}
+
+func TestS3ClientBucketLifecycle(t *testing.T) {
+	var stored []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/logs", r.URL.Path)
+		require.True(t, r.URL.Query().Has("lifecycle"))
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			sum := md5.Sum(data) //nolint:gosec
+			require.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), r.Header.Get("Content-MD5"))
+			stored = data
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`<Error><Code>NoSuchLifecycleConfiguration</Code></Error>`))
+				return
+			}
+			_, _ = w.Write(stored)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewS3Client(srv.URL, "", "access", "secret")
+
+	got, err := c.GetBucketLifecycle(context.Background(), "logs")
+	require.NoError(t, err)
+	require.Empty(t, got.Rules)
+
+	lifecycle := &S3LifecycleConfiguration{Rules: []*S3LifecycleRule{
+		{ID: "expire", Status: S3LifecycleRuleEnabled, Filter: &S3LifecycleFilter{Prefix: "app/"}, Expiration: &S3LifecycleExpiration{Days: 7}},
+	}}
+	err = c.PutBucketLifecycle(context.Background(), "logs", lifecycle)
+	require.NoError(t, err)
+	require.Contains(t, string(stored), `<Filter><Prefix>app/</Prefix></Filter>`)
+
+	got, err = c.GetBucketLifecycle(context.Background(), "logs")
+	require.NoError(t, err)
+
+	if diff := cmp.Diff(lifecycle.Rules, got.Rules); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
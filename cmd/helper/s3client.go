@@ -0,0 +1,445 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	// S3DefaultPartSize is the size of the parts of multipart uploads, which limits the size of an object to 640GiB
+	S3DefaultPartSize = 64 << 20
+	s3MaxParts        = 10000
+)
+
+// S3Client is a minimal client for s3 compatible endpoints, requests are signed with aws signature version 4
+// and use path-style addressing of buckets.
+type S3Client struct {
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+	// PartSize is the size of the parts of multipart uploads
+	PartSize int64
+
+	now func() time.Time
+}
+
+// S3Error is the error returned by an s3 endpoint.
+type S3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3 request failed with status %d", e.StatusCode)
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("s3 request failed with status %d: %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("s3 request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// NewS3Client returns a client for the given endpoint, an endpoint without scheme defaults to https.
+func NewS3Client(endpoint, region, accessKey, secretKey string) *S3Client {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if region == "" {
+		region = s3DefaultRegion
+	}
+
+	// objects can be large, so only the time until the response starts is limited
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 30 * time.Second
+
+	return &S3Client{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    &http.Client{Transport: transport},
+		PartSize:  S3DefaultPartSize,
+		now:       time.Now,
+	}
+}
+
+// S3Bucket is a bucket owned by the user of the credentials.
+type S3Bucket struct {
+	Name         string    `xml:"Name" json:"name" yaml:"name"`
+	CreationDate time.Time `xml:"CreationDate" json:"creation_date" yaml:"creation_date"`
+}
+
+// ListBuckets lists the buckets of the user.
+func (c *S3Client) ListBuckets(ctx context.Context) ([]*S3Bucket, error) {
+	var result struct {
+		Buckets []*S3Bucket `xml:"Buckets>Bucket"`
+	}
+	err := c.doXML(ctx, http.MethodGet, "", "", nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Buckets, nil
+}
+
+// CreateBucket creates a bucket.
+func (c *S3Client) CreateBucket(ctx context.Context, bucket string) error {
+	var body []byte
+	// us-east-1 is the default location and must not be given explicitly
+	if c.Region != s3DefaultRegion {
+		body = fmt.Appendf(nil, `<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>%s</LocationConstraint></CreateBucketConfiguration>`, c.Region)
+	}
+	return c.doXML(ctx, http.MethodPut, bucket, "", nil, body, nil)
+}
+
+// DeleteBucket deletes an empty bucket.
+func (c *S3Client) DeleteBucket(ctx context.Context, bucket string) error {
+	return c.doXML(ctx, http.MethodDelete, bucket, "", nil, nil, nil)
+}
+
+// GetObject returns the content of an object, the caller must close it.
+func (c *S3Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	resp, err := c.Do(ctx, http.MethodGet, bucket, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PutObject uploads size bytes read from r as object. the payload is not signed, such that it can be streamed.
+func (c *S3Client) PutObject(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := c.do(ctx, http.MethodPut, bucket, key, nil, header, r, size, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// PutObjectMultipart uploads the content read from r as object in parts of PartSize, such that content of unknown or
+// large size can be streamed with bounded memory. the upload is aborted on errors. it returns the size of the object.
+func (c *S3Client) PutObjectMultipart(ctx context.Context, bucket, key string, r io.Reader, contentType string) (int64, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	resp, err := c.Do(ctx, http.MethodPost, bucket, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return 0, err
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	_ = resp.Body.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := c.uploadParts(ctx, bucket, key, initiated.UploadID, r)
+	if err != nil {
+		abortErr := c.doXML(ctx, http.MethodDelete, bucket, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil)
+		if abortErr != nil {
+			return 0, fmt.Errorf("%w, aborting the upload failed as well: %w", err, abortErr)
+		}
+		return 0, err
+	}
+
+	return size, nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (c *S3Client) uploadParts(ctx context.Context, bucket, key, uploadID string, r io.Reader) (int64, error) {
+	var (
+		buf   = make([]byte, c.PartSize)
+		parts []s3CompletedPart
+		size  int64
+	)
+
+	for partNumber := 1; ; partNumber++ {
+		n, err := io.ReadFull(r, buf)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return 0, err
+		}
+
+		// an empty object still consists of a single part
+		if n > 0 || partNumber == 1 {
+			if partNumber > s3MaxParts {
+				return 0, fmt.Errorf("the object is larger than %d parts of %s", s3MaxParts, HumanizeSize(c.PartSize))
+			}
+
+			query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+			resp, err := c.Do(ctx, http.MethodPut, bucket, key, query, nil, buf[:n])
+			if err != nil {
+				return 0, fmt.Errorf("unable to upload part %d: %w", partNumber, err)
+			}
+			_ = resp.Body.Close()
+
+			parts = append(parts, s3CompletedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+			size += int64(n)
+		}
+
+		if last {
+			break
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return 0, err
+	}
+
+	// completing the upload can fail after the response status was already sent
+	var result struct {
+		XMLName xml.Name
+		S3Error
+	}
+	err = c.doXML(ctx, http.MethodPost, bucket, key, url.Values{"uploadId": {uploadID}}, body, &result)
+	if err != nil {
+		return 0, err
+	}
+	if result.XMLName.Local == "Error" {
+		result.StatusCode = http.StatusOK
+		return 0, &result.S3Error
+	}
+
+	return size, nil
+}
+
+// DeleteObject deletes an object, deleting an object which does not exist is not an error.
+func (c *S3Client) DeleteObject(ctx context.Context, bucket, key string) error {
+	return c.doXML(ctx, http.MethodDelete, bucket, key, nil, nil, nil)
+}
+
+// HeadBucket checks that the bucket exists and is accessible with the credentials of the client.
+func (c *S3Client) HeadBucket(ctx context.Context, bucket string) error {
+	resp, err := c.Do(ctx, http.MethodHead, bucket, "", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// S3Object is an object listed in a bucket.
+type S3Object struct {
+	Key          string    `xml:"Key" json:"key" yaml:"key"`
+	LastModified time.Time `xml:"LastModified" json:"last_modified" yaml:"last_modified"`
+	Size         int64     `xml:"Size" json:"size" yaml:"size"`
+	ETag         string    `xml:"ETag" json:"etag" yaml:"etag"`
+}
+
+// ListObjects lists the objects of the bucket with the given prefix, at most maxKeys objects are returned if maxKeys is greater than zero.
+func (c *S3Client) ListObjects(ctx context.Context, bucket, prefix string, maxKeys int) ([]*S3Object, error) {
+	var (
+		objects []*S3Object
+		token   string
+	)
+
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		if maxKeys > 0 {
+			query.Set("max-keys", fmt.Sprintf("%d", maxKeys-len(objects)))
+		}
+
+		var result struct {
+			Contents              []*S3Object `xml:"Contents"`
+			IsTruncated           bool        `xml:"IsTruncated"`
+			NextContinuationToken string      `xml:"NextContinuationToken"`
+		}
+		err := c.doXML(ctx, http.MethodGet, bucket, "", query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, result.Contents...)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" || (maxKeys > 0 && len(objects) >= maxKeys) {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// S3ObjectVersion is a version or a delete marker of an object in a bucket. objects of buckets without versioning
+// have the single version "null".
+type S3ObjectVersion struct {
+	Key       string `xml:"Key"`
+	VersionID string `xml:"VersionId"`
+}
+
+// ListObjectVersions lists all versions and delete markers of the objects of the bucket.
+func (c *S3Client) ListObjectVersions(ctx context.Context, bucket string) ([]*S3ObjectVersion, error) {
+	var (
+		versions            []*S3ObjectVersion
+		keyMarker, idMarker string
+	)
+
+	for {
+		query := url.Values{"versions": {""}}
+		if keyMarker != "" {
+			query.Set("key-marker", keyMarker)
+		}
+		if idMarker != "" {
+			query.Set("version-id-marker", idMarker)
+		}
+
+		var result struct {
+			Versions            []*S3ObjectVersion `xml:"Version"`
+			DeleteMarkers       []*S3ObjectVersion `xml:"DeleteMarker"`
+			IsTruncated         bool               `xml:"IsTruncated"`
+			NextKeyMarker       string             `xml:"NextKeyMarker"`
+			NextVersionIDMarker string             `xml:"NextVersionIdMarker"`
+		}
+		err := c.doXML(ctx, http.MethodGet, bucket, "", query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, result.Versions...)
+		versions = append(versions, result.DeleteMarkers...)
+
+		if !result.IsTruncated || result.NextKeyMarker == "" {
+			return versions, nil
+		}
+		keyMarker, idMarker = result.NextKeyMarker, result.NextVersionIDMarker
+	}
+}
+
+// DeleteObjectVersion deletes a version or a delete marker of an object irrevocably.
+func (c *S3Client) DeleteObjectVersion(ctx context.Context, bucket, key, versionID string) error {
+	return c.doXML(ctx, http.MethodDelete, bucket, key, url.Values{"versionId": {versionID}}, nil, nil)
+}
+
+// Do sends a signed request for the given bucket and object key. responses with a status code of 300 or above
+// are returned as *S3Error. the caller must close the body of the response.
+func (c *S3Client) Do(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	sum := sha256.Sum256(body)
+	return c.do(ctx, method, bucket, key, query, header, bytes.NewReader(body), int64(len(body)), hex.EncodeToString(sum[:]))
+}
+
+func (c *S3Client) do(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint %q: %w", c.Endpoint, err)
+	}
+
+	// the path is not cleaned like by JoinPath, otherwise keys like a//b or a/../b would address another object
+	if bucket != "" {
+		base, rawBase := strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(u.EscapedPath(), "/")
+		u.Path = base + "/" + bucket
+		u.RawPath = rawBase + "/" + s3EscapePath(bucket)
+		if key != "" {
+			u.Path += "/" + key
+			u.RawPath += "/" + s3EscapePath(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// s3 expects the path to be escaped only once
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	err = signer.SignHTTP(ctx, aws.Credentials{AccessKeyID: c.AccessKey, SecretAccessKey: c.SecretKey}, req, payloadHash, "s3", c.Region, c.now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		s3Err := &S3Error{}
+		data, _ := io.ReadAll(resp.Body)
+		_ = xml.Unmarshal(data, s3Err)
+		s3Err.StatusCode = resp.StatusCode
+
+		return nil, s3Err
+	}
+
+	return resp, nil
+}
+
+func (c *S3Client) doXML(ctx context.Context, method, bucket, key string, query url.Values, body []byte, result any) error {
+	resp, err := c.Do(ctx, method, bucket, key, query, nil, body)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if result == nil {
+		return nil
+	}
+
+	return xml.NewDecoder(resp.Body).Decode(result)
+}
+
+// s3EscapePath escapes all characters of the path except the unreserved ones and slashes as required for signing.
+func s3EscapePath(path string) string {
+	var b strings.Builder
+	for _, c := range []byte(path) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,296 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3ClientListObjects(t *testing.T) {
+	var pages int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/backups", r.URL.Path)
+		require.Equal(t, "2", r.URL.Query().Get("list-type"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/"), r.Header.Get("Authorization"))
+		require.Contains(t, r.Header.Get("Authorization"), "/us-east-1/s3/aws4_request")
+		require.NotEmpty(t, r.Header.Get("X-Amz-Content-Sha256"))
+
+		pages++
+		if r.URL.Query().Get("continuation-token") == "" {
+			_, _ = w.Write([]byte(`<ListBucketResult><IsTruncated>true</IsTruncated><NextContinuationToken>next</NextContinuationToken><Contents><Key>a</Key><Size>1</Size></Contents></ListBucketResult>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<ListBucketResult><IsTruncated>false</IsTruncated><Contents><Key>b</Key><Size>2</Size></Contents></ListBucketResult>`))
+	}))
+	defer srv.Close()
+
+	c := NewS3Client(srv.URL, "", "access", "secret")
+
+	objects, err := c.ListObjects(context.Background(), "backups", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, pages)
+
+	if diff := cmp.Diff([]*S3Object{{Key: "a", Size: 1}, {Key: "b", Size: 2}}, objects); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
+
+func TestS3ClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<Error><Code>InvalidAccessKeyId</Code><Message>The access key does not exist.</Message></Error>`))
+	}))
+	defer srv.Close()
+
+	c := NewS3Client(srv.URL, "", "access", "secret")
+
+	_, err := c.ListObjects(context.Background(), "backups", "", 1)
+
+	var s3Err *S3Error
+	require.True(t, errors.As(err, &s3Err))
+	require.Equal(t, http.StatusForbidden, s3Err.StatusCode)
+	require.Equal(t, "InvalidAccessKeyId", s3Err.Code)
+
+	// responses to head requests have no body
+	err = c.HeadBucket(context.Background(), "backups")
+	require.True(t, errors.As(err, &s3Err))
+	require.Equal(t, http.StatusForbidden, s3Err.StatusCode)
+	require.Empty(t, s3Err.Code)
+}
+
+func TestS3ClientPutGetObject(t *testing.T) {
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			require.Equal(t, "UNSIGNED-PAYLOAD", r.Header.Get("X-Amz-Content-Sha256"))
+			require.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			objects[r.URL.EscapedPath()] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.EscapedPath()]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+				return
+			}
+			_, _ = w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewS3Client(srv.URL, "", "access", "secret")
+
+	content := "hello world"
+	err := c.PutObject(context.Background(), "data", "dir/my file.txt", strings.NewReader(content), int64(len(content)), "text/plain")
+	require.NoError(t, err)
+	require.Contains(t, objects, "/data/dir/my%20file.txt")
+
+	r, err := c.GetObject(context.Background(), "data", "dir/my file.txt")
+	require.NoError(t, err)
+	defer func() {
+		_ = r.Close()
+	}()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+
+	_, err = c.GetObject(context.Background(), "data", "missing")
+	var s3Err *S3Error
+	require.True(t, errors.As(err, &s3Err))
+	require.Equal(t, "NoSuchKey", s3Err.Code)
+}
+
+func TestS3ClientObjectPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		key      string
+		want     string
+	}{
+		{name: "plain key", endpoint: "", key: "dir/file.txt", want: "/data/dir/file.txt"},
+		{name: "double slash", endpoint: "", key: "a//b", want: "/data/a//b"},
+		{name: "dot segments", endpoint: "", key: "a/../b/./c", want: "/data/a/../b/./c"},
+		{name: "trailing slash", endpoint: "", key: "dir/", want: "/data/dir/"},
+		{name: "reserved characters", endpoint: "", key: "a b+c=d?e#f%g", want: "/data/a%20b%2Bc%3Dd%3Fe%23f%25g"},
+		{name: "endpoint with path", endpoint: "/s3/", key: "a//b", want: "/s3/data/a//b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.EscapedPath()
+			}))
+			defer srv.Close()
+
+			c := NewS3Client(srv.URL+tt.endpoint, "", "access", "secret")
+
+			err := c.DeleteObject(context.Background(), "data", tt.key)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestS3ClientPutObjectMultipart(t *testing.T) {
+	type upload struct {
+		contentType string
+		parts       map[string][]byte
+		completed   string
+		aborted     bool
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		failPart    string
+		failOnClose bool
+		wantParts   []string
+		wantErr     string
+	}{
+		{
+			name:      "multiple parts",
+			content:   "hello world",
+			wantParts: []string{"hell", "o wo", "rld"},
+		},
+		{
+			name:      "exact multiple of the part size",
+			content:   "hello wo",
+			wantParts: []string{"hell", "o wo"},
+		},
+		{
+			name:      "empty object",
+			wantParts: []string{""},
+		},
+		{
+			name:     "part fails",
+			content:  "hello world",
+			failPart: "2",
+			wantErr:  "unable to upload part 2: s3 request failed with status 500: InternalError",
+		},
+		{
+			name:        "completion fails",
+			content:     "hello",
+			failOnClose: true,
+			wantErr:     "s3 request failed with status 200: InternalError: retry the upload",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &upload{parts: map[string][]byte{}}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/data/dumps/db.sql", r.URL.Path)
+				query := r.URL.Query()
+
+				switch {
+				case r.Method == http.MethodPost && query.Has("uploads"):
+					u.contentType = r.Header.Get("Content-Type")
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					require.Equal(t, "upload-1", query.Get("uploadId"))
+					if query.Get("partNumber") == tt.failPart {
+						w.WriteHeader(http.StatusInternalServerError)
+						_, _ = w.Write([]byte(`<Error><Code>InternalError</Code></Error>`))
+						return
+					}
+					data, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					u.parts[query.Get("partNumber")] = data
+					w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+				case r.Method == http.MethodPost:
+					require.Equal(t, "upload-1", query.Get("uploadId"))
+					data, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					u.completed = string(data)
+					if tt.failOnClose {
+						_, _ = w.Write([]byte(`<Error><Code>InternalError</Code><Message>retry the upload</Message></Error>`))
+						return
+					}
+					_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><Key>dumps/db.sql</Key></CompleteMultipartUploadResult>`))
+				case r.Method == http.MethodDelete:
+					require.Equal(t, "upload-1", query.Get("uploadId"))
+					u.aborted = true
+				}
+			}))
+			defer srv.Close()
+
+			c := NewS3Client(srv.URL, "", "access", "secret")
+			c.PartSize = 4
+
+			size, err := c.PutObjectMultipart(context.Background(), "data", "dumps/db.sql", strings.NewReader(tt.content), "application/sql")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.True(t, u.aborted)
+				return
+			}
+			require.NoError(t, err)
+			require.False(t, u.aborted)
+			require.Equal(t, int64(len(tt.content)), size)
+			require.Equal(t, "application/sql", u.contentType)
+
+			var (
+				parts    []string
+				complete strings.Builder
+			)
+			complete.WriteString("<CompleteMultipartUpload>")
+			for i := range tt.wantParts {
+				number := strconv.Itoa(i + 1)
+				parts = append(parts, string(u.parts[number]))
+				complete.WriteString(`<Part><PartNumber>` + number + `</PartNumber><ETag>&#34;etag-` + number + `&#34;</ETag></Part>`)
+			}
+			complete.WriteString("</CompleteMultipartUpload>")
+
+			if diff := cmp.Diff(tt.wantParts, parts); diff != "" {
+				t.Errorf("diff (+got -want):\n %s", diff)
+			}
+			require.Equal(t, complete.String(), u.completed)
+		})
+	}
+}
+
+func TestS3ClientObjectVersions(t *testing.T) {
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		switch r.Method {
+		case http.MethodGet:
+			require.Equal(t, "/backups", r.URL.Path)
+			require.True(t, query.Has("versions"))
+			if query.Get("key-marker") == "" {
+				_, _ = w.Write([]byte(`<ListVersionsResult><IsTruncated>true</IsTruncated><NextKeyMarker>a</NextKeyMarker><NextVersionIdMarker>2</NextVersionIdMarker><Version><Key>a</Key><VersionId>1</VersionId></Version><DeleteMarker><Key>a</Key><VersionId>2</VersionId></DeleteMarker></ListVersionsResult>`))
+				return
+			}
+			require.Equal(t, "a", query.Get("key-marker"))
+			require.Equal(t, "2", query.Get("version-id-marker"))
+			_, _ = w.Write([]byte(`<ListVersionsResult><IsTruncated>false</IsTruncated><Version><Key>b</Key><VersionId>null</VersionId></Version></ListVersionsResult>`))
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.Path+"@"+query.Get("versionId"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewS3Client(srv.URL, "", "access", "secret")
+
+	versions, err := c.ListObjectVersions(context.Background(), "backups")
+	require.NoError(t, err)
+
+	if diff := cmp.Diff([]*S3ObjectVersion{{Key: "a", VersionID: "1"}, {Key: "a", VersionID: "2"}, {Key: "b", VersionID: "null"}}, versions); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+
+	for _, v := range versions {
+		require.NoError(t, c.DeleteObjectVersion(context.Background(), "backups", v.Key, v.VersionID))
+	}
+	require.Equal(t, []string{"/backups/a@1", "/backups/a@2", "/backups/b@null"}, deleted)
+}
@@ -0,0 +1,230 @@
+package helper
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DialFunc dials a connection to the given address, e.g. through an ssh tunnel.
+type DialFunc func(network, address string) (net.Conn, error)
+
+// ParseForwardSpec parses a local port forwarding in the format of ssh -L, which is
+// [bind_address:]port:host:hostport, and returns the listen address and the target address.
+// the listen address defaults to localhost.
+func ParseForwardSpec(spec string) (string, string, error) {
+	parts := strings.Split(spec, ":")
+
+	var bind string
+	switch len(parts) {
+	case 3:
+		bind = "127.0.0.1"
+	case 4:
+		bind = parts[0]
+		parts = parts[1:]
+	default:
+		return "", "", fmt.Errorf("invalid forward %q, expected [bind_address:]port:host:hostport", spec)
+	}
+
+	for _, port := range []string{parts[0], parts[2]} {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || p == 0 {
+			return "", "", fmt.Errorf("invalid port %q in forward %q", port, spec)
+		}
+	}
+	if parts[1] == "" {
+		return "", "", fmt.Errorf("invalid forward %q, host is missing", spec)
+	}
+
+	return net.JoinHostPort(bind, parts[0]), net.JoinHostPort(parts[1], parts[2]), nil
+}
+
+// Forward accepts connections on the listener and forwards them to the target until the listener is closed.
+func Forward(l net.Listener, target string, dial DialFunc, log *slog.Logger) error {
+	return serve(l, func(conn net.Conn) {
+		remote, err := dial("tcp", target)
+		if err != nil {
+			log.Error("unable to forward connection", "client", conn.RemoteAddr().String(), "target", target, "error", err)
+			_ = conn.Close()
+			return
+		}
+
+		logSession(log, conn, target, remote)
+	})
+}
+
+const (
+	socks5Version         = 0x05
+	socks5NoAuth          = 0x00
+	socks5NoAcceptable    = 0xff
+	socks5CmdConnect      = 0x01
+	socks5AddrIPv4        = 0x01
+	socks5AddrDomain      = 0x03
+	socks5AddrIPv6        = 0x04
+	socks5Succeeded       = 0x00
+	socks5HostUnreachable = 0x04
+	socks5CmdUnsupported  = 0x07
+	socks5AddrUnsupported = 0x08
+)
+
+// ServeSOCKS5 runs a SOCKS5 proxy without authentication on the listener until the listener is closed.
+// only the CONNECT command is supported, all connections are established with dial.
+func ServeSOCKS5(l net.Listener, dial DialFunc, log *slog.Logger) error {
+	return serve(l, func(conn net.Conn) {
+		target, err := socks5Handshake(conn)
+		if err != nil {
+			log.Error("socks5 handshake failed", "client", conn.RemoteAddr().String(), "error", err)
+			_ = conn.Close()
+			return
+		}
+
+		remote, err := dial("tcp", target)
+		if err != nil {
+			log.Error("unable to proxy connection", "client", conn.RemoteAddr().String(), "target", target, "error", err)
+			_ = socks5Reply(conn, socks5HostUnreachable)
+			_ = conn.Close()
+			return
+		}
+
+		err = socks5Reply(conn, socks5Succeeded)
+		if err != nil {
+			_ = remote.Close()
+			_ = conn.Close()
+			return
+		}
+
+		logSession(log, conn, target, remote)
+	})
+}
+
+// socks5Handshake negotiates the authentication method and reads the connect request, returning the target address.
+func socks5Handshake(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("unsupported socks version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	if !slices.Contains(methods, socks5NoAuth) {
+		_, _ = conn.Write([]byte{socks5Version, socks5NoAcceptable})
+		return "", fmt.Errorf("client does not support connecting without authentication")
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5NoAuth}); err != nil {
+		return "", err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	if request[0] != socks5Version {
+		return "", fmt.Errorf("unsupported socks version %d", request[0])
+	}
+	if request[1] != socks5CmdConnect {
+		_ = socks5Reply(conn, socks5CmdUnsupported)
+		return "", fmt.Errorf("unsupported socks command %d", request[1])
+	}
+
+	var host string
+	switch request[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		ip := make([]byte, net.IPv4len)
+		if request[3] == socks5AddrIPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", err
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", err
+		}
+		host = string(domain)
+	default:
+		_ = socks5Reply(conn, socks5AddrUnsupported)
+		return "", fmt.Errorf("unsupported socks address type %d", request[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socks5Reply sends a reply with the given status, the bound address is not reported as it is not known for tunneled connections.
+func socks5Reply(conn net.Conn, status byte) error {
+	_, err := conn.Write([]byte{socks5Version, status, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+func serve(l net.Listener, handle func(conn net.Conn)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go handle(conn)
+	}
+}
+
+// logSession pipes the client connection to the remote connection and logs the start and the end of the session.
+func logSession(log *slog.Logger, client net.Conn, target string, remote net.Conn) {
+	var (
+		start = time.Now()
+		attrs = []any{"client", client.RemoteAddr().String(), "target", target}
+	)
+
+	log.Info("session started", attrs...)
+
+	sent, received := pipe(client, remote)
+
+	log.Info("session ended", append(attrs, "duration", time.Since(start).Round(time.Millisecond).String(), "bytes_sent", sent, "bytes_received", received)...)
+}
+
+// pipe copies data between both connections until one of them is closed and returns the amount of bytes copied from a to b and vice versa.
+func pipe(a, b net.Conn) (int64, int64) {
+	var (
+		wg         sync.WaitGroup
+		aToB, bToA int64
+	)
+
+	transfer := func(dst, src net.Conn, n *int64) {
+		*n, _ = io.Copy(dst, src)
+		// unblock the copy in the other direction
+		_ = dst.Close()
+		_ = src.Close()
+	}
+
+	wg.Go(func() { transfer(b, a, &aToB) })
+	wg.Go(func() { transfer(a, b, &bToA) })
+
+	wg.Wait()
+
+	return aToB, bToA
+}
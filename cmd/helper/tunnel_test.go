@@ -0,0 +1,213 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForwardSpec(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantListen string
+		wantTarget string
+		wantErr    string
+	}{
+		{spec: "8080:10.0.0.1:80", wantListen: "127.0.0.1:8080", wantTarget: "10.0.0.1:80"},
+		{spec: "0.0.0.0:9100:node-exporter:9100", wantListen: "0.0.0.0:9100", wantTarget: "node-exporter:9100"},
+		{spec: "8080:10.0.0.1", wantErr: `invalid forward "8080:10.0.0.1", expected [bind_address:]port:host:hostport`},
+		{spec: "8080:10.0.0.1:http", wantErr: `invalid port "http" in forward "8080:10.0.0.1:http"`},
+		{spec: "8080::80", wantErr: `invalid forward "8080::80", host is missing`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			listen, target, err := ParseForwardSpec(tt.spec)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantListen, listen)
+			require.Equal(t, tt.wantTarget, target)
+		})
+	}
+}
+
+func TestTunnel(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+	dial := func(network, address string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, address)
+		mu.Unlock()
+		return net.Dial(network, echo.Addr().String())
+	}
+	requireDialed := func(t *testing.T, want ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, want, dialed)
+	}
+	log := slog.New(slog.DiscardHandler)
+
+	roundtrip := func(t *testing.T, conn net.Conn) {
+		defer conn.Close()
+		_, err := conn.Write([]byte("ping"))
+		require.NoError(t, err)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		require.Equal(t, "ping", string(buf))
+	}
+
+	t.Run("forward", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() { _ = Forward(l, "10.0.0.1:80", dial, log) }()
+		defer l.Close()
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		roundtrip(t, conn)
+		requireDialed(t, "10.0.0.1:80")
+	})
+
+	t.Run("socks5", func(t *testing.T) {
+		mu.Lock()
+		dialed = nil
+		mu.Unlock()
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() { _ = ServeSOCKS5(l, dial, log) }()
+		defer l.Close()
+
+		connect := func(request []byte) net.Conn {
+			conn, err := net.Dial("tcp", l.Addr().String())
+			require.NoError(t, err)
+
+			_, err = conn.Write([]byte{0x05, 0x01, 0x00})
+			require.NoError(t, err)
+			reply := make([]byte, 2)
+			_, err = io.ReadFull(conn, reply)
+			require.NoError(t, err)
+			require.Equal(t, []byte{0x05, 0x00}, reply)
+
+			_, err = conn.Write(append([]byte{0x05, 0x01, 0x00}, request...))
+			require.NoError(t, err)
+			reply = make([]byte, 10)
+			_, err = io.ReadFull(conn, reply)
+			require.NoError(t, err)
+			require.Equal(t, byte(0x00), reply[1])
+
+			return conn
+		}
+
+		roundtrip(t, connect(append([]byte{0x03, 16}, append([]byte("kubelet.internal"), 0x28, 0x0a)...)))
+		roundtrip(t, connect([]byte{0x01, 10, 0, 0, 2, 0x23, 0x8c}))
+
+		requireDialed(t, "kubelet.internal:10250", "10.0.0.2:9100")
+	})
+}
+
+func TestPipe(t *testing.T) {
+	client, clientPeer := net.Pipe()
+	remote, remotePeer := net.Pipe()
+
+	type counts struct{ sent, received int64 }
+	done := make(chan counts, 1)
+	go func() {
+		sent, received := pipe(client, remote)
+		done <- counts{sent: sent, received: received}
+	}()
+
+	exchange(t, clientPeer, remotePeer, "hello")
+	exchange(t, remotePeer, clientPeer, "abc")
+	exchange(t, clientPeer, remotePeer, "!")
+
+	require.NoError(t, clientPeer.Close())
+
+	got := <-done
+	require.Equal(t, counts{sent: 6, received: 3}, got)
+
+	// the other side is closed as well
+	_, err := remotePeer.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestLogSession(t *testing.T) {
+	client, clientPeer := net.Pipe()
+	remote, remotePeer := net.Pipe()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	done := make(chan struct{})
+	go func() {
+		logSession(log, client, "10.0.0.1:5432", remote)
+		close(done)
+	}()
+
+	exchange(t, clientPeer, remotePeer, "select 1;")
+	exchange(t, remotePeer, clientPeer, "1")
+	require.NoError(t, remotePeer.Close())
+	<-done
+
+	var records []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r map[string]any
+		require.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+	require.Len(t, records, 2)
+
+	require.Equal(t, "session started", records[0]["msg"])
+	require.Equal(t, "pipe", records[0]["client"])
+	require.Equal(t, "10.0.0.1:5432", records[0]["target"])
+
+	require.Equal(t, "session ended", records[1]["msg"])
+	require.Equal(t, "10.0.0.1:5432", records[1]["target"])
+	require.Equal(t, float64(9), records[1]["bytes_sent"])
+	require.Equal(t, float64(1), records[1]["bytes_received"])
+	require.NotEmpty(t, records[1]["duration"])
+}
+
+// exchange writes msg to from and reads it from to.
+func exchange(t *testing.T, from, to net.Conn, msg string) {
+	t.Helper()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := from.Write([]byte(msg))
+		errs <- err
+	}()
+
+	buf := make([]byte, len(msg))
+	_, err := io.ReadFull(to, buf)
+	require.NoError(t, err)
+	require.Equal(t, msg, string(buf))
+	require.NoError(t, <-errs)
+}
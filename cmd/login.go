@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -38,34 +39,7 @@ func newLoginCmd(c *config) *cobra.Command {
 				handler = auth.NewUpdateKubeConfigHandler(viper.GetString("kubeconfig"), console, auth.WithContextName(api.FormatContextName(api.CloudContext, cs.CurrentContext)))
 			}
 
-			scopes := auth.DexScopes
-			ctx := api.MustDefaultContext()
-			if ctx.IssuerType == "generic" {
-				scopes = auth.GenericScopes
-			} else if ctx.CustomScopes != "" {
-				cs := strings.Split(ctx.CustomScopes, ",")
-				for i := range cs {
-					cs[i] = strings.TrimSpace(cs[i])
-				}
-				scopes = cs
-			}
-
-			config := auth.Config{
-				ClientID:     ctx.ClientID,
-				ClientSecret: ctx.ClientSecret,
-				IssuerURL:    ctx.IssuerURL,
-				Scopes:       scopes,
-				TokenHandler: handler,
-				Console:      console,
-				Debug:        viper.GetBool("debug"),
-				Log:          c.log,
-			}
-
-			if ctx.IssuerType == "generic" {
-				config.SuccessMessage = fmt.Sprintf(`Please close this page and return to your terminal. Manage your session on: <a href=%q>%s</a>`, ctx.IssuerURL+"/account", ctx.IssuerURL+"/account")
-			}
-
-			err := auth.OIDCFlow(config)
+			err := oidcFlow(api.MustDefaultContext(), handler, console, c.log)
 			if err != nil {
 				return err
 			}
@@ -113,3 +87,34 @@ func printTokenHandler(tokenInfo auth.TokenInfo) error {
 	fmt.Println(tokenInfo.IDToken)
 	return nil
 }
+
+// oidcFlow runs the oidc login flow against the issuer of the given cloudctl context.
+func oidcFlow(ctx api.Context, handler auth.TokenHandlerFunc, console io.Writer, log *slog.Logger) error {
+	scopes := auth.DexScopes
+	if ctx.IssuerType == "generic" {
+		scopes = auth.GenericScopes
+	} else if ctx.CustomScopes != "" {
+		cs := strings.Split(ctx.CustomScopes, ",")
+		for i := range cs {
+			cs[i] = strings.TrimSpace(cs[i])
+		}
+		scopes = cs
+	}
+
+	config := auth.Config{
+		ClientID:     ctx.ClientID,
+		ClientSecret: ctx.ClientSecret,
+		IssuerURL:    ctx.IssuerURL,
+		Scopes:       scopes,
+		TokenHandler: handler,
+		Console:      console,
+		Debug:        viper.GetBool("debug"),
+		Log:          log,
+	}
+
+	if ctx.IssuerType == "generic" {
+		config.SuccessMessage = fmt.Sprintf(`Please close this page and return to your terminal. Manage your session on: <a href=%q>%s</a>`, ctx.IssuerURL+"/account", ctx.IssuerURL+"/account")
+	}
+
+	return auth.OIDCFlow(config)
+}
@@ -2,12 +2,23 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fatih/color"
 	"github.com/fi-ts/cloud-go/api/client/database"
 	"github.com/fi-ts/cloud-go/api/models"
 	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/gosimple/slug"
 	"github.com/metal-stack/metal-lib/pkg/genericcli"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -163,6 +174,68 @@ postgres=#
 			return c.postgresDescribe(args)
 		},
 	}
+	postgresWaitCmd := &cobra.Command{
+		Use:   "wait <postgres>",
+		Short: "wait until the postgres database reaches the given condition",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresWaitCmd(args)
+		},
+		ValidArgsFunction: c.comp.PostgresListCompletion,
+	}
+	postgresRightsizeCmd := &cobra.Command{
+		Use:   "rightsize",
+		Short: "recommend the size of the postgres databases based on their accounted usage",
+		Long: `compares the accounted usage of the postgres databases with their configured size and recommends to downsize or upsize them.
+cpu and buffer are adjusted such that the average utilization gets close to 70%, storage is only ever upsized.
+memory follows the cpu by the memoryfactor of the database, so it is scaled along with the cpu for the estimated costs.
+
+You may want to estimate the difference in costs by using the prices from your contract. You can use the following environment variables:
+
+export CLOUDCTL_COSTS_CPU_HOUR=0.01        # costs per cpu hour
+export CLOUDCTL_COSTS_MEMORY_GI_HOUR=0.01  # costs per memory hour
+export CLOUDCTL_COSTS_STORAGE_GI_HOUR=0.01 # Costs per capacity hour
+
+⚠ Please be aware that any costs calculated in this fashion can still be different from the final bill as it does not include contract specific details like minimum purchase, discounts, etc.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresRightsize()
+		},
+	}
+	postgresMaintenanceCmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "show the upcoming maintenance windows of the postgres databases",
+		Long:  "shows the upcoming maintenance windows of the postgres databases in local time, windows overlapping with the maintenance window of a cluster in the same project are marked.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresMaintenance()
+		},
+	}
+	postgresTopologyCmd := &cobra.Command{
+		Use:   "topology",
+		Short: "show which postgres databases replicate from which primary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresTopology()
+		},
+	}
+	postgresFailoverCmd := &cobra.Command{
+		Use:   "failover <primary> <standby>",
+		Short: "switch the replication roles of a primary and its standby",
+		Long:  "demotes the primary to standby and promotes the standby to primary afterwards, such that there are never two primaries. each step waits for the database to be ready again, if a step fails the original roles are restored.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresFailover(args)
+		},
+		ValidArgsFunction: c.comp.PostgresListCompletion,
+	}
+	postgresProxyCmd := &cobra.Command{
+		Use:   "proxy <postgres>",
+		Short: "forward local connections to the postgres database",
+		Long:  "accepts local connections and tunnels them through the firewall of a cluster to the address of the postgres database, such that databases whose access is restricted to the egress addresses of the cluster can be reached. the credentials of the database users are added to the postgres password file for the local address.",
+		Example: `cloudctl postgres proxy <postgres> --listen 127.0.0.1:5432 --cluster <clusterid>
+psql --host=127.0.0.1 --port=5432 --username=postgres`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresProxy(args)
+		},
+		ValidArgsFunction: c.comp.PostgresListCompletion,
+	}
 	postgresConnectionStringCmd := &cobra.Command{
 		Use:   "connectionstring <postgres>",
 		Short: "return the connectionstring for a postgres",
@@ -233,6 +306,22 @@ postgres=#
 			return c.postgresBackupDelete(args)
 		},
 	}
+	postgresBackupRotateKeysCmd := &cobra.Command{
+		Use:   "rotate-keys <backup-config>",
+		Short: "rotate the s3 credentials of a backup config",
+		Long:  "adds a new key to the s3 user of the backup config, verifies that the bucket is accessible with it, updates the backup config and removes the old key. if the old key is still used elsewhere, it can be kept with --keep-old-key.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresBackupRotateKeys(args)
+		},
+	}
+	postgresBackupVerifyCmd := &cobra.Command{
+		Use:   "verify <backup-config>",
+		Short: "verify that the s3 endpoint and bucket of a backup config are accessible",
+		Long:  "verifies that the s3 endpoint of the backup config is reachable and that its bucket can be accessed with the given credentials or all keys of the given s3 user.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.postgresBackupVerify(args)
+		},
+	}
 
 	postgresCmd.AddCommand(postgresBackupCmd)
 
@@ -252,6 +341,12 @@ postgres=#
 	postgresCmd.AddCommand(postgresVersionsCmd)
 	postgresCmd.AddCommand(postgresPartitionsCmd)
 	postgresCmd.AddCommand(postgresConnectionStringCmd)
+	postgresCmd.AddCommand(postgresWaitCmd)
+	postgresCmd.AddCommand(postgresProxyCmd)
+	postgresCmd.AddCommand(postgresTopologyCmd)
+	postgresCmd.AddCommand(postgresMaintenanceCmd)
+	postgresCmd.AddCommand(postgresRightsizeCmd)
+	postgresCmd.AddCommand(postgresFailoverCmd)
 
 	postgresBackupCmd.AddCommand(postgresBackupCreateCmd)
 	postgresBackupCmd.AddCommand(postgresBackupAutoCreateCmd)
@@ -259,6 +354,8 @@ postgres=#
 	postgresBackupCmd.AddCommand(postgresBackupListCmd)
 	postgresBackupCmd.AddCommand(postgresBackupDescribeCmd)
 	postgresBackupCmd.AddCommand(postgresBackupDeleteCmd)
+	postgresBackupCmd.AddCommand(postgresBackupRotateKeysCmd)
+	postgresBackupCmd.AddCommand(postgresBackupVerifyCmd)
 
 	// Create
 	postgresCreateCmd.Flags().StringP("description", "", "", "description of the database")
@@ -273,7 +370,7 @@ postgres=#
 	postgresCreateCmd.Flags().StringP("buffer", "", "64Mi", "shared buffer for the database")
 	postgresCreateCmd.Flags().StringP("storage", "", "10Gi", "storage for the database")
 	postgresCreateCmd.Flags().StringP("backup-config", "", "", "backup to use")
-	postgresCreateCmd.Flags().StringSliceP("maintenance", "", []string{"Sun:22:00-23:00"}, "time specification of the automatic maintenance in the form Weekday:HH:MM-HH-MM [optional]")
+	postgresCreateCmd.Flags().StringSliceP("maintenance", "", []string{"Sun:22:00-23:00"}, "time specification of the automatic maintenance in UTC in the form Weekday:HH:MM-HH:MM [optional]")
 	postgresCreateCmd.Flags().BoolP("audit-logs", "", true, "enable audit logs for the database")
 	postgresCreateCmd.Flags().StringP("dedicated-load-balancer-ip", "", "", "an existing ip address for a dedicated load balancer [optional]")
 	postgresCreateCmd.Flags().StringP("auto-assign-ip-from", "", "", "a network used for auto-assigning an ip for a dedicated load balancer [optional]")
@@ -290,6 +387,7 @@ postgres=#
 	genericcli.Must(postgresCreateCmd.RegisterFlagCompletionFunc("partition", c.comp.PostgresListPartitionsCompletion))
 	genericcli.Must(postgresCreateCmd.RegisterFlagCompletionFunc("version", c.comp.PostgresListVersionsCompletion))
 	genericcli.Must(postgresCreateCmd.RegisterFlagCompletionFunc("storage-class", c.comp.PostgresListStorageClassesCompletion))
+	addPostgresWaitFlags(postgresCreateCmd)
 
 	// CreateStandby
 	postgresCreateStandbyCmd.Flags().StringP("primary-postgres-id", "", "", "id of the primary database")
@@ -298,7 +396,7 @@ postgres=#
 	postgresCreateStandbyCmd.Flags().IntP("replicas", "", 1, "replicas of the database")
 	postgresCreateStandbyCmd.Flags().StringSliceP("labels", "", []string{}, "labels to add to that postgres database")
 	postgresCreateStandbyCmd.Flags().StringP("backup-config", "", "", "backup to use")
-	postgresCreateStandbyCmd.Flags().StringSliceP("maintenance", "", []string{"Sun:22:00-23:00"}, "time specification of the automatic maintenance in the form Weekday:HH:MM-HH-MM [optional]")
+	postgresCreateStandbyCmd.Flags().StringSliceP("maintenance", "", []string{"Sun:22:00-23:00"}, "time specification of the automatic maintenance in UTC in the form Weekday:HH:MM-HH:MM [optional]")
 	postgresCreateStandbyCmd.Flags().StringP("dedicated-load-balancer-ip", "", "", "an existing ip address for a dedicated load balancer [optional]")
 	postgresCreateStandbyCmd.Flags().StringP("auto-assign-ip-from", "", "", "a network used for auto-assigning an ip for a dedicated load balancer [optional]")
 	postgresCreateStandbyCmd.Flags().IntP("dedicated-load-balancer-port", "", 0, "a port for a dedicated load balancer [optional]")
@@ -312,25 +410,36 @@ postgres=#
 	genericcli.Must(postgresCreateStandbyCmd.RegisterFlagCompletionFunc("primary-postgres-id", c.comp.PostgresListCompletion))
 	genericcli.Must(postgresCreateStandbyCmd.RegisterFlagCompletionFunc("partition", c.comp.PostgresListPartitionsCompletion))
 	genericcli.Must(postgresCreateStandbyCmd.RegisterFlagCompletionFunc("storage-class", c.comp.PostgresListStorageClassesCompletion))
+	addPostgresWaitFlags(postgresCreateStandbyCmd)
 
 	// PromoteToPrimary
 	postgresPromoteToPrimaryCmd.Flags().BoolP("synchronous", "", false, "make the replication synchronous")
+	addPostgresRoleWaitFlags(postgresPromoteToPrimaryCmd)
+	addPostgresRoleWaitFlags(postgresDemoteToStandbyCmd)
 
 	// Restore
 	postgresRestoreCmd.Flags().StringP("source-postgres-id", "", "", "if of the primary database")
-	postgresRestoreCmd.Flags().StringP("timestamp", "", time.Now().Format(ZALANDO_TIMESTAMP_FORMAT), "point-in-time to restore to")
+	postgresRestoreCmd.Flags().StringP("timestamp", "", time.Now().Format(ZALANDO_TIMESTAMP_FORMAT), "point-in-time to restore to, can also be relative like -2h or yesterday 18:00")
+	postgresRestoreCmd.Flags().Bool("pick", false, "interactively pick the point-in-time from the backups of the source database")
 	postgresRestoreCmd.Flags().StringP("version", "", "", "postgres version of the database")
 	postgresRestoreCmd.Flags().StringP("description", "", "", "description of the database")
 	postgresRestoreCmd.Flags().StringP("partition", "", "", "partition where the database should be created. Changing the partition compared to the source database requires administrative privileges")
 	postgresRestoreCmd.Flags().StringSliceP("labels", "", []string{}, "labels to add to that postgres database")
-	postgresRestoreCmd.Flags().StringSliceP("maintenance", "", []string{"Sun:22:00-23:00"}, "time specification of the automatic maintenance in the form Weekday:HH:MM-HH-MM [optional]")
+	postgresRestoreCmd.Flags().StringSliceP("maintenance", "", []string{"Sun:22:00-23:00"}, "time specification of the automatic maintenance in UTC in the form Weekday:HH:MM-HH:MM [optional]")
 	postgresRestoreCmd.Flags().BoolP("disable-loadbalancers", "", false, "disable connections with the public loadbalancer IP")
 	postgresRestoreCmd.Flags().StringP("storage-class", "", "", "the storage class to use for the database [optional]")
 
 	genericcli.Must(postgresRestoreCmd.MarkFlagRequired("source-postgres-id"))
+	postgresRestoreCmd.MarkFlagsMutuallyExclusive("timestamp", "pick")
 	genericcli.Must(postgresRestoreCmd.RegisterFlagCompletionFunc("source-postgres-id", c.comp.PostgresListCompletion))
 	genericcli.Must(postgresRestoreCmd.RegisterFlagCompletionFunc("partition", c.comp.PostgresListPartitionsCompletion))
 	genericcli.Must(postgresRestoreCmd.RegisterFlagCompletionFunc("storage-class", c.comp.PostgresListStorageClassesCompletion))
+	addPostgresWaitFlags(postgresRestoreCmd)
+
+	// Wait
+	postgresWaitCmd.Flags().String("for", postgresWaitForSocketReady, fmt.Sprintf("the condition to wait for, can be one of %s|%s|%s", postgresWaitForRunning, postgresWaitForDeleted, postgresWaitForSocketReady))
+	postgresWaitCmd.Flags().Duration("timeout", postgresWaitTimeoutDefault, "maximum duration to wait for the condition")
+	genericcli.Must(postgresWaitCmd.RegisterFlagCompletionFunc("for", cobra.FixedCompletions(postgresWaitConditions, cobra.ShellCompDirectiveNoFileComp)))
 
 	// Update
 	postgresUpdateCmd.Flags().IntP("replicas", "", 1, "replicas of the database [optional]")
@@ -374,11 +483,36 @@ postgres=#
 	# cloudctl postgres apply -f postgres1.yaml
 	`)
 
-	postgresConnectionStringCmd.Flags().StringP("type", "", "psql", "the type of the connectionstring to create, can be one of psql|jdbc")
+	postgresConnectionStringCmd.Flags().StringP("type", "", "psql", "the type of the connectionstring to create, can be one of "+strings.Join(postgresConnectionStringTypes, "|")+". pgpass adds the credentials to ~/.pgpass or the file given by PGPASSFILE.")
+	postgresConnectionStringCmd.Flags().StringP("user", "", "", "only create the connectionstring for the given database user [optional]")
+	postgresConnectionStringCmd.Flags().StringP("namespace", "", "default", "the namespace of the secret for type k8s-secret")
 	genericcli.Must(postgresConnectionStringCmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"jdbc", "psql"}, cobra.ShellCompDirectiveNoFileComp
+		return postgresConnectionStringTypes, cobra.ShellCompDirectiveNoFileComp
 	}))
 
+	postgresRightsizeCmd.Flags().StringP("project", "", "", "only consider the databases of the given project [optional]")
+	postgresRightsizeCmd.Flags().String("from", "30d", "start of the accounting window the usage is averaged over, e.g. 30d, 2006-01-02T15:04:05Z")
+	postgresRightsizeCmd.Flags().Float64("low", 0.3, "utilization below which a database is downsized")
+	postgresRightsizeCmd.Flags().Float64("high", 0.8, "utilization above which a database is upsized")
+	genericcli.Must(postgresRightsizeCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+
+	postgresMaintenanceCmd.Flags().StringP("project", "", "", "only show the databases of the given project [optional]")
+	postgresMaintenanceCmd.Flags().Int("count", 3, "the number of upcoming maintenance windows to show per database")
+	genericcli.Must(postgresMaintenanceCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+
+	postgresTopologyCmd.Flags().StringP("project", "", "", "only show the databases of the given project [optional]")
+	genericcli.Must(postgresTopologyCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+
+	postgresFailoverCmd.Flags().BoolP("synchronous", "", false, "make the replication synchronous, defaults to the replication mode of the current primary [optional]")
+	postgresFailoverCmd.Flags().Duration("timeout", postgresWaitTimeoutDefault, "maximum duration to wait for each database after changing its role")
+
+	postgresProxyCmd.Flags().StringP("listen", "", "127.0.0.1:5432", "the local address to accept connections on")
+	postgresProxyCmd.Flags().StringP("cluster", "", "", "tunnel the connections through the firewall of this cluster, whose egress addresses are allowed to access the database")
+	postgresProxyCmd.Flags().StringP("reason", "", "", "a short description why ssh access to the firewall is required [optional]")
+	postgresProxyCmd.Flags().Bool("pgpass", true, "add the credentials of the database users for the local address to the postgres password file")
+	genericcli.Must(postgresProxyCmd.MarkFlagRequired("cluster"))
+	genericcli.Must(postgresProxyCmd.RegisterFlagCompletionFunc("cluster", c.comp.ClusterListCompletion))
+
 	postgresBackupCreateCmd.Flags().StringP("name", "", "", "name of the backup config")
 	postgresBackupCreateCmd.Flags().StringP("project", "", "", "project of the backup config")
 	postgresBackupCreateCmd.Flags().StringP("schedule", "", "30 00 * * *", "backup schedule in cron syntax")
@@ -409,6 +543,28 @@ postgres=#
 	postgresBackupUpdateCmd.Flags().Int32P("retention", "", int32(0), "number of backups per database to retain [optional]")
 	genericcli.Must(postgresBackupUpdateCmd.MarkFlagRequired("id"))
 
+	postgresBackupRotateKeysCmd.Flags().String("s3-id", "", "id of the s3 user, whose keys are used by the backup config")
+	postgresBackupRotateKeysCmd.Flags().String("s3-partition", "", "s3 partition of the s3 user")
+	postgresBackupRotateKeysCmd.Flags().StringP("tenant", "t", "", "tenant of the s3 user, defaults to logged in tenant")
+	postgresBackupRotateKeysCmd.Flags().String("s3-accesskey", "", "the access key currently used by the backup config, defaults to the access key of the backup config and must match it if given [optional]")
+	postgresBackupRotateKeysCmd.Flags().String("s3-region", "", "s3 region of the bucket [optional]")
+	postgresBackupRotateKeysCmd.Flags().String("s3-encryptionkey", "", "s3 encryption key, defaults to the encryption key of the backup config and must match it if given [optional]")
+	postgresBackupRotateKeysCmd.Flags().Bool("keep-old-key", false, "do not remove the old key from the s3 user, e.g. if it is still used elsewhere [optional]")
+	genericcli.Must(postgresBackupRotateKeysCmd.MarkFlagRequired("s3-id"))
+	genericcli.Must(postgresBackupRotateKeysCmd.MarkFlagRequired("s3-partition"))
+	genericcli.Must(postgresBackupRotateKeysCmd.RegisterFlagCompletionFunc("s3-partition", c.comp.S3ListPartitionsCompletion))
+
+	postgresBackupVerifyCmd.Flags().String("s3-id", "", "id of the s3 user, all of its keys are verified [optional]")
+	postgresBackupVerifyCmd.Flags().String("s3-partition", "", "s3 partition of the s3 user [optional]")
+	postgresBackupVerifyCmd.Flags().StringP("tenant", "t", "", "tenant of the s3 user, defaults to logged in tenant")
+	postgresBackupVerifyCmd.Flags().String("s3-accesskey", "", "the access key to verify [optional]")
+	postgresBackupVerifyCmd.Flags().String("s3-secretkey", "", "the secret key to verify [optional]")
+	postgresBackupVerifyCmd.Flags().String("s3-region", "", "s3 region of the bucket [optional]")
+	postgresBackupVerifyCmd.MarkFlagsRequiredTogether("s3-id", "s3-partition")
+	postgresBackupVerifyCmd.MarkFlagsRequiredTogether("s3-accesskey", "s3-secretkey")
+	postgresBackupVerifyCmd.MarkFlagsMutuallyExclusive("s3-id", "s3-accesskey")
+	genericcli.Must(postgresBackupVerifyCmd.RegisterFlagCompletionFunc("s3-partition", c.comp.S3ListPartitionsCompletion))
+
 	return postgresCmd
 }
 
@@ -425,6 +581,9 @@ func (c *config) postgresCreate() error {
 	backupConfig := viper.GetString("backup-config")
 	storage := viper.GetString("storage")
 	maintenance := viper.GetStringSlice("maintenance")
+	if err := validatePostgresMaintenance(maintenance); err != nil {
+		return err
+	}
 	auditLogs := viper.GetBool("audit-logs")
 	lbIP := viper.GetString("dedicated-load-balancer-ip")
 	lbPort := viper.GetInt32("dedicated-load-balancer-port")
@@ -486,6 +645,10 @@ func (c *config) postgresCreate() error {
 		return err
 	}
 
+	if viper.GetBool("wait") {
+		return c.postgresWaitAndPrint(*response.Payload.ID, postgresWaitForSocketReady)
+	}
+
 	return c.listPrinter.Print(response.Payload)
 }
 
@@ -496,6 +659,9 @@ func (c *config) postgresCreateStandby() error {
 	labels := viper.GetStringSlice("labels")
 	backupConfig := viper.GetString("backup-config")
 	maintenance := viper.GetStringSlice("maintenance")
+	if err := validatePostgresMaintenance(maintenance); err != nil {
+		return err
+	}
 	var dedicatedloadbalancerip *string
 	if lbIP := viper.GetString("dedicated-load-balancer-ip"); lbIP != "" {
 		dedicatedloadbalancerip = &lbIP
@@ -539,6 +705,10 @@ func (c *config) postgresCreateStandby() error {
 		return err
 	}
 
+	if viper.GetBool("wait") {
+		return c.postgresWaitAndPrint(*response.Payload.ID, postgresWaitForSocketReady)
+	}
+
 	return c.listPrinter.Print(response.Payload)
 }
 
@@ -548,65 +718,61 @@ func (c *config) postgresPromoteToPrimary(args []string) error {
 		return err
 	}
 
-	var disableLB *bool
-	if viper.GetString("disable-loadbalancers") != "" {
-		disableLB = new(viper.GetBool("disable-loadbalancers"))
+	var synchronous *bool
+	if viper.IsSet("synchronous") {
+		// also set the sync flag if given
+		synchronous = new(viper.GetBool("synchronous"))
 	}
 
-	params := database.NewGetPostgresParams().WithID(id)
-	resp, err := c.cloud.Database.GetPostgres(params, nil)
+	pg, err := c.postgresSetReplicationRole(id, true, synchronous, postgresDisableLoadBalancersFlag())
 	if err != nil {
 		return err
 	}
-	current := resp.Payload
-
-	// copy the (minimum) current config
-	body := &models.V1PostgresUpdateRequest{
-		ProjectID:            current.ProjectID,
-		ID:                   current.ID,
-		Connection:           current.Connection,
-		AuditLogs:            current.AuditLogs,
-		PostgresParams:       current.PostgresParams,
-		DisableLoadBalancers: disableLB,
-	}
 
-	// abort if there is no configured connection
-	if body.Connection == nil {
-		return fmt.Errorf("standalone postgres cluster detected, cannot be promoted to primary")
+	if viper.GetBool("wait") {
+		pg, err = c.postgresWaitForRole(id, true, viper.GetDuration("timeout"))
+		if err != nil {
+			return err
+		}
 	}
 
-	// promote to primary
-	body.Connection.LocalSideIsPrimary = true
-	if viper.IsSet("synchronous") {
-		// also set the sync flag if given
-		body.Connection.Synchronous = viper.GetBool("synchronous")
-	}
+	return c.listPrinter.Print(pg)
+}
 
-	// send the update request
-	req := database.NewUpdatePostgresParams()
-	req.Body = body
-	uresp, err := c.cloud.Database.UpdatePostgres(req, nil)
+func (c *config) postgresDemoteToStandby(args []string) error {
+	id, err := c.postgresID("demote-to-standby", args)
 	if err != nil {
 		return err
 	}
-	return c.listPrinter.Print(uresp.Payload)
-}
 
-func (c *config) postgresDemoteToStandby(args []string) error {
-	id, err := c.postgresID("demote-to-standby", args)
+	pg, err := c.postgresSetReplicationRole(id, false, nil, postgresDisableLoadBalancersFlag())
 	if err != nil {
 		return err
 	}
 
-	var disableLB *bool
+	if viper.GetBool("wait") {
+		pg, err = c.postgresWaitForRole(id, false, viper.GetDuration("timeout"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.listPrinter.Print(pg)
+}
+
+func postgresDisableLoadBalancersFlag() *bool {
 	if viper.GetString("disable-loadbalancers") != "" {
-		disableLB = new(viper.GetBool("disable-loadbalancers"))
+		return new(viper.GetBool("disable-loadbalancers"))
 	}
+	return nil
+}
 
+// postgresSetReplicationRole promotes the database to the replication primary or demotes it to a standby.
+func (c *config) postgresSetReplicationRole(id string, primary bool, synchronous, disableLB *bool) (*models.V1PostgresResponse, error) {
 	params := database.NewGetPostgresParams().WithID(id)
 	resp, err := c.cloud.Database.GetPostgres(params, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	current := resp.Payload
 
@@ -622,20 +788,26 @@ func (c *config) postgresDemoteToStandby(args []string) error {
 
 	// abort if there is no configured connection
 	if body.Connection == nil {
-		return fmt.Errorf("standalone postgres cluster detected, cannot be demoted to standby")
+		if primary {
+			return nil, fmt.Errorf("standalone postgres cluster detected, cannot be promoted to primary")
+		}
+		return nil, fmt.Errorf("standalone postgres cluster detected, cannot be demoted to standby")
 	}
 
-	// demote to standby
-	body.Connection.LocalSideIsPrimary = false
+	body.Connection.LocalSideIsPrimary = primary
+	if synchronous != nil {
+		body.Connection.Synchronous = *synchronous
+	}
 
 	// send the update request
 	req := database.NewUpdatePostgresParams()
 	req.Body = body
 	uresp, err := c.cloud.Database.UpdatePostgres(req, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return c.listPrinter.Print(uresp.Payload)
+
+	return uresp.Payload, nil
 }
 
 func (c *config) postgresRestore() error {
@@ -645,7 +817,9 @@ func (c *config) postgresRestore() error {
 	labels := viper.GetStringSlice("labels")
 	version := viper.GetString("version")
 	maintenance := viper.GetStringSlice("maintenance")
-	timestamp := viper.GetString("timestamp")
+	if err := validatePostgresMaintenance(maintenance); err != nil {
+		return err
+	}
 	disableLB := viper.GetBool("disable-loadbalancers")
 	sc := viper.GetString("storage-class")
 
@@ -654,10 +828,30 @@ func (c *config) postgresRestore() error {
 		return err
 	}
 
-	_, err = time.Parse(ZALANDO_TIMESTAMP_FORMAT, timestamp)
+	backups, err := c.cloud.Database.GetPostgresBackups(database.NewGetPostgresBackupsParams().WithID(srcID), nil)
 	if err != nil {
-		return fmt.Errorf("restore.timestamp cannot be parsed:%s, please provide a timestamp similar to e.g. %s", timestamp, ZALANDO_TIMESTAMP_FORMAT)
+		return err
+	}
+
+	var pointInTime time.Time
+	if viper.GetBool("pick") {
+		pointInTime, err = c.postgresPickBackup(backups.Payload)
+		if err != nil {
+			return err
+		}
+	} else {
+		ts := viper.GetString("timestamp")
+		dt, err := eventuallyRelativeDateTime(ts)
+		if err != nil || ts == "" {
+			return fmt.Errorf("restore.timestamp cannot be parsed:%s, please provide a timestamp similar to e.g. %s or a relative time like -2h or yesterday 18:00", ts, ZALANDO_TIMESTAMP_FORMAT)
+		}
+		pointInTime = time.Time(dt)
+
+		if warning := postgresRestoreWindowWarning(pointInTime, backups.Payload, time.Now()); warning != "" {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", warning)
+		}
 	}
+	timestamp := pointInTime.Format(ZALANDO_TIMESTAMP_FORMAT)
 
 	prr := &models.V1PostgresRestoreRequest{
 		SourceID:             &srcID,
@@ -680,6 +874,10 @@ func (c *config) postgresRestore() error {
 		return err
 	}
 
+	if viper.GetBool("wait") {
+		return c.postgresWaitAndPrint(*response.Payload.ID, postgresWaitForSocketReady)
+	}
+
 	return c.listPrinter.Print(response.Payload)
 }
 
@@ -1036,19 +1234,112 @@ func (c *config) postgresListBackups(args []string) error {
 
 func (c *config) postgresConnectionString(args []string) error {
 	t := viper.GetString("type")
+	if !slices.Contains(postgresConnectionStringTypes, t) {
+		return fmt.Errorf("unknown connectionstring type:%s", t)
+	}
 
 	postgres, err := c.getPostgresFromArgs(args)
 	if err != nil {
 		return err
 	}
 
-	params := database.NewGetPostgresSecretsParams().WithID(*postgres.ID)
-	resp, err := c.cloud.Database.GetPostgresSecrets(params, nil)
+	userpassword, err := c.postgresUserPasswords(*postgres.ID)
 	if err != nil {
 		return err
 	}
-	ip := "localhost"
-	port := int32(5432)
+	if len(userpassword) == 0 {
+		switch t {
+		case "pgpass", "k8s-secret":
+			// do not persist placeholder credentials
+			return fmt.Errorf("postgres %s has no user secrets yet, cannot create connectionstring type %s", *postgres.ID, t)
+		}
+		userpassword["unknown"] = "unknown"
+	}
+	ip, port, ok := postgresSocket(postgres)
+	if !ok {
+		switch t {
+		case "pgpass", "k8s-secret":
+			// do not persist a placeholder address
+			return fmt.Errorf("postgres %s has no address assigned yet, wait for it with: cloudctl postgres wait %s", *postgres.ID, *postgres.ID)
+		}
+		ip = "localhost"
+		port = int32(5432)
+	}
+
+	users := slices.Sorted(maps.Keys(userpassword))
+	if user := viper.GetString("user"); user != "" {
+		if _, ok := userpassword[user]; !ok {
+			return fmt.Errorf("user %q not found, available users are: %s", user, strings.Join(users, ", "))
+		}
+		users = []string{user}
+	}
+
+	switch t {
+	case "env":
+		if len(users) > 1 {
+			return fmt.Errorf("connectionstring type env requires a single user, select one of %s with --user", strings.Join(users, ", "))
+		}
+	case "pgpass":
+		filename, err := helper.PgpassFile()
+		if err != nil {
+			return err
+		}
+		var entries []helper.PgpassEntry
+		for _, user := range users {
+			entries = append(entries, helper.PgpassEntry{
+				Host:     ip,
+				Port:     strconv.Itoa(int(port)),
+				Database: "*",
+				Username: user,
+				Password: userpassword[user],
+			})
+		}
+		err = helper.UpsertPgpass(filename, entries...)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			fmt.Printf("%s added user %q of %s:%d to %s\n", color.GreenString("✔"), user, ip, port, filename)
+		}
+		return nil
+	case "k8s-secret":
+		for _, user := range users {
+			helper.MustPrintKubernetesResource(postgresConnectionSecret(*postgres.ID, viper.GetString("namespace"), ip, port, user, userpassword[user]))
+		}
+		return nil
+	}
+
+	for _, user := range users {
+		fmt.Println(postgresConnectionStringFormat(t, ip, port, user, userpassword[user]))
+	}
+	return nil
+}
+
+// postgresUserPasswords returns the passwords of the database users by their name.
+func (c *config) postgresUserPasswords(id string) (map[string]string, error) {
+	params := database.NewGetPostgresSecretsParams().WithID(id)
+	resp, err := c.cloud.Database.GetPostgresSecrets(params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	userpassword := make(map[string]string)
+	for _, user := range resp.Payload.UserSecret {
+		userpassword[user.Username] = user.Password
+	}
+	return userpassword, nil
+}
+
+// postgresSocket returns the address clients connect to, which is the dedicated load balancer if configured.
+// false is returned if no address is assigned yet.
+func postgresSocket(postgres *models.V1PostgresResponse) (string, int32, bool) {
+	if postgres.Status == nil {
+		return "", 0, false
+	}
+	var (
+		ip   string
+		port int32
+	)
 	if postgres.Status.Socket != nil {
 		ip = postgres.Status.Socket.IP
 		port = postgres.Status.Socket.Port
@@ -1063,27 +1354,60 @@ func (c *config) postgresConnectionString(args []string) error {
 			port = ps.Port
 		}
 	}
+	return ip, port, ip != ""
+}
 
-	userpassword := make(map[string]string)
-	if len(resp.Payload.UserSecret) > 0 {
-		for _, user := range resp.Payload.UserSecret {
-			userpassword[user.Username] = user.Password
+var postgresConnectionStringTypes = []string{"psql", "jdbc", "uri", "dotnet", "libpq-keyvalue", "env", "pgpass", "k8s-secret"}
+
+// postgresConnectionStringFormat returns the connectionstring of the given line based type.
+func postgresConnectionStringFormat(t, ip string, port int32, user, password string) string {
+	switch t {
+	case "jdbc":
+		return fmt.Sprintf("jdbc:postgresql://%s:%d/postgres?user=%s&password=%s&ssl=true&tcpKeepAlive=true", ip, port, user, password)
+	case "uri":
+		u := url.URL{
+			Scheme:   "postgresql",
+			User:     url.UserPassword(user, password),
+			Host:     net.JoinHostPort(ip, strconv.Itoa(int(port))),
+			Path:     "/postgres",
+			RawQuery: "sslmode=require",
 		}
+		return u.String()
+	case "dotnet":
+		return fmt.Sprintf("Host=%s;Port=%d;Database=postgres;Username=%s;Password=%s;SSL Mode=Require", ip, port, user, password)
+	case "libpq-keyvalue":
+		quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace
+		return fmt.Sprintf("host=%s port=%d dbname=postgres user='%s' password='%s' sslmode=require", ip, port, quote(user), quote(password))
+	case "env":
+		// the output is meant to be evaluated by a shell
+		quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+		return fmt.Sprintf("PGHOST=%s\nPGPORT=%d\nPGUSER=%s\nPGPASSWORD=%s\nPGDATABASE=postgres\nPGSSLMODE=require", quote(ip), port, quote(user), quote(password))
+	default:
+		return fmt.Sprintf("PGPASSWORD=%s psql --host=%s --port=%d --username=%s", password, ip, port, user)
 	}
-	if len(userpassword) == 0 {
-		userpassword["unknown"] = "unknown"
-	}
-	for user, password := range userpassword {
-		switch t {
-		case "jdbc":
-			fmt.Printf("jdbc:postgresql://%s:%d/postgres?user=%s&password=%s&ssl=true&tcpKeepAlive=true\n", ip, port, user, password)
-		case "psql":
-			fmt.Printf("PGPASSWORD=%s psql --host=%s --port=%d --username=%s\n", password, ip, port, user)
-		default:
-			return fmt.Errorf("unknown connectionstring type:%s", t)
-		}
+}
+
+// postgresConnectionSecret returns a secret with the connection details of the given user, which can be consumed by applications.
+func postgresConnectionSecret(id, namespace, ip string, port int32, user, password string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      slug.Make(fmt.Sprintf("postgres-%s-%s", id, user)),
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"host":     ip,
+			"port":     strconv.Itoa(int(port)),
+			"database": "postgres",
+			"username": user,
+			"password": password,
+			"uri":      postgresConnectionStringFormat("uri", ip, port, user, password),
+		},
 	}
-	return nil
 }
 
 func (c *config) postgresBackupCreate(autocreate bool) error {
@@ -1178,21 +1502,11 @@ func (c *config) postgresBackupList() error {
 	return c.listPrinter.Print(resp.Payload)
 }
 func (c *config) postgresBackupDescribe(args []string) error {
-
-	if len(args) < 1 {
-		return fmt.Errorf("missing backup id")
-	}
-	if len(args) > 1 {
-		return fmt.Errorf("only a single backup id is supported")
-	}
-	id := args[0]
-
-	gbcp := database.NewGetBackupConfigParams().WithID(id)
-	resp, err := c.cloud.Database.GetBackupConfig(gbcp, nil)
+	bc, err := c.getPostgresBackupConfig(args)
 	if err != nil {
 		return err
 	}
-	return c.listPrinter.Print(resp.Payload)
+	return c.listPrinter.Print(bc)
 }
 func (c *config) postgresBackupDelete(args []string) error {
 	if len(args) < 1 {
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/client/s3"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+const (
+	postgresBackupVerifyAttempts = 5
+)
+
+// postgresBackupVerifyInterval is the interval between the attempts to access the bucket with a new key, it is shortened in tests.
+var postgresBackupVerifyInterval = 2 * time.Second
+
+func (c *config) getPostgresBackupConfig(args []string) (*models.V1PostgresBackupConfigResponse, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("missing backup id")
+	}
+	if len(args) > 1 {
+		return nil, fmt.Errorf("only a single backup id is supported")
+	}
+
+	resp, err := c.cloud.Database.GetBackupConfig(database.NewGetBackupConfigParams().WithID(args[0]), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// postgresBackupS3User returns the s3 user given by --s3-id and --s3-partition, which belongs to the project of the backup config.
+func (c *config) postgresBackupS3User(bc *models.V1PostgresBackupConfigResponse) (*models.V1S3CredentialsResponse, error) {
+	var (
+		id        = viper.GetString("s3-id")
+		partition = viper.GetString("s3-partition")
+		tenant    = viper.GetString("tenant")
+	)
+
+	request := s3.NewGets3Params()
+	request.SetBody(&models.V1S3GetRequest{
+		ID:        &id,
+		Partition: &partition,
+		Tenant:    &tenant,
+		Project:   &bc.ProjectID,
+	})
+
+	response, err := c.cloud.S3.Gets3(request, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Payload, nil
+}
+
+func (c *config) postgresBackupVerify(args []string) error {
+	bc, err := c.getPostgresBackupConfig(args)
+	if err != nil {
+		return err
+	}
+
+	type credentials struct {
+		accessKey, secretKey string
+	}
+
+	var creds []credentials
+	if viper.GetString("s3-id") != "" {
+		user, err := c.postgresBackupS3User(bc)
+		if err != nil {
+			return err
+		}
+		for _, k := range user.Keys {
+			creds = append(creds, credentials{accessKey: pointer.SafeDeref(k.AccessKey), secretKey: pointer.SafeDeref(k.SecretKey)})
+		}
+		if len(creds) == 0 {
+			return fmt.Errorf("s3 user %s has no keys", viper.GetString("s3-id"))
+		}
+	} else {
+		if viper.GetString("s3-accesskey") == "" || viper.GetString("s3-secretkey") == "" {
+			return fmt.Errorf("either --s3-id and --s3-partition or --s3-accesskey and --s3-secretkey must be given")
+		}
+		creds = append(creds, credentials{accessKey: viper.GetString("s3-accesskey"), secretKey: viper.GetString("s3-secretkey")})
+	}
+
+	var errs []error
+	for _, cred := range creds {
+		err := postgresBackupVerifyAccess(context.Background(), bc.S3Endpoint, viper.GetString("s3-region"), bc.S3BucketName, cred.accessKey, cred.secretKey)
+		if err != nil {
+			fmt.Fprintf(c.out, "%s: %s/%s is not usable with access key %s: %s\n", *bc.ID, bc.S3Endpoint, bc.S3BucketName, cred.accessKey, err)
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(c.out, "%s: %s/%s is usable with access key %s\n", *bc.ID, bc.S3Endpoint, bc.S3BucketName, cred.accessKey)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("verification of backup config %s failed", *bc.ID)
+	}
+
+	return nil
+}
+
+// postgresBackupVerifyAccess checks that the endpoint is reachable and the bucket can be listed with the given credentials.
+func postgresBackupVerifyAccess(ctx context.Context, endpoint, region, bucket, accessKey, secretKey string) error {
+	client := helper.NewS3Client(endpoint, region, accessKey, secretKey)
+
+	err := client.HeadBucket(ctx, bucket)
+	if err != nil {
+		var s3Err *helper.S3Error
+		if !errors.As(err, &s3Err) {
+			return fmt.Errorf("endpoint is not reachable: %w", err)
+		}
+
+		switch s3Err.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		case http.StatusForbidden:
+			return fmt.Errorf("access to bucket %s is denied, the credentials are invalid or lack permissions", bucket)
+		case http.StatusMovedPermanently:
+			return fmt.Errorf("bucket %s is located in a different region, use --s3-region", bucket)
+		default:
+			return err
+		}
+	}
+
+	_, err = client.ListObjects(ctx, bucket, "", 1)
+	if err != nil {
+		return fmt.Errorf("unable to list the objects of bucket %s: %w", bucket, err)
+	}
+
+	return nil
+}
+
+func (c *config) postgresBackupRotateKeys(args []string) error {
+	bc, err := c.getPostgresBackupConfig(args)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.postgresBackupS3User(bc)
+	if err != nil {
+		return err
+	}
+
+	oldKey, err := postgresBackupOldKey(bc, user, viper.GetString("s3-accesskey"))
+	if err != nil {
+		return err
+	}
+	encryptionKey, err := postgresBackupEncryptionKey(bc, viper.GetString("s3-encryptionkey"))
+	if err != nil {
+		return err
+	}
+
+	var oldKeys []string
+	for _, k := range user.Keys {
+		oldKeys = append(oldKeys, pointer.SafeDeref(k.AccessKey))
+	}
+
+	var (
+		id        = viper.GetString("s3-id")
+		partition = viper.GetString("s3-partition")
+		tenant    = viper.GetString("tenant")
+		empty     = ""
+	)
+
+	updateS3 := func(u *models.V1S3UpdateRequest) (*models.V1S3CredentialsResponse, error) {
+		u.ID = &id
+		u.Partition = &partition
+		u.Tenant = &tenant
+		u.Project = &bc.ProjectID
+
+		request := s3.NewUpdates3Params()
+		request.SetBody(u)
+
+		response, err := c.cloud.S3.Updates3(request, nil)
+		if err != nil {
+			return nil, err
+		}
+		return response.Payload, nil
+	}
+
+	// empty keys are generated by the api
+	updated, err := updateS3(&models.V1S3UpdateRequest{
+		AddKeys: []*models.V1S3Key{{AccessKey: &empty, SecretKey: &empty}},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to add a new key, nothing was changed: %w", err)
+	}
+
+	var newKey *models.V1S3Key
+	for _, k := range updated.Keys {
+		if !slices.Contains(oldKeys, pointer.SafeDeref(k.AccessKey)) {
+			newKey = k
+			break
+		}
+	}
+	if newKey == nil {
+		return fmt.Errorf("the new key is not contained in the response of the api")
+	}
+	newAccessKey := pointer.SafeDeref(newKey.AccessKey)
+	fmt.Fprintf(os.Stderr, "added access key %s to s3 user %s\n", newAccessKey, id)
+
+	rollback := func(cause error) error {
+		_, err := updateS3(&models.V1S3UpdateRequest{RemoveAccessKeys: []string{newAccessKey}})
+		if err != nil {
+			return fmt.Errorf("%w, removing the new access key %s failed as well: %w", cause, newAccessKey, err)
+		}
+		return fmt.Errorf("%w, the new access key %s was removed again", cause, newAccessKey)
+	}
+
+	// new keys can take a moment until they are accepted by the endpoint
+	for attempt := 1; ; attempt++ {
+		err = postgresBackupVerifyAccess(context.Background(), bc.S3Endpoint, viper.GetString("s3-region"), bc.S3BucketName, newAccessKey, pointer.SafeDeref(newKey.SecretKey))
+		if err == nil {
+			break
+		}
+		if attempt == postgresBackupVerifyAttempts {
+			return rollback(fmt.Errorf("the new access key %s is not usable for the backup config: %w", newAccessKey, err))
+		}
+		time.Sleep(postgresBackupVerifyInterval)
+	}
+
+	secret := &models.V1PostgresBackupSecret{
+		Accesskey:       newAccessKey,
+		Secretkey:       pointer.SafeDeref(newKey.SecretKey),
+		S3encryptionkey: encryptionKey,
+	}
+
+	req := database.NewUpdatePostgresBackupConfigParams()
+	req.SetBody(&models.V1PostgresBackupConfigUpdateRequest{
+		ID:     *bc.ID,
+		Secret: secret,
+	})
+	response, err := c.cloud.Database.UpdatePostgresBackupConfig(req, nil)
+	if err != nil {
+		return rollback(fmt.Errorf("unable to update backup config %s: %w", *bc.ID, err))
+	}
+	fmt.Fprintf(os.Stderr, "updated backup config %s to use access key %s\n", *bc.ID, newAccessKey)
+
+	if viper.GetBool("keep-old-key") {
+		fmt.Fprintf(os.Stderr, "the old access key %s is still valid, remove it once it is not used anymore with:\n  cloudctl s3 remove-key --id %s --partition %s --project %s --tenant %s --access-key %s\n", oldKey, id, partition, bc.ProjectID, pointer.SafeDeref(user.Tenant), oldKey)
+	} else {
+		// the backup config already uses the new key, so the rotation is not rolled back anymore
+		_, err = updateS3(&models.V1S3UpdateRequest{RemoveAccessKeys: []string{oldKey}})
+		if err != nil {
+			return fmt.Errorf("backup config %s uses the new access key %s, but removing the old access key %s failed: %w", *bc.ID, newAccessKey, oldKey, err)
+		}
+		fmt.Fprintf(os.Stderr, "removed the old access key %s from s3 user %s\n", oldKey, id)
+	}
+
+	return c.listPrinter.Print(response.Payload)
+}
+
+// postgresBackupOldKey returns the access key of the s3 user, which is currently used by the backup config.
+// the key is taken from the secret of the backup config, a given key must match it.
+func postgresBackupOldKey(bc *models.V1PostgresBackupConfigResponse, user *models.V1S3CredentialsResponse, given string) (string, error) {
+	var current string
+	if bc.Secret != nil {
+		current = bc.Secret.Accesskey
+	}
+
+	switch {
+	case current == "" && given == "":
+		return "", fmt.Errorf("the access key of backup config %s is unknown, specify it with --s3-accesskey", pointer.SafeDeref(bc.ID))
+	case current != "" && given != "" && current != given:
+		return "", fmt.Errorf("backup config %s uses access key %s and not %s", pointer.SafeDeref(bc.ID), current, given)
+	case current == "":
+		current = given
+	}
+
+	for _, k := range user.Keys {
+		if pointer.SafeDeref(k.AccessKey) == current {
+			return current, nil
+		}
+	}
+	return "", fmt.Errorf("access key %s of backup config %s does not belong to s3 user %s", current, pointer.SafeDeref(bc.ID), pointer.SafeDeref(user.ID))
+}
+
+// postgresBackupEncryptionKey returns the encryption key for the rotated secret. the secret is replaced as a whole,
+// so the key of a backup config using sse is carried over. a given key must match it, otherwise new backups
+// would be encrypted with another key than the existing ones.
+func postgresBackupEncryptionKey(bc *models.V1PostgresBackupConfigResponse, given string) (string, error) {
+	var current string
+	if bc.Secret != nil {
+		current = bc.Secret.S3encryptionkey
+	}
+
+	if current != "" && given != "" && current != given {
+		return "", fmt.Errorf("the given encryption key does not match the encryption key of backup config %s", pointer.SafeDeref(bc.ID))
+	}
+	if given != "" {
+		return given, nil
+	}
+	return current, nil
+}
@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/client/s3"
+	"github.com/fi-ts/cloud-go/api/models"
+	testclient "github.com/fi-ts/cloud-go/test/client"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_postgresBackupOldKey(t *testing.T) {
+	user := &models.V1S3CredentialsResponse{ID: new("backup"), Keys: []*models.V1S3Key{{AccessKey: new("a")}, {AccessKey: new("b")}}}
+	bc := func(accessKey string) *models.V1PostgresBackupConfigResponse {
+		return &models.V1PostgresBackupConfigResponse{ID: new("bc1"), Secret: &models.V1PostgresBackupSecret{Accesskey: accessKey}}
+	}
+
+	tests := []struct {
+		name    string
+		bc      *models.V1PostgresBackupConfigResponse
+		given   string
+		want    string
+		wantErr string
+	}{
+		{name: "key of the backup config", bc: bc("b"), want: "b"},
+		{name: "given key matches", bc: bc("b"), given: "b", want: "b"},
+		{name: "given key differs", bc: bc("b"), given: "a", wantErr: "backup config bc1 uses access key b and not a"},
+		{name: "key of another user", bc: bc("c"), wantErr: "access key c of backup config bc1 does not belong to s3 user backup"},
+		{name: "unknown key is given", bc: &models.V1PostgresBackupConfigResponse{ID: new("bc1")}, given: "a", want: "a"},
+		{name: "unknown key", bc: &models.V1PostgresBackupConfigResponse{ID: new("bc1")}, wantErr: "the access key of backup config bc1 is unknown, specify it with --s3-accesskey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := postgresBackupOldKey(tt.bc, user, tt.given)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_postgresBackupEncryptionKey(t *testing.T) {
+	const key = "01234567890123456789012345678901"
+
+	var (
+		sse   = &models.V1PostgresBackupConfigResponse{ID: new("bc1"), Secret: &models.V1PostgresBackupSecret{S3encryptionkey: key}}
+		plain = &models.V1PostgresBackupConfigResponse{ID: new("bc1")}
+	)
+
+	tests := []struct {
+		name    string
+		bc      *models.V1PostgresBackupConfigResponse
+		given   string
+		want    string
+		wantErr string
+	}{
+		{name: "no sse", bc: plain},
+		{name: "key is carried over", bc: sse, want: key},
+		{name: "given key matches", bc: sse, given: key, want: key},
+		{name: "given key differs", bc: sse, given: "98765432109876543210987654321098", wantErr: "the given encryption key does not match the encryption key of backup config bc1"},
+		{name: "sse is enabled", bc: plain, given: key, want: key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := postgresBackupEncryptionKey(tt.bc, tt.given)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_postgresBackupRotateKeys(t *testing.T) {
+	interval := postgresBackupVerifyInterval
+	postgresBackupVerifyInterval = time.Millisecond
+	t.Cleanup(func() { postgresBackupVerifyInterval = interval })
+
+	const encryptionKey = "01234567890123456789012345678901"
+
+	var (
+		errConflict = errors.New("conflict")
+		empty       = ""
+		oldKey      = &models.V1S3Key{AccessKey: new("old"), SecretKey: new("old-secret")}
+		otherKey    = &models.V1S3Key{AccessKey: new("other"), SecretKey: new("other-secret")}
+		newKey      = &models.V1S3Key{AccessKey: new("new"), SecretKey: new("new-secret")}
+	)
+
+	user := func(keys ...*models.V1S3Key) *models.V1S3CredentialsResponse {
+		return &models.V1S3CredentialsResponse{ID: new("backup"), Tenant: new("fits"), Project: new("p1"), Partition: new("dc1"), Keys: keys}
+	}
+
+	type step func(t *testing.T, m *mock.Mock)
+
+	getUser := func(t *testing.T, m *mock.Mock) {
+		m.On("Gets3", mock.Anything, nil).Return(&s3.Gets3OK{Payload: user(oldKey, otherKey)}, nil)
+	}
+	updateS3 := func(u *models.V1S3UpdateRequest, result *models.V1S3CredentialsResponse, err error) step {
+		return func(t *testing.T, m *mock.Mock) {
+			u.ID = new("backup")
+			u.Partition = new("dc1")
+			u.Tenant = new("fits")
+			u.Project = new("p1")
+
+			call := m.On("Updates3", testcommon.MatchIgnoreContext(t, s3.NewUpdates3Params().WithBody(u)), nil)
+			if err != nil {
+				call.Return(nil, err).Once()
+				return
+			}
+			call.Return(&s3.Updates3OK{Payload: result}, nil).Once()
+		}
+	}
+	addKey := updateS3(&models.V1S3UpdateRequest{AddKeys: []*models.V1S3Key{{AccessKey: &empty, SecretKey: &empty}}}, user(oldKey, otherKey, newKey), nil)
+	removeNewKey := func(err error) step {
+		return updateS3(&models.V1S3UpdateRequest{RemoveAccessKeys: []string{"new"}}, user(oldKey, otherKey), err)
+	}
+	removeOldKey := func(err error) step {
+		return updateS3(&models.V1S3UpdateRequest{RemoveAccessKeys: []string{"old"}}, user(otherKey, newKey), err)
+	}
+	updateConfig := func(secret *models.V1PostgresBackupSecret, err error) step {
+		return func(t *testing.T, m *mock.Mock) {
+			call := m.On("UpdatePostgresBackupConfig", testcommon.MatchIgnoreContext(t, database.NewUpdatePostgresBackupConfigParams().WithBody(&models.V1PostgresBackupConfigUpdateRequest{
+				ID:     "bc1",
+				Secret: secret,
+			})), nil)
+			if err != nil {
+				call.Return(nil, err)
+				return
+			}
+			call.Return(&database.UpdatePostgresBackupConfigOK{Payload: &models.V1PostgresBackupConfigResponse{ID: new("bc1")}}, nil)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		bucketStatus  int
+		sse           bool
+		encryptionKey string
+		keepOldKey    bool
+		s3            []step
+		database      []step
+		wantErr       string
+	}{
+		{
+			name:         "rotate",
+			bucketStatus: http.StatusOK,
+			s3: []step{
+				getUser,
+				addKey,
+				removeOldKey(nil),
+			},
+			database: []step{
+				updateConfig(&models.V1PostgresBackupSecret{Accesskey: "new", Secretkey: "new-secret"}, nil),
+			},
+		},
+		{
+			name:         "old key is kept",
+			bucketStatus: http.StatusOK,
+			keepOldKey:   true,
+			s3: []step{
+				getUser,
+				addKey,
+			},
+			database: []step{
+				updateConfig(&models.V1PostgresBackupSecret{Accesskey: "new", Secretkey: "new-secret"}, nil),
+			},
+		},
+		{
+			name:         "sse key is carried over",
+			bucketStatus: http.StatusOK,
+			sse:          true,
+			s3: []step{
+				getUser,
+				addKey,
+				removeOldKey(nil),
+			},
+			database: []step{
+				updateConfig(&models.V1PostgresBackupSecret{Accesskey: "new", Secretkey: "new-secret", S3encryptionkey: encryptionKey}, nil),
+			},
+		},
+		{
+			name:          "sse key does not match",
+			bucketStatus:  http.StatusOK,
+			sse:           true,
+			encryptionKey: "98765432109876543210987654321098",
+			s3:            []step{getUser},
+			wantErr:       "the given encryption key does not match the encryption key of backup config bc1",
+		},
+		{
+			name:         "new key is not usable",
+			bucketStatus: http.StatusForbidden,
+			s3: []step{
+				getUser,
+				addKey,
+				removeNewKey(nil),
+			},
+			wantErr: "the new access key new is not usable for the backup config: access to bucket backups is denied, the credentials are invalid or lack permissions, the new access key new was removed again",
+		},
+		{
+			name:         "backup config update fails",
+			bucketStatus: http.StatusOK,
+			s3: []step{
+				getUser,
+				addKey,
+				removeNewKey(nil),
+			},
+			database: []step{
+				updateConfig(&models.V1PostgresBackupSecret{Accesskey: "new", Secretkey: "new-secret"}, errConflict),
+			},
+			wantErr: "unable to update backup config bc1: conflict, the new access key new was removed again",
+		},
+		{
+			name:         "removing the new key fails",
+			bucketStatus: http.StatusOK,
+			s3: []step{
+				getUser,
+				addKey,
+				removeNewKey(errConflict),
+			},
+			database: []step{
+				updateConfig(&models.V1PostgresBackupSecret{Accesskey: "new", Secretkey: "new-secret"}, errConflict),
+			},
+			wantErr: "unable to update backup config bc1: conflict, removing the new access key new failed as well: conflict",
+		},
+		{
+			name:         "removing the old key fails",
+			bucketStatus: http.StatusOK,
+			s3: []step{
+				getUser,
+				addKey,
+				removeOldKey(errConflict),
+			},
+			database: []step{
+				updateConfig(&models.V1PostgresBackupSecret{Accesskey: "new", Secretkey: "new-secret"}, nil),
+			},
+			wantErr: "backup config bc1 uses the new access key new, but removing the old access key old failed: conflict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.bucketStatus)
+				if r.Method == http.MethodGet && tt.bucketStatus == http.StatusOK {
+					_, _ = w.Write([]byte(`<ListBucketResult><Name>backups</Name></ListBucketResult>`))
+				}
+			}))
+			t.Cleanup(bucket.Close)
+
+			bc := &models.V1PostgresBackupConfigResponse{
+				ID:           new("bc1"),
+				ProjectID:    "p1",
+				S3Endpoint:   bucket.URL,
+				S3BucketName: "backups",
+				Secret:       &models.V1PostgresBackupSecret{Accesskey: "old"},
+			}
+			if tt.sse {
+				bc.Secret.S3encryptionkey = encryptionKey
+			}
+
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("s3-id", "backup")
+			viper.Set("s3-partition", "dc1")
+			viper.Set("tenant", "fits")
+			viper.Set("s3-encryptionkey", tt.encryptionKey)
+			viper.Set("keep-old-key", tt.keepOldKey)
+
+			var (
+				out            bytes.Buffer
+				s3Mock, dbMock *mock.Mock
+			)
+			c := &config{
+				cloud: testclient.NewCloudMockClient(t, &testclient.CloudMockFns{
+					S3: func(m *mock.Mock) {
+						s3Mock = m
+						for _, s := range tt.s3 {
+							s(t, m)
+						}
+					},
+					Database: func(m *mock.Mock) {
+						dbMock = m
+						m.On("GetBackupConfig", mock.Anything, nil).Return(&database.GetBackupConfigOK{Payload: bc}, nil)
+						for _, s := range tt.database {
+							s(t, m)
+						}
+					},
+				}),
+				out:         &out,
+				listPrinter: printers.NewJSONPrinter().WithOut(&out),
+			}
+
+			err := c.postgresBackupRotateKeys([]string{"bc1"})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// the removal of the old or the new key is only visible in the calls to the api
+			s3Mock.AssertExpectations(t)
+			dbMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+// validatePostgresMaintenance checks the maintenance windows given with --maintenance before sending the request.
+func validatePostgresMaintenance(maintenance []string) error {
+	for _, m := range maintenance {
+		_, err := helper.ParsePostgresMaintenanceWindow(m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *config) postgresMaintenance() error {
+	var (
+		project = viper.GetString("project")
+		count   = viper.GetInt("count")
+		pgs     []*models.V1PostgresResponse
+	)
+
+	if count < 1 {
+		return fmt.Errorf("--count must be at least 1")
+	}
+
+	if project != "" {
+		params := database.NewFindPostgresParams()
+		params.SetBody(&models.V1PostgresFindRequest{ProjectID: project})
+		resp, err := c.cloud.Database.FindPostgres(params, nil)
+		if err != nil {
+			return err
+		}
+		pgs = resp.Payload
+	} else {
+		resp, err := c.cloud.Database.ListPostgres(nil, nil)
+		if err != nil {
+			return err
+		}
+		pgs = resp.Payload
+	}
+
+	cfr := &models.V1ClusterFindRequest{}
+	if project != "" {
+		cfr.ProjectID = &project
+	}
+	fcp := cluster.NewFindClustersParams()
+	fcp.SetBody(cfr)
+	shoots, err := c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.listPrinter.Print(postgresMaintenanceWindows(pgs, shoots.Payload, time.Now(), count))
+}
+
+// postgresMaintenanceWindows returns the next count maintenance windows of every database together with the clusters
+// of the same project, whose maintenance window overlaps.
+func postgresMaintenanceWindows(pgs []*models.V1PostgresResponse, shoots []*models.V1ClusterResponse, now time.Time, count int) []*tableprinters.PostgresMaintenanceWindow {
+	type clusterWindow struct {
+		name   string
+		window *helper.MaintenanceWindow
+	}
+
+	clusterWindows := map[string][]clusterWindow{}
+	for _, s := range shoots {
+		if s.Maintenance == nil || s.Maintenance.TimeWindow == nil {
+			continue
+		}
+		w, err := helper.ParseClusterMaintenanceWindow(pointer.SafeDeref(s.Maintenance.TimeWindow.Begin), pointer.SafeDeref(s.Maintenance.TimeWindow.End))
+		if err != nil {
+			continue
+		}
+		project := pointer.SafeDeref(s.ProjectID)
+		clusterWindows[project] = append(clusterWindows[project], clusterWindow{name: pointer.SafeDeref(s.Name), window: w})
+	}
+
+	var result []*tableprinters.PostgresMaintenanceWindow
+
+	for _, pg := range pgs {
+		var ranges []helper.TimeRange
+		for _, m := range pg.Maintenance {
+			w, err := helper.ParsePostgresMaintenanceWindow(m)
+			if err != nil {
+				continue
+			}
+			ranges = append(ranges, w.Next(now, count)...)
+		}
+
+		slices.SortFunc(ranges, func(a, b helper.TimeRange) int {
+			return a.Begin.Compare(b.Begin)
+		})
+		if len(ranges) > count {
+			ranges = ranges[:count]
+		}
+
+		for _, r := range ranges {
+			var overlapping []string
+			for _, cw := range clusterWindows[pg.ProjectID] {
+				// cluster windows are shorter than a day, so the two occurrences around the begin are sufficient
+				for _, cr := range cw.window.Next(r.Begin, 2) {
+					if cr.Overlaps(r) {
+						overlapping = append(overlapping, cw.name)
+						break
+					}
+				}
+			}
+			slices.Sort(overlapping)
+
+			result = append(result, &tableprinters.PostgresMaintenanceWindow{
+				ID:                  pointer.SafeDeref(pg.ID),
+				Description:         pg.Description,
+				ProjectID:           pg.ProjectID,
+				Begin:               r.Begin,
+				End:                 r.End,
+				OverlappingClusters: overlapping,
+			})
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b *tableprinters.PostgresMaintenanceWindow) int {
+		return cmp.Or(
+			a.Begin.Compare(b.Begin),
+			cmp.Compare(a.ProjectID, b.ProjectID),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	return result
+}
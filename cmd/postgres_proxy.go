@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+func (c *config) postgresProxy(args []string) error {
+	pg, err := c.getPostgresFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	ip, port, ok := postgresSocket(pg)
+	if !ok {
+		return fmt.Errorf("postgres %s has no address assigned yet, wait for it with: cloudctl postgres wait %s", *pg.ID, *pg.ID)
+	}
+	target := net.JoinHostPort(ip, strconv.Itoa(int(port)))
+
+	userpassword, err := c.postgresUserPasswords(*pg.ID)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// the firewall is required, connecting from the local machine is blocked if the access is restricted to source ranges
+	cid := viper.GetString("cluster")
+	shoot, err := c.cloud.Cluster.FindCluster(cluster.NewFindClusterParams().WithID(cid), nil)
+	if err != nil {
+		return err
+	}
+
+	keypair, err := c.sshKeyPair(cid, false, pointer.PointerOrNil(viper.GetString("reason")))
+	if err != nil {
+		return err
+	}
+
+	s, err := c.clusterFirewallSSH(ctx, shoot.Payload, keypair, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = s.Close()
+	}()
+
+	l, err := net.Listen("tcp", viper.GetString("listen"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+
+	localIP, localPort, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "proxying %s to postgres %s at %s\n", l.Addr().String(), *pg.ID, target)
+
+	err = postgresProxyCredentials(os.Stderr, *pg.ID, localIP, localPort, userpassword, viper.GetBool("pgpass"))
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- helper.Forward(l, target, s.tenantDial, c.log)
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr, "closing proxy")
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+// postgresProxyCredentials stores the credentials of the users for the local address of the proxy in the postgres
+// password file, such that clients connecting to the proxy find them, and prints how to connect.
+func postgresProxyCredentials(out io.Writer, id, host, port string, userpassword map[string]string, pgpass bool) error {
+	if len(userpassword) == 0 {
+		fmt.Fprintln(out, "the database has no user secrets yet")
+		return nil
+	}
+
+	users := slices.Sorted(maps.Keys(userpassword))
+
+	if !pgpass {
+		// passwords are not printed as the output is likely to end up in a terminal scrollback or a log
+		fmt.Fprintln(out, "connect with:")
+		for _, user := range users {
+			fmt.Fprintf(out, "  PGPASSWORD=<password> psql --host=%s --port=%s --username=%s\n", host, port, user)
+		}
+		fmt.Fprintf(out, "the passwords are shown by: cloudctl postgres connectionstring %s --type psql\n", id)
+		return nil
+	}
+
+	filename, err := helper.PgpassFile()
+	if err != nil {
+		return err
+	}
+
+	var entries []helper.PgpassEntry
+	for _, user := range users {
+		entries = append(entries, helper.PgpassEntry{
+			Host:     host,
+			Port:     port,
+			Database: "*",
+			Username: user,
+			Password: userpassword[user],
+		})
+	}
+
+	err = helper.UpsertPgpass(filename, entries...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "added the credentials to %s, connect with:\n", filename)
+	for _, user := range users {
+		fmt.Fprintf(out, "  psql --host=%s --port=%s --username=%s\n", host, port, user)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_postgresProxyCredentials(t *testing.T) {
+	userpassword := map[string]string{"postgres": "p:w", "app": "secret"}
+
+	tests := []struct {
+		name         string
+		userpassword map[string]string
+		pgpass       bool
+		wantOut      string
+		wantPgpass   string
+	}{
+		{
+			name:         "credentials are added to the password file",
+			userpassword: userpassword,
+			pgpass:       true,
+			wantOut: `added the credentials to PGPASSFILE, connect with:
+  psql --host=127.0.0.1 --port=15432 --username=app
+  psql --host=127.0.0.1 --port=15432 --username=postgres
+`,
+			wantPgpass: "127.0.0.1:15432:*:app:secret\n127.0.0.1:15432:*:postgres:p\\:w\n",
+		},
+		{
+			name:         "passwords are not printed",
+			userpassword: userpassword,
+			wantOut: `connect with:
+  PGPASSWORD=<password> psql --host=127.0.0.1 --port=15432 --username=app
+  PGPASSWORD=<password> psql --host=127.0.0.1 --port=15432 --username=postgres
+the passwords are shown by: cloudctl postgres connectionstring pg1 --type psql
+`,
+		},
+		{
+			name:         "no user secrets",
+			userpassword: map[string]string{},
+			pgpass:       true,
+			wantOut:      "the database has no user secrets yet\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgpass := filepath.Join(t.TempDir(), "pgpass")
+			t.Setenv("PGPASSFILE", pgpass)
+
+			var out bytes.Buffer
+			err := postgresProxyCredentials(&out, "pg1", "127.0.0.1", "15432", tt.userpassword, tt.pgpass)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantOut, string(bytes.ReplaceAll(out.Bytes(), []byte(pgpass), []byte("PGPASSFILE"))))
+
+			content, err := os.ReadFile(pgpass)
+			if tt.wantPgpass == "" {
+				require.True(t, os.IsNotExist(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPgpass, string(content))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+)
+
+// postgresPickBackup lets the user choose one of the given backups and returns its point-in-time.
+func (c *config) postgresPickBackup(backups []*models.V1PostgresBackupEntry) (time.Time, error) {
+	if len(backups) == 0 {
+		return time.Time{}, fmt.Errorf("the source database has no backups to pick from")
+	}
+
+	backups = slices.Clone(backups)
+	slices.SortFunc(backups, func(a, b *models.V1PostgresBackupEntry) int {
+		return time.Time(b.Timestamp).Compare(time.Time(a.Timestamp))
+	})
+
+	for i, b := range backups {
+		fmt.Fprintf(c.out, "%3d  %s  %10s  %s\n", i+1, time.Time(b.Timestamp).Format(ZALANDO_TIMESTAMP_FORMAT), helper.HumanizeSize(pointer.SafeDeref(b.Size)), pointer.SafeDeref(b.Name))
+	}
+	fmt.Fprintf(c.out, "pick a backup to restore (1-%d): ", len(backups))
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+
+	answer := strings.TrimSpace(scanner.Text())
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(backups) {
+		return time.Time{}, fmt.Errorf("unexpected answer given (%q), aborting", answer)
+	}
+
+	return time.Time(backups[n-1].Timestamp), nil
+}
+
+// postgresRestoreWindowWarning returns a warning if the point-in-time is not covered by the backups of the source database.
+// the window starts with the oldest backup and ends now, as the write-ahead log is archived continuously.
+func postgresRestoreWindowWarning(pointInTime time.Time, backups []*models.V1PostgresBackupEntry, now time.Time) string {
+	if len(backups) == 0 {
+		return "the source database has no backups, the restore will most likely fail"
+	}
+
+	oldest := time.Time(backups[0].Timestamp)
+	for _, b := range backups[1:] {
+		if ts := time.Time(b.Timestamp); ts.Before(oldest) {
+			oldest = ts
+		}
+	}
+
+	switch {
+	case pointInTime.Before(oldest):
+		return fmt.Sprintf("%s is before the oldest backup from %s, the restore will most likely fail", pointInTime.Format(ZALANDO_TIMESTAMP_FORMAT), oldest.Format(ZALANDO_TIMESTAMP_FORMAT))
+	case pointInTime.After(now):
+		return fmt.Sprintf("%s is in the future, the database is restored to the latest state available", pointInTime.Format(ZALANDO_TIMESTAMP_FORMAT))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_postgresRestoreWindowWarning(t *testing.T) {
+	var (
+		now    = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+		oldest = now.Add(-72 * time.Hour)
+
+		backups = []*models.V1PostgresBackupEntry{
+			{Name: new("b2"), Timestamp: strfmt.DateTime(now.Add(-24 * time.Hour))},
+			{Name: new("b0"), Timestamp: strfmt.DateTime(oldest)},
+			{Name: new("b1"), Timestamp: strfmt.DateTime(now.Add(-48 * time.Hour))},
+		}
+	)
+
+	tests := []struct {
+		name        string
+		pointInTime time.Time
+		backups     []*models.V1PostgresBackupEntry
+		want        string
+	}{
+		{
+			name:        "inside the window",
+			pointInTime: now.Add(-60 * time.Hour),
+			backups:     backups,
+		},
+		{
+			name:        "at the oldest backup",
+			pointInTime: oldest,
+			backups:     backups,
+		},
+		{
+			name:        "now",
+			pointInTime: now,
+			backups:     backups,
+		},
+		{
+			name:        "before the oldest backup",
+			pointInTime: oldest.Add(-time.Minute),
+			backups:     backups,
+			want:        "2024-03-07T11:59:00+00:00 is before the oldest backup from 2024-03-07T12:00:00+00:00, the restore will most likely fail",
+		},
+		{
+			name:        "in the future",
+			pointInTime: now.Add(time.Hour),
+			backups:     backups,
+			want:        "2024-03-10T13:00:00+00:00 is in the future, the database is restored to the latest state available",
+		},
+		{
+			name:        "no backups",
+			pointInTime: now,
+			want:        "the source database has no backups, the restore will most likely fail",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, postgresRestoreWindowWarning(tt.pointInTime, tt.backups, now))
+		})
+	}
+}
+
+func Test_postgresPickBackup(t *testing.T) {
+	var (
+		now     = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+		backups = []*models.V1PostgresBackupEntry{
+			{Name: new("b1"), Size: new(int64(1024)), Timestamp: strfmt.DateTime(now.Add(-48 * time.Hour))},
+			{Name: new("b2"), Size: new(int64(2048)), Timestamp: strfmt.DateTime(now.Add(-24 * time.Hour))},
+			{Name: new("b0"), Size: new(int64(512)), Timestamp: strfmt.DateTime(now.Add(-72 * time.Hour))},
+		}
+	)
+
+	tests := []struct {
+		name    string
+		backups []*models.V1PostgresBackupEntry
+		answer  string
+		want    time.Time
+		wantErr string
+	}{
+		{
+			name:    "newest backup first",
+			backups: backups,
+			answer:  "1\n",
+			want:    now.Add(-24 * time.Hour),
+		},
+		{
+			name:    "oldest backup last",
+			backups: backups,
+			answer:  "3\n",
+			want:    now.Add(-72 * time.Hour),
+		},
+		{
+			name:    "out of range",
+			backups: backups,
+			answer:  "4\n",
+			wantErr: `unexpected answer given ("4"), aborting`,
+		},
+		{
+			name:    "no number",
+			backups: backups,
+			answer:  "latest\n",
+			wantErr: `unexpected answer given ("latest"), aborting`,
+		},
+		{
+			name:    "no backups",
+			wantErr: "the source database has no backups to pick from",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			require.NoError(t, err)
+			_, err = w.WriteString(tt.answer)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			stdin := os.Stdin
+			os.Stdin = r
+			t.Cleanup(func() {
+				os.Stdin = stdin
+				_ = r.Close()
+			})
+
+			var out bytes.Buffer
+			c := &config{out: &out}
+
+			got, err := c.postgresPickBackup(tt.backups)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.want.Equal(got), "got %s, want %s", got, tt.want)
+		})
+	}
+}
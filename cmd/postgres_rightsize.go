@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/accounting"
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/go-openapi/strfmt"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// postgresRightsizeTargetUtilization is the utilization a recommended size aims for, leaving room for peaks
+	postgresRightsizeTargetUtilization = 0.7
+	postgresRightsizeCPUStepMilli      = 500
+	postgresRightsizeHoursPerMonth     = 730
+)
+
+// postgresSizing is the configured size of a single instance of a database.
+type postgresSizing struct {
+	cpu     resource.Quantity
+	memory  resource.Quantity
+	buffer  resource.Quantity
+	storage resource.Quantity
+}
+
+// postgresAverageUsage is the accounted usage of all instances of a database averaged over its lifetime.
+type postgresAverageUsage struct {
+	// cpu in cores
+	cpu float64
+	// memory in bytes
+	memory float64
+	// storage in bytes
+	storage float64
+}
+
+// postgresPrices are the prices per hour for a core and a gibibyte of memory and storage.
+type postgresPrices struct {
+	cpu     float64
+	memory  float64
+	storage float64
+}
+
+func (c *config) postgresRightsize() error {
+	from, err := eventuallyRelativeDateTime(viper.GetString("from"))
+	if err != nil {
+		return err
+	}
+
+	var (
+		project = viper.GetString("project")
+		pgs     []*models.V1PostgresResponse
+	)
+
+	if project != "" {
+		params := database.NewFindPostgresParams()
+		params.SetBody(&models.V1PostgresFindRequest{ProjectID: project})
+		resp, err := c.cloud.Database.FindPostgres(params, nil)
+		if err != nil {
+			return err
+		}
+		pgs = resp.Payload
+	} else {
+		resp, err := c.cloud.Database.ListPostgres(nil, nil)
+		if err != nil {
+			return err
+		}
+		pgs = resp.Payload
+	}
+
+	cur := &models.V1PostgresUsageRequest{
+		From: &from,
+		To:   strfmt.DateTime(time.Now()),
+	}
+	if project != "" {
+		cur.Projectid = project
+	}
+	request := accounting.NewPostgresUsageParams()
+	request.SetBody(cur)
+	usage, err := c.cloud.Accounting.PostgresUsage(request, nil)
+	if err != nil {
+		return err
+	}
+
+	var (
+		low    = viper.GetFloat64("low")
+		high   = viper.GetFloat64("high")
+		prices = postgresPrices{
+			cpu:     viper.GetFloat64("costs-cpu-hour"),
+			memory:  viper.GetFloat64("costs-memory-gi-hour"),
+			storage: viper.GetFloat64("costs-storage-gi-hour"),
+		}
+		result []*tableprinters.PostgresRightsizeRecommendation
+	)
+
+	if low <= 0 || high > 1 || low >= high {
+		return fmt.Errorf("the thresholds must satisfy 0 < --low < --high <= 1")
+	}
+
+	for _, pg := range pgs {
+		if pg.Size == nil {
+			continue
+		}
+
+		current, err := parsePostgresSizing(pg.Size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping postgres %s: %s\n", pointer.SafeDeref(pg.ID), err)
+			continue
+		}
+
+		avg, ok := postgresAverageUsageOf(pointer.SafeDeref(pg.ID), usage.Payload.Usage)
+		if !ok {
+			continue
+		}
+
+		instances := max(int(pg.NumberOfInstances), 1)
+
+		// memory is derived from the cpu by the memoryfactor and not always part of the size, but it is accounted
+		if current.memory.IsZero() && avg.memory > 0 {
+			current.memory = scaleQuantity(*resource.NewQuantity(int64(avg.memory), resource.BinarySI), 1, int64(instances), 1<<20)
+		}
+
+		r := postgresRightsizeRecommendation(current, instances, avg, low, high, prices)
+		r.ID = pointer.SafeDeref(pg.ID)
+		r.Description = pg.Description
+		r.ProjectID = pg.ProjectID
+		if r.Action != tableprinters.PostgresRightsizeKeep {
+			r.Command = postgresRightsizeCommand(r)
+		}
+
+		result = append(result, r)
+	}
+
+	err = c.listPrinter.Print(result)
+	if err != nil {
+		return err
+	}
+
+	if format := viper.GetString("output-format"); format == "table" || format == "wide" {
+		for _, r := range result {
+			if r.Command != "" {
+				fmt.Fprintln(c.out, r.Command)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parsePostgresSizing(size *models.V1PostgresSize) (postgresSizing, error) {
+	var (
+		s   postgresSizing
+		err error
+	)
+
+	s.cpu, err = resource.ParseQuantity(size.CPU)
+	if err != nil {
+		return s, fmt.Errorf("unable to parse cpu %q: %w", size.CPU, err)
+	}
+	s.buffer, err = resource.ParseQuantity(size.SharedBuffer)
+	if err != nil {
+		return s, fmt.Errorf("unable to parse buffer %q: %w", size.SharedBuffer, err)
+	}
+	s.storage, err = resource.ParseQuantity(size.StorageSize)
+	if err != nil {
+		return s, fmt.Errorf("unable to parse storage %q: %w", size.StorageSize, err)
+	}
+	if size.Memory != "" {
+		s.memory, err = resource.ParseQuantity(size.Memory)
+		if err != nil {
+			return s, fmt.Errorf("unable to parse memory %q: %w", size.Memory, err)
+		}
+	}
+
+	return s, nil
+}
+
+// postgresAverageUsageOf sums up the accounted usage of the database and averages it over its lifetime.
+func postgresAverageUsageOf(id string, usage []*models.V1PostgresUsage) (postgresAverageUsage, bool) {
+	var (
+		cpuSeconds, memorySeconds, storageSeconds float64
+		lifetime                                  time.Duration
+	)
+
+	for _, u := range usage {
+		if pointer.SafeDeref(u.Postgresid) != id {
+			continue
+		}
+		cpu, _ := strconv.ParseFloat(pointer.SafeDeref(u.Cpuseconds), 64)
+		memory, _ := strconv.ParseFloat(pointer.SafeDeref(u.Memoryseconds), 64)
+		storage, _ := strconv.ParseFloat(pointer.SafeDeref(u.Storageseconds), 64)
+
+		cpuSeconds += cpu
+		memorySeconds += memory
+		storageSeconds += storage
+		lifetime += time.Duration(pointer.SafeDeref(u.Lifetime))
+	}
+
+	if lifetime <= 0 {
+		return postgresAverageUsage{}, false
+	}
+
+	return postgresAverageUsage{
+		cpu:     cpuSeconds / lifetime.Seconds(),
+		memory:  memorySeconds / lifetime.Seconds(),
+		storage: storageSeconds / lifetime.Seconds(),
+	}, true
+}
+
+// postgresRightsizeRecommendation recommends the size of a single instance, such that the utilization gets close to the target.
+// cpu is downsized below the low and upsized above the high threshold, the buffer is scaled along with the cpu.
+// memory cannot be set, it follows the cpu by the memoryfactor of the database and is scaled for the costs.
+// storage is only upsized as volumes cannot shrink.
+func postgresRightsizeRecommendation(current postgresSizing, instances int, avg postgresAverageUsage, low, high float64, prices postgresPrices) *tableprinters.PostgresRightsizeRecommendation {
+	var (
+		recommended = current
+		cpuUtil     = avg.cpu / (current.cpu.AsApproximateFloat64() * float64(instances))
+		storageUtil = avg.storage / (current.storage.AsApproximateFloat64() * float64(instances))
+		currentCPU  = current.cpu.MilliValue()
+	)
+
+	if cpuUtil < low || cpuUtil > high {
+		needed := avg.cpu / float64(instances) / postgresRightsizeTargetUtilization
+		milli := max(int64(math.Ceil(needed*1000/postgresRightsizeCPUStepMilli))*postgresRightsizeCPUStepMilli, postgresRightsizeCPUStepMilli)
+
+		if (cpuUtil < low && milli < currentCPU) || (cpuUtil > high && milli > currentCPU) {
+			recommended.cpu = *resource.NewMilliQuantity(milli, resource.DecimalSI)
+			recommended.buffer = scaleQuantity(current.buffer, milli, currentCPU, 1<<20)
+			recommended.memory = scaleQuantity(current.memory, milli, currentCPU, 1<<20)
+		}
+	}
+
+	if storageUtil > high {
+		needed := avg.storage / float64(instances) / postgresRightsizeTargetUtilization
+		gi := int64(math.Ceil(needed / (1 << 30)))
+		if gi<<30 > current.storage.Value() {
+			recommended.storage = *resource.NewQuantity(gi<<30, resource.BinarySI)
+		}
+	}
+
+	action := tableprinters.PostgresRightsizeKeep
+	switch {
+	case recommended.cpu.Cmp(current.cpu) > 0 || recommended.storage.Cmp(current.storage) > 0:
+		action = tableprinters.PostgresRightsizeUpsize
+	case recommended.cpu.Cmp(current.cpu) < 0:
+		action = tableprinters.PostgresRightsizeDownsize
+	}
+
+	r := &tableprinters.PostgresRightsizeRecommendation{
+		CPU:                current.cpu.String(),
+		Buffer:             current.buffer.String(),
+		Memory:             current.memory.String(),
+		Storage:            current.storage.String(),
+		CPUUtilization:     cpuUtil,
+		StorageUtilization: storageUtil,
+		RecommendedCPU:     recommended.cpu.String(),
+		RecommendedBuffer:  recommended.buffer.String(),
+		RecommendedMemory:  recommended.memory.String(),
+		RecommendedStorage: recommended.storage.String(),
+		Action:             action,
+	}
+
+	if prices.cpu > 0 || prices.memory > 0 || prices.storage > 0 {
+		hourly := (recommended.cpu.AsApproximateFloat64()-current.cpu.AsApproximateFloat64())*prices.cpu +
+			(recommended.memory.AsApproximateFloat64()-current.memory.AsApproximateFloat64())/(1<<30)*prices.memory +
+			(recommended.storage.AsApproximateFloat64()-current.storage.AsApproximateFloat64())/(1<<30)*prices.storage
+		r.MonthlyCostDelta = new(hourly * float64(instances) * postgresRightsizeHoursPerMonth)
+	}
+
+	return r
+}
+
+// scaleQuantity scales q by numerator/denominator and rounds up to a multiple of unit.
+func scaleQuantity(q resource.Quantity, numerator, denominator, unit int64) resource.Quantity {
+	if q.IsZero() || denominator == 0 {
+		return q
+	}
+	scaled := float64(q.Value()) * float64(numerator) / float64(denominator)
+	units := max(int64(math.Ceil(scaled/float64(unit))), 1)
+	return *resource.NewQuantity(units*unit, resource.BinarySI)
+}
+
+func postgresRightsizeCommand(r *tableprinters.PostgresRightsizeRecommendation) string {
+	args := []string{"cloudctl", "postgres", "update", r.ID}
+	if r.RecommendedCPU != r.CPU {
+		args = append(args, "--cpu", r.RecommendedCPU)
+	}
+	if r.RecommendedBuffer != r.Buffer {
+		args = append(args, "--buffer", r.RecommendedBuffer)
+	}
+	if r.RecommendedStorage != r.Storage {
+		args = append(args, "--storage", r.RecommendedStorage)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+func (c *config) postgresTopology() error {
+	var pgs []*models.V1PostgresResponse
+
+	if project := viper.GetString("project"); project != "" {
+		params := database.NewFindPostgresParams()
+		params.SetBody(&models.V1PostgresFindRequest{ProjectID: project})
+		resp, err := c.cloud.Database.FindPostgres(params, nil)
+		if err != nil {
+			return err
+		}
+		pgs = resp.Payload
+	} else {
+		resp, err := c.cloud.Database.ListPostgres(nil, nil)
+		if err != nil {
+			return err
+		}
+		pgs = resp.Payload
+	}
+
+	return c.listPrinter.Print(postgresTopology(pgs))
+}
+
+// postgresTopology builds the replication graph of the given databases. standbys are attached to their primary,
+// standbys whose primary is not contained in the given databases are returned as roots with their upstream set.
+func postgresTopology(pgs []*models.V1PostgresResponse) []*tableprinters.PostgresTopologyNode {
+	var (
+		nodes = map[string]*tableprinters.PostgresTopologyNode{}
+		roots []*tableprinters.PostgresTopologyNode
+	)
+
+	for _, pg := range pgs {
+		n := &tableprinters.PostgresTopologyNode{
+			ID:          pointer.SafeDeref(pg.ID),
+			Description: pg.Description,
+			ProjectID:   pg.ProjectID,
+			Partition:   pg.PartitionID,
+			Role:        tableprinters.PostgresRoleStandalone,
+		}
+		if pg.Status != nil {
+			n.Status = pg.Status.Description
+		}
+		if ip, port, ok := postgresSocket(pg); ok {
+			n.Address = fmt.Sprintf("%s:%d", ip, port)
+		}
+		if pg.Connection != nil {
+			if pg.Connection.LocalSideIsPrimary {
+				n.Role = tableprinters.PostgresRolePrimary
+				n.Synchronous = pg.Connection.Synchronous
+			} else {
+				n.Role = tableprinters.PostgresRoleStandby
+				n.Upstream = pg.Connection.PostgresID
+			}
+		}
+		nodes[n.ID] = n
+	}
+
+	for _, pg := range pgs {
+		n := nodes[pointer.SafeDeref(pg.ID)]
+		if n.Role == tableprinters.PostgresRoleStandby {
+			if upstream, ok := nodes[n.Upstream]; ok && upstream.Role == tableprinters.PostgresRolePrimary {
+				upstream.Standbys = append(upstream.Standbys, n)
+				continue
+			}
+		}
+		roots = append(roots, n)
+	}
+
+	sortNodes := func(nodes []*tableprinters.PostgresTopologyNode) {
+		slices.SortFunc(nodes, func(a, b *tableprinters.PostgresTopologyNode) int {
+			return cmp.Or(
+				cmp.Compare(a.ProjectID, b.ProjectID),
+				cmp.Compare(a.Description, b.Description),
+				cmp.Compare(a.ID, b.ID),
+			)
+		})
+	}
+
+	sortNodes(roots)
+	for _, n := range nodes {
+		sortNodes(n.Standbys)
+	}
+
+	return roots
+}
+
+func (c *config) postgresFailover(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("postgres failover requires the primary and the standby postgresID as arguments")
+	}
+	if args[0] == args[1] {
+		return fmt.Errorf("primary and standby must be different databases")
+	}
+
+	primary, err := c.getPostgresFromArgs(args[:1])
+	if err != nil {
+		return err
+	}
+	standby, err := c.getPostgresFromArgs(args[1:])
+	if err != nil {
+		return err
+	}
+
+	var (
+		primaryID = *primary.ID
+		standbyID = *standby.ID
+		timeout   = viper.GetDuration("timeout")
+	)
+
+	if primary.Connection == nil || !primary.Connection.LocalSideIsPrimary {
+		return fmt.Errorf("postgres %s is not a replication primary", primaryID)
+	}
+	if standby.Connection == nil || standby.Connection.LocalSideIsPrimary {
+		return fmt.Errorf("postgres %s is not a standby", standbyID)
+	}
+	if standby.Connection.PostgresID != primaryID {
+		return fmt.Errorf("postgres %s does not replicate from %s but from %s", standbyID, primaryID, standby.Connection.PostgresID)
+	}
+
+	// the new primary takes over the replication mode of the current primary unless given otherwise
+	synchronous := primary.Connection.Synchronous
+	if viper.IsSet("synchronous") {
+		synchronous = viper.GetBool("synchronous")
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		genericcli.Must(c.listPrinter.Print(postgresTopology([]*models.V1PostgresResponse{primary, standby})))
+		fmt.Printf("\npostgres %s will be demoted to standby, afterwards postgres %s will be promoted to primary.\n", primaryID, standbyID)
+		fmt.Println("connections to the current primary are terminated, writes are not possible until the promotion is finished.")
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	// the primary must be demoted first, otherwise both sides would accept writes
+	fmt.Fprintf(os.Stderr, "demoting postgres %s to standby\n", primaryID)
+	_, err = c.postgresSetReplicationRole(primaryID, false, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to demote postgres %s, nothing was changed: %w", primaryID, err)
+	}
+	_, err = c.postgresWaitForRole(primaryID, false, timeout)
+	if err != nil {
+		return c.postgresFailoverRollback(primaryID, "", primary.Connection.Synchronous, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "promoting postgres %s to primary\n", standbyID)
+	_, err = c.postgresSetReplicationRole(standbyID, true, &synchronous, nil)
+	if err != nil {
+		return c.postgresFailoverRollback(primaryID, "", primary.Connection.Synchronous, err)
+	}
+	_, err = c.postgresWaitForRole(standbyID, true, timeout)
+	if err != nil {
+		return c.postgresFailoverRollback(primaryID, standbyID, primary.Connection.Synchronous, err)
+	}
+
+	var result []*models.V1PostgresResponse
+	for _, id := range []string{standbyID, primaryID} {
+		pg, err := c.getPostgresFromArgs([]string{id})
+		if err != nil {
+			return err
+		}
+		result = append(result, pg)
+	}
+
+	return c.listPrinter.Print(postgresTopology(result))
+}
+
+// postgresFailoverRollback restores the original replication roles after a failed failover.
+// the standby is only demoted again if it was already promoted, which is the case if standbyID is given.
+func (c *config) postgresFailoverRollback(primaryID, standbyID string, synchronous bool, cause error) error {
+	fmt.Fprintf(os.Stderr, "failover failed, rolling back: %s\n", cause)
+
+	timeout := viper.GetDuration("timeout")
+
+	if standbyID != "" {
+		fmt.Fprintf(os.Stderr, "demoting postgres %s to standby\n", standbyID)
+		_, err := c.postgresSetReplicationRole(standbyID, false, nil, nil)
+		if err == nil {
+			_, err = c.postgresWaitForRole(standbyID, false, timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("failover failed: %w, rollback failed as well, postgres %s could not be demoted to standby, manual intervention is required: %w", cause, standbyID, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "promoting postgres %s to primary\n", primaryID)
+	_, err := c.postgresSetReplicationRole(primaryID, true, &synchronous, nil)
+	if err == nil {
+		_, err = c.postgresWaitForRole(primaryID, true, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failover failed: %w, rollback failed as well, postgres %s could not be promoted to primary, manual intervention is required: %w", cause, primaryID, err)
+	}
+
+	return fmt.Errorf("failover failed, the original replication roles were restored: %w", cause)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/models"
+	testclient "github.com/fi-ts/cloud-go/test/client"
+	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_postgresFailover(t *testing.T) {
+	interval := postgresWaitPollInterval
+	postgresWaitPollInterval = time.Millisecond
+	t.Cleanup(func() { postgresWaitPollInterval = interval })
+
+	// responses are created for every call as setting the role modifies the returned database
+	pg := func(id string, primary bool, status string) *models.V1PostgresResponse {
+		pg := mustPostgresResponse(t, `{"connection": {}, "status": {}}`)
+		pg.ID = new(id)
+		pg.Connection.LocalSideIsPrimary = primary
+		pg.Status.Description = status
+		// the connection points to the other side of the replication
+		pg.Connection.PostgresID = "primary"
+		if id == "primary" {
+			pg.Connection.PostgresID = "standby"
+		}
+		return pg
+	}
+
+	type step func(t *testing.T, m *mock.Mock)
+
+	get := func(id string, primary bool, status string) step {
+		return func(t *testing.T, m *mock.Mock) {
+			m.On("GetPostgres", testcommon.MatchIgnoreContext(t, database.NewGetPostgresParams().WithID(id)), nil).
+				Return(&database.GetPostgresOK{Payload: pg(id, primary, status)}, nil).Once()
+		}
+	}
+	update := func(id string, primary bool, err error) step {
+		return func(t *testing.T, m *mock.Mock) {
+			current := pg(id, !primary, postgresStatusRunning)
+			connection := *current.Connection
+			connection.LocalSideIsPrimary = primary
+
+			body := &models.V1PostgresUpdateRequest{
+				ID:             current.ID,
+				ProjectID:      current.ProjectID,
+				Connection:     &connection,
+				AuditLogs:      current.AuditLogs,
+				PostgresParams: current.PostgresParams,
+			}
+
+			call := m.On("UpdatePostgres", testcommon.MatchIgnoreContext(t, database.NewUpdatePostgresParams().WithBody(body)), nil)
+			if err != nil {
+				call.Return(nil, err).Once()
+				return
+			}
+			call.Return(&database.UpdatePostgresOK{Payload: pg(id, primary, postgresStatusRunning)}, nil).Once()
+		}
+	}
+	// setRole changes the role and waits for the operator to reconcile it
+	setRole := func(id string, primary bool) []step {
+		return []step{
+			get(id, !primary, postgresStatusRunning),
+			update(id, primary, nil),
+			get(id, primary, "Updating"),
+			get(id, primary, postgresStatusRunning),
+		}
+	}
+	steps := func(parts ...[]step) []step {
+		var all []step
+		for _, p := range parts {
+			all = append(all, p...)
+		}
+		return all
+	}
+
+	var (
+		preconditions = []step{
+			get("primary", true, postgresStatusRunning),
+			get("standby", false, postgresStatusRunning),
+		}
+		result = []step{
+			get("standby", true, postgresStatusRunning),
+			get("primary", false, postgresStatusRunning),
+		}
+		errConflict = errors.New("conflict")
+	)
+
+	tests := []struct {
+		name    string
+		steps   []step
+		wantErr string
+	}{
+		{
+			name:  "failover",
+			steps: steps(preconditions, setRole("primary", false), setRole("standby", true), result),
+		},
+		{
+			name: "demotion fails",
+			steps: steps(preconditions,
+				[]step{
+					get("primary", true, postgresStatusRunning),
+					update("primary", false, nil),
+					get("primary", false, postgresStatusInvalid),
+				},
+				setRole("primary", true),
+			),
+			wantErr: "failover failed, the original replication roles were restored: postgres primary is in status Invalid",
+		},
+		{
+			name: "promotion request fails",
+			steps: steps(preconditions,
+				setRole("primary", false),
+				[]step{
+					get("standby", false, postgresStatusRunning),
+					update("standby", true, errConflict),
+				},
+				setRole("primary", true),
+			),
+			wantErr: "failover failed, the original replication roles were restored: conflict",
+		},
+		{
+			name: "promotion fails",
+			steps: steps(preconditions,
+				setRole("primary", false),
+				[]step{
+					get("standby", false, postgresStatusRunning),
+					update("standby", true, nil),
+					get("standby", true, postgresStatusInvalid),
+				},
+				setRole("standby", false),
+				setRole("primary", true),
+			),
+			wantErr: "failover failed, the original replication roles were restored: postgres standby is in status Invalid",
+		},
+		{
+			name: "rollback fails",
+			steps: steps(preconditions,
+				setRole("primary", false),
+				[]step{
+					get("standby", false, postgresStatusRunning),
+					update("standby", true, nil),
+					get("standby", true, postgresStatusInvalid),
+					get("standby", true, postgresStatusRunning),
+					update("standby", false, errConflict),
+				},
+			),
+			wantErr: "failover failed: postgres standby is in status Invalid, rollback failed as well, postgres standby could not be demoted to standby, manual intervention is required: conflict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("yes-i-really-mean-it", true)
+			viper.Set("timeout", time.Minute)
+
+			var out bytes.Buffer
+			c := &config{
+				cloud: testclient.NewCloudMockClient(t, &testclient.CloudMockFns{
+					Database: func(m *mock.Mock) {
+						for _, s := range tt.steps {
+							s(t, m)
+						}
+					},
+				}),
+				out:         &out,
+				listPrinter: printers.NewJSONPrinter().WithOut(&out),
+			}
+
+			err := c.postgresFailover([]string{"primary", "standby"})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, out.String())
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/database"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	postgresWaitForRunning     = "running"
+	postgresWaitForDeleted     = "deleted"
+	postgresWaitForSocketReady = "socket-ready"
+	postgresWaitTimeoutDefault = 20 * time.Minute
+
+	// postgresRoleChangeSettleTime is the time after which a role change is considered done
+	// even if no status transition was observed, as the operator might reconcile between two polls.
+	postgresRoleChangeSettleTime = 2 * time.Minute
+
+	// status descriptions as reported by the postgres operator
+	postgresStatusRunning = "Running"
+	postgresStatusInvalid = "Invalid"
+)
+
+// postgresWaitPollInterval is the interval in which the database is polled while waiting, it is shortened in tests.
+var postgresWaitPollInterval = 5 * time.Second
+
+var postgresWaitConditions = []string{
+	postgresWaitForRunning + "\tthe database is running",
+	postgresWaitForDeleted + "\tthe database does not exist anymore",
+	postgresWaitForSocketReady + "\tthe database is running and its address is assigned, such that the connectionstring is usable",
+}
+
+// addPostgresWaitFlags adds the flags to wait for the database after the request of the command was accepted.
+func addPostgresWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", false, "waits until the database is running and its address is assigned")
+	cmd.Flags().Duration("timeout", postgresWaitTimeoutDefault, "maximum duration to wait for the database when --wait is given")
+}
+
+// addPostgresRoleWaitFlags adds the flags to wait for a database after its replication role was changed.
+func addPostgresRoleWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", false, "waits until the database took the new replication role and the operator reconciled the change")
+	cmd.Flags().Duration("timeout", postgresWaitTimeoutDefault, "maximum duration to wait for the database when --wait is given")
+}
+
+func (c *config) postgresWaitCmd(args []string) error {
+	id, err := c.postgresID("wait", args)
+	if err != nil {
+		return err
+	}
+
+	condition := viper.GetString("for")
+	switch condition {
+	case postgresWaitForRunning, postgresWaitForDeleted, postgresWaitForSocketReady:
+	default:
+		return fmt.Errorf("unsupported wait condition %q, must be one of %s|%s|%s", condition, postgresWaitForRunning, postgresWaitForDeleted, postgresWaitForSocketReady)
+	}
+
+	return c.postgresWaitAndPrint(id, condition)
+}
+
+// postgresWaitAndPrint waits for the condition and prints the database afterwards.
+func (c *config) postgresWaitAndPrint(id, condition string) error {
+	pg, err := c.postgresWait(id, condition, viper.GetDuration("timeout"))
+	if err != nil {
+		return err
+	}
+	if pg == nil {
+		fmt.Fprintf(c.out, "postgres %s was deleted\n", id)
+		return nil
+	}
+	return c.listPrinter.Print(pg)
+}
+
+// postgresWait polls the database until the given condition is met, the database reports a failure or the timeout is reached.
+func (c *config) postgresWait(id, condition string, timeout time.Duration) (*models.V1PostgresResponse, error) {
+	return c.postgresPoll(id, condition, timeout, func(pg *models.V1PostgresResponse) bool {
+		return postgresWaitConditionMet(pg, condition)
+	})
+}
+
+// postgresWaitForRole polls the database after its replication role was changed until it took the given role.
+func (c *config) postgresWaitForRole(id string, primary bool, timeout time.Duration) (*models.V1PostgresResponse, error) {
+	state := &postgresRoleWaitState{primary: primary, since: time.Now()}
+	return c.postgresPoll(id, postgresRoleName(primary), timeout, func(pg *models.V1PostgresResponse) bool {
+		return state.evaluate(pg, time.Now())
+	})
+}
+
+// postgresPoll polls the database until done returns true, the database reports a failure or the timeout is reached.
+func (c *config) postgresPoll(id, condition string, timeout time.Duration, done func(pg *models.V1PostgresResponse) bool) (*models.V1PostgresResponse, error) {
+	var (
+		deadline     = time.Now().Add(timeout)
+		lastProgress string
+	)
+
+	for {
+		resp, err := c.cloud.Database.GetPostgres(database.NewGetPostgresParams().WithID(id), nil)
+		if err != nil {
+			var r *database.GetPostgresDefault
+			if condition == postgresWaitForDeleted && errors.As(err, &r) && r.Code() == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		pg := resp.Payload
+
+		status, address := postgresWaitProgress(pg)
+		progress := fmt.Sprintf("status: %s, address: %s", status, address)
+		if progress != lastProgress {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", time.Now().Format(time.TimeOnly), id, progress)
+			lastProgress = progress
+		}
+
+		// other failure states like SyncFailed are retried by the operator
+		if condition != postgresWaitForDeleted && status == postgresStatusInvalid {
+			return pg, fmt.Errorf("postgres %s is in status %s", id, status)
+		}
+
+		if done(pg) {
+			return pg, nil
+		}
+
+		if time.Now().After(deadline) {
+			return pg, fmt.Errorf("timeout after %s while waiting for postgres %s to be %s", timeout, id, condition)
+		}
+
+		time.Sleep(postgresWaitPollInterval)
+	}
+}
+
+func postgresWaitConditionMet(pg *models.V1PostgresResponse, condition string) bool {
+	if pg.Status == nil || pg.Status.Description != postgresStatusRunning {
+		return false
+	}
+
+	switch condition {
+	case postgresWaitForRunning:
+		return true
+	case postgresWaitForSocketReady:
+		if pg.Status.Socket == nil || pg.Status.Socket.IP == "" || pg.Status.Socket.Port == 0 {
+			return false
+		}
+		// the connectionstring uses the dedicated load balancer if configured
+		if pg.Dedicatedloadbalancerip != nil && *pg.Dedicatedloadbalancerip != "" {
+			for _, s := range pg.Status.Additionalsockets {
+				if s.IP == *pg.Dedicatedloadbalancerip {
+					return true
+				}
+			}
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// postgresRoleWaitState keeps track of the status of a database while waiting for a role change.
+type postgresRoleWaitState struct {
+	primary bool
+	since   time.Time
+	// seenTransition is set once the operator reported that it is reconciling the role change
+	seenTransition bool
+}
+
+// evaluate returns true once the database has the role and the operator reports it as running again.
+func (s *postgresRoleWaitState) evaluate(pg *models.V1PostgresResponse, now time.Time) bool {
+	if pg.Status == nil {
+		return false
+	}
+	if pg.Status.Description != postgresStatusRunning {
+		s.seenTransition = true
+		return false
+	}
+	if pg.Connection == nil || pg.Connection.LocalSideIsPrimary != s.primary {
+		return false
+	}
+	return s.seenTransition || now.Sub(s.since) >= postgresRoleChangeSettleTime
+}
+
+func postgresRoleName(primary bool) string {
+	if primary {
+		return "primary"
+	}
+	return "standby"
+}
+
+func postgresWaitProgress(pg *models.V1PostgresResponse) (string, string) {
+	status, address := "unknown", "pending"
+	if pg.Status == nil {
+		return status, address
+	}
+	if pg.Status.Description != "" {
+		status = pg.Status.Description
+	}
+	if pg.Status.Socket != nil && pg.Status.Socket.IP != "" {
+		address = fmt.Sprintf("%s:%d", pg.Status.Socket.IP, pg.Status.Socket.Port)
+	}
+	return status, address
+}
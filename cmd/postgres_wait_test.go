@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func mustPostgresResponse(t *testing.T, raw string) *models.V1PostgresResponse {
+	pg := &models.V1PostgresResponse{}
+	require.NoError(t, json.Unmarshal([]byte(raw), pg))
+	return pg
+}
+
+func Test_postgresWaitConditionMet(t *testing.T) {
+	tests := []struct {
+		name      string
+		pg        string
+		condition string
+		want      bool
+	}{
+		{
+			name:      "no status",
+			pg:        `{}`,
+			condition: postgresWaitForRunning,
+		},
+		{
+			name:      "creating",
+			pg:        `{"status": {"description": "Creating"}}`,
+			condition: postgresWaitForRunning,
+		},
+		{
+			name:      "running",
+			pg:        `{"status": {"description": "Running"}}`,
+			condition: postgresWaitForRunning,
+			want:      true,
+		},
+		{
+			name:      "running without socket",
+			pg:        `{"status": {"description": "Running"}}`,
+			condition: postgresWaitForSocketReady,
+		},
+		{
+			name:      "socket ready",
+			pg:        `{"status": {"description": "Running", "socket": {"ip": "1.2.3.4", "port": 32004}}}`,
+			condition: postgresWaitForSocketReady,
+			want:      true,
+		},
+		{
+			name:      "dedicated load balancer not assigned yet",
+			pg:        `{"dedicatedloadbalancerip": "5.6.7.8", "status": {"description": "Running", "socket": {"ip": "1.2.3.4", "port": 32004}, "additionalsockets": [{"ip": "9.9.9.9", "port": 5432}]}}`,
+			condition: postgresWaitForSocketReady,
+		},
+		{
+			name:      "dedicated load balancer assigned",
+			pg:        `{"dedicatedloadbalancerip": "5.6.7.8", "status": {"description": "Running", "socket": {"ip": "1.2.3.4", "port": 32004}, "additionalsockets": [{"ip": "5.6.7.8", "port": 5432}]}}`,
+			condition: postgresWaitForSocketReady,
+			want:      true,
+		},
+		{
+			name:      "deleted is never met by a response",
+			pg:        `{"status": {"description": "Running"}}`,
+			condition: postgresWaitForDeleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, postgresWaitConditionMet(mustPostgresResponse(t, tt.pg), tt.condition))
+		})
+	}
+}
+
+func Test_postgresRoleWaitState_evaluate(t *testing.T) {
+	var (
+		since   = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		early   = since.Add(10 * time.Second)
+		settled = since.Add(postgresRoleChangeSettleTime)
+
+		runningPrimary = `{"connection": {"localSideIsPrimary": true}, "status": {"description": "Running"}}`
+		runningStandby = `{"connection": {"localSideIsPrimary": false}, "status": {"description": "Running"}}`
+		updating       = `{"connection": {"localSideIsPrimary": true}, "status": {"description": "Updating"}}`
+		standalone     = `{"status": {"description": "Running"}}`
+	)
+
+	type poll struct {
+		pg  string
+		now time.Time
+	}
+
+	tests := []struct {
+		name      string
+		primary   bool
+		polls     []poll
+		wantPolls int
+	}{
+		{
+			name:    "running before the operator picked up the change",
+			primary: true,
+			polls: []poll{
+				{pg: runningPrimary, now: early},
+				{pg: updating, now: early},
+				{pg: runningPrimary, now: early},
+			},
+			wantPolls: 3,
+		},
+		{
+			name:    "role does not match after the transition",
+			primary: false,
+			polls: []poll{
+				{pg: updating, now: early},
+				{pg: runningPrimary, now: early},
+				{pg: runningStandby, now: early},
+			},
+			wantPolls: 3,
+		},
+		{
+			name:    "transition between two polls is accepted after the settle time",
+			primary: true,
+			polls: []poll{
+				{pg: runningPrimary, now: early},
+				{pg: runningPrimary, now: settled},
+			},
+			wantPolls: 2,
+		},
+		{
+			name:    "wrong role is never accepted",
+			primary: true,
+			polls: []poll{
+				{pg: updating, now: early},
+				{pg: runningStandby, now: settled},
+			},
+			wantPolls: -1,
+		},
+		{
+			name:    "standalone database",
+			primary: false,
+			polls: []poll{
+				{pg: updating, now: early},
+				{pg: standalone, now: settled},
+			},
+			wantPolls: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &postgresRoleWaitState{primary: tt.primary, since: since}
+
+			gotPolls := -1
+			for i, p := range tt.polls {
+				if s.evaluate(mustPostgresResponse(t, p.pg), p.now) {
+					gotPolls = i + 1
+					break
+				}
+			}
+
+			require.Equal(t, tt.wantPolls, gotPolls)
+		})
+	}
+}
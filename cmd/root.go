@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -52,10 +53,19 @@ func Execute() {
 		if viper.GetBool("debug") {
 			panic(err)
 		}
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// exitCode returns the exit status of a failed remote command, such that it is passed on to the caller.
+func exitCode(err error) int {
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() > 0 {
+		return exitErr.ExitStatus()
+	}
+	return 1
+}
+
 func newRootCmd(cfg *config) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:          binaryName,
@@ -97,6 +107,7 @@ func newRootCmd(cfg *config) *cobra.Command {
 	rootCmd.AddCommand(newUpdateCmd(cfg, binaryName))
 	rootCmd.AddCommand(newLoginCmd(cfg))
 	rootCmd.AddCommand(newLogoutCmd(cfg))
+	rootCmd.AddCommand(newExecCredentialCmd(cfg))
 	rootCmd.AddCommand(newWhoamiCmd())
 	rootCmd.AddCommand(newProjectCmd(cfg))
 	rootCmd.AddCommand(newTenantCmd(cfg))
@@ -57,6 +57,22 @@ func newS3Cmd(c *config) *cobra.Command {
 			return c.s3ListPartitions()
 		},
 	}
+	s3UsageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "show the usage of the s3 users in comparison with their quota",
+		Long: `joins the s3 users with the accounted usage of their buckets and shows the number of buckets in comparison with the maximum number of buckets of each user.
+users whose number of buckets reaches the threshold of their maximum are flagged.
+
+You may want to convert the usage to a price in Euro by using the prices from your contract. You can use the following environment variables:
+
+export CLOUDCTL_COSTS_STORAGE_GI_HOUR=0.01        # costs per storage hour
+
+⚠ Please be aware that any costs calculated in this fashion can still be different from the final bill as it does not include contract specific details like minimum purchase, discounts, etc.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.s3UserUsage()
+		},
+	}
 	s3AddKeyCmd := &cobra.Command{
 		Use:   "add-key",
 		Short: "adds a key for an s3 user",
@@ -91,6 +107,13 @@ func newS3Cmd(c *config) *cobra.Command {
 	genericcli.Must(s3ListCmd.RegisterFlagCompletionFunc("partition", c.comp.S3ListPartitionsCompletion))
 	genericcli.Must(s3ListCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
 
+	s3UsageCmd.Flags().StringP("partition", "p", "", "name of s3 partition.")
+	s3UsageCmd.Flags().String("project", "", "id of the project that the s3 users belong to")
+	s3UsageCmd.Flags().String("from", "30d", "start of the accounting window, e.g. 30d, 2006-01-02T15:04:05Z")
+	s3UsageCmd.Flags().Float64("threshold", 0.8, "the share of the maximum number of buckets from which on a user is flagged as near its quota")
+	genericcli.Must(s3UsageCmd.RegisterFlagCompletionFunc("partition", c.comp.S3ListPartitionsCompletion))
+	genericcli.Must(s3UsageCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+
 	s3DescribeCmd.Flags().StringP("id", "i", "", "id of the s3 user [required]")
 	s3DescribeCmd.Flags().StringP("partition", "p", "", "name of s3 partition where this user is in [required]")
 	s3DescribeCmd.Flags().String("project", "", "id of the project that the s3 user belongs to [required]")
@@ -143,6 +166,9 @@ func newS3Cmd(c *config) *cobra.Command {
 	s3Cmd.AddCommand(s3PartitionListCmd)
 	s3Cmd.AddCommand(s3AddKeyCmd)
 	s3Cmd.AddCommand(s3RemoveKeyCmd)
+	s3Cmd.AddCommand(s3UsageCmd)
+	s3Cmd.AddCommand(newS3BucketCmd(c))
+	s3Cmd.AddCommand(newS3ObjectCmd(c))
 	return s3Cmd
 }
 
@@ -0,0 +1,694 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fi-ts/cloud-go/api/client/s3"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type s3DataCmd struct {
+	c *config
+}
+
+// addS3UserFlags adds the flags identifying the s3 user, whose endpoint and credentials are used to access buckets and objects.
+func addS3UserFlags(c *config, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("id", "i", "", "id of the s3 user [required]")
+	cmd.PersistentFlags().StringP("partition", "p", "", "name of s3 partition where this user is in [required]")
+	cmd.PersistentFlags().String("project", "", "id of the project that the s3 user belongs to [required]")
+	cmd.PersistentFlags().StringP("tenant", "t", "", "tenant of the s3 user, defaults to logged in tenant")
+	cmd.PersistentFlags().String("access-key", "", "the access key of the s3 user to use, defaults to the first key [optional]")
+	genericcli.Must(cmd.MarkPersistentFlagRequired("id"))
+	genericcli.Must(cmd.MarkPersistentFlagRequired("partition"))
+	genericcli.Must(cmd.MarkPersistentFlagRequired("project"))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("partition", c.comp.S3ListPartitionsCompletion))
+	genericcli.Must(cmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+}
+
+func newS3BucketCmd(c *config) *cobra.Command {
+	d := s3DataCmd{c: c}
+
+	bucketCmd := &cobra.Command{
+		Use:   "bucket",
+		Short: "manage the buckets of an s3 user",
+	}
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Short:   "list buckets",
+		Aliases: []string{"ls"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketList()
+		},
+	}
+	createCmd := &cobra.Command{
+		Use:   "create <bucket>",
+		Short: "create a bucket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketCreate(args)
+		},
+	}
+	deleteCmd := &cobra.Command{
+		Use:     "delete <bucket>",
+		Aliases: []string{"destroy", "rm", "remove"},
+		Short:   "delete a bucket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketDelete(args)
+		},
+	}
+
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "manage the policy of a bucket",
+	}
+	policyGetCmd := &cobra.Command{
+		Use:   "get <bucket>",
+		Short: "print the policy of a bucket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketPolicyGet(args)
+		},
+	}
+	policySetCmd := &cobra.Command{
+		Use:   "set <bucket>",
+		Short: "replace the policy of a bucket",
+		Long:  "replaces the policy of a bucket with a policy document in json format. the policy is validated before it is sent, all resources must refer to the bucket.",
+		Example: `cat > policy.json <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Effect": "Allow",
+    "Principal": {"AWS": ["arn:aws:iam:::user/reader"]},
+    "Action": ["s3:GetObject", "s3:ListBucket"],
+    "Resource": ["arn:aws:s3:::my-bucket", "arn:aws:s3:::my-bucket/*"]
+  }]
+}
+EOF
+cloudctl s3 bucket policy set my-bucket -f policy.json -i my-user -p dc1 --project <project>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketPolicySet(args)
+		},
+	}
+	policyDeleteCmd := &cobra.Command{
+		Use:     "delete <bucket>",
+		Aliases: []string{"destroy", "rm", "remove"},
+		Short:   "delete the policy of a bucket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketPolicyDelete(args)
+		},
+	}
+	lifecycleCmd := &cobra.Command{
+		Use:   "lifecycle",
+		Short: "manage the lifecycle rules of a bucket",
+	}
+	lifecycleGetCmd := &cobra.Command{
+		Use:   "get <bucket>",
+		Short: "print the lifecycle rules of a bucket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketLifecycleGet(args)
+		},
+	}
+	lifecycleSetCmd := &cobra.Command{
+		Use:   "set <bucket>",
+		Short: "replace the lifecycle rules of a bucket",
+		Long:  "replaces the lifecycle rules of a bucket with the rules in yaml format, the output of lifecycle get can be used as template.",
+		Example: `cat > rules.yaml <<EOF
+rules:
+- id: expire-logs
+  filter:
+    prefix: logs/
+  expiration:
+    days: 30
+- id: cleanup
+  noncurrent_version_expiration:
+    noncurrent_days: 7
+  abort_incomplete_multipart_upload:
+    days_after_initiation: 1
+EOF
+cloudctl s3 bucket lifecycle set my-bucket -f rules.yaml -i my-user -p dc1 --project <project>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketLifecycleSet(args)
+		},
+	}
+	versioningCmd := &cobra.Command{
+		Use:   "versioning",
+		Short: "manage the versioning of a bucket",
+	}
+	versioningEnableCmd := &cobra.Command{
+		Use:   "enable <bucket>",
+		Short: "enable versioning of the objects in a bucket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketVersioning(args, helper.S3VersioningEnabled)
+		},
+	}
+	versioningSuspendCmd := &cobra.Command{
+		Use:   "suspend <bucket>",
+		Short: "suspend versioning of the objects in a bucket, existing versions are kept",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.bucketVersioning(args, helper.S3VersioningSuspended)
+		},
+	}
+
+	addS3UserFlags(c, bucketCmd)
+	bucketCmd.PersistentFlags().String("region", "", "region of the buckets [optional]")
+	deleteCmd.Flags().Bool("force", false, "deletes all objects of the bucket including their previous versions before deleting the bucket (dangerous!)")
+	policySetCmd.Flags().StringP("file", "f", "", "filename of the policy in json format, or - for stdin")
+	genericcli.Must(policySetCmd.MarkFlagRequired("file"))
+	lifecycleSetCmd.Flags().StringP("file", "f", "", "filename of the lifecycle rules in yaml format, or - for stdin")
+	genericcli.Must(lifecycleSetCmd.MarkFlagRequired("file"))
+
+	policyCmd.AddCommand(policyGetCmd)
+	policyCmd.AddCommand(policySetCmd)
+	policyCmd.AddCommand(policyDeleteCmd)
+	lifecycleCmd.AddCommand(lifecycleGetCmd)
+	lifecycleCmd.AddCommand(lifecycleSetCmd)
+	versioningCmd.AddCommand(versioningEnableCmd)
+	versioningCmd.AddCommand(versioningSuspendCmd)
+
+	bucketCmd.AddCommand(listCmd)
+	bucketCmd.AddCommand(createCmd)
+	bucketCmd.AddCommand(deleteCmd)
+	bucketCmd.AddCommand(policyCmd)
+	bucketCmd.AddCommand(lifecycleCmd)
+	bucketCmd.AddCommand(versioningCmd)
+
+	return bucketCmd
+}
+
+func newS3ObjectCmd(c *config) *cobra.Command {
+	d := s3DataCmd{c: c}
+
+	objectCmd := &cobra.Command{
+		Use:   "object",
+		Short: "manage the objects in the buckets of an s3 user",
+	}
+	listCmd := &cobra.Command{
+		Use:     "list <bucket>",
+		Short:   "list objects of a bucket",
+		Aliases: []string{"ls"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.objectList(args)
+		},
+	}
+	getCmd := &cobra.Command{
+		Use:   "get <bucket> <key>",
+		Short: "download an object",
+		Example: `cloudctl s3 object get my-bucket reports/2024.csv -i my-user -p dc1 --project <project>
+cloudctl s3 object get my-bucket reports/2024.csv -f - | head`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.objectGet(args)
+		},
+	}
+	putCmd := &cobra.Command{
+		Use:   "put <bucket> <file>",
+		Short: "upload a file as object, use - to read from stdin",
+		Example: `cloudctl s3 object put my-bucket report.csv --key reports/2024.csv -i my-user -p dc1 --project <project>
+pg_dump mydb | cloudctl s3 object put my-bucket - --key dumps/mydb.sql`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.objectPut(args)
+		},
+	}
+	deleteCmd := &cobra.Command{
+		Use:     "delete <bucket> <key>...",
+		Aliases: []string{"destroy", "rm", "remove"},
+		Short:   "delete objects",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.objectDelete(args)
+		},
+	}
+
+	addS3UserFlags(c, objectCmd)
+	objectCmd.PersistentFlags().String("region", "", "region of the bucket [optional]")
+	listCmd.Flags().String("prefix", "", "only list objects whose key starts with the prefix [optional]")
+	getCmd.Flags().StringP("file", "f", "", "the file to write the object to, use - for stdout, defaults to the base name of the key")
+	putCmd.Flags().String("key", "", "the key of the object, defaults to the base name of the file")
+	putCmd.Flags().String("content-type", "", "the content type of the object, guessed from the file extension if not given [optional]")
+
+	objectCmd.AddCommand(listCmd)
+	objectCmd.AddCommand(getCmd)
+	objectCmd.AddCommand(putCmd)
+	objectCmd.AddCommand(deleteCmd)
+
+	return objectCmd
+}
+
+// client returns an s3 client for the endpoint and credentials of the s3 user given by the flags.
+func (d *s3DataCmd) client() (*helper.S3Client, error) {
+	var (
+		tenant    = viper.GetString("tenant")
+		id        = viper.GetString("id")
+		partition = viper.GetString("partition")
+		project   = viper.GetString("project")
+		accessKey = viper.GetString("access-key")
+	)
+
+	request := s3.NewGets3Params()
+	request.SetBody(&models.V1S3GetRequest{
+		ID:        &id,
+		Partition: &partition,
+		Tenant:    &tenant,
+		Project:   &project,
+	})
+
+	response, err := d.c.cloud.S3.Gets3(request, nil)
+	if err != nil {
+		return nil, err
+	}
+	user := response.Payload
+
+	var key *models.V1S3Key
+	for _, k := range user.Keys {
+		if accessKey == "" || pointer.SafeDeref(k.AccessKey) == accessKey {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		if accessKey != "" {
+			return nil, fmt.Errorf("s3 user %s has no access key %s", id, accessKey)
+		}
+		return nil, fmt.Errorf("s3 user %s has no keys, add one with cloudctl s3 add-key", id)
+	}
+
+	return helper.NewS3Client(pointer.SafeDeref(user.Endpoint), viper.GetString("region"), pointer.SafeDeref(key.AccessKey), pointer.SafeDeref(key.SecretKey)), nil
+}
+
+func (d *s3DataCmd) bucketList() error {
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	buckets, err := client.ListBuckets(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return d.c.listPrinter.Print(buckets)
+}
+
+func (d *s3DataCmd) bucketCreate(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.CreateBucket(context.Background(), bucket)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(d.c.out, "created bucket %s\n", bucket)
+	return nil
+}
+
+func (d *s3DataCmd) bucketDelete(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if viper.GetBool("force") {
+		// a versioned bucket can only be deleted once all versions and delete markers of its objects are gone
+		versions, err := client.ListObjectVersions(ctx, bucket)
+		if err != nil {
+			return err
+		}
+
+		if len(versions) > 0 && !viper.GetBool("yes-i-really-mean-it") {
+			fmt.Printf("bucket %s contains %d object versions, which will be deleted irrevocably.\n", bucket, len(versions))
+			err = helper.Prompt("Are you sure? (y/n)", "y")
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, v := range versions {
+			err = client.DeleteObjectVersion(ctx, bucket, v.Key, v.VersionID)
+			if err != nil {
+				return fmt.Errorf("unable to delete version %s of object %s: %w", v.VersionID, v.Key, err)
+			}
+		}
+	}
+
+	err = client.DeleteBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(d.c.out, "deleted bucket %s\n", bucket)
+	return nil
+}
+
+func (d *s3DataCmd) bucketPolicyGet(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	policy, err := client.GetBucketPolicy(context.Background(), bucket)
+	if err != nil {
+		return err
+	}
+	if len(policy) == 0 {
+		fmt.Fprintf(os.Stderr, "bucket %s has no policy\n", bucket)
+		return nil
+	}
+
+	var out bytes.Buffer
+	err = json.Indent(&out, policy, "", "  ")
+	if err != nil {
+		// print the policy as returned by the endpoint
+		out.Reset()
+		out.Write(policy)
+	}
+	fmt.Fprintln(d.c.out, strings.TrimSpace(out.String()))
+
+	return nil
+}
+
+func (d *s3DataCmd) bucketPolicySet(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var policy []byte
+	if from := viper.GetString("file"); from == "-" {
+		policy, err = io.ReadAll(os.Stdin)
+	} else {
+		policy, err = os.ReadFile(from)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read policy: %w", err)
+	}
+
+	err = helper.ValidateS3BucketPolicy(policy, bucket)
+	if err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.PutBucketPolicy(context.Background(), bucket, policy)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(d.c.out, "updated policy of bucket %s\n", bucket)
+	return nil
+}
+
+func (d *s3DataCmd) bucketPolicyDelete(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteBucketPolicy(context.Background(), bucket)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(d.c.out, "deleted policy of bucket %s\n", bucket)
+	return nil
+}
+
+func (d *s3DataCmd) bucketLifecycleGet(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	lifecycle, err := client.GetBucketLifecycle(context.Background(), bucket)
+	if err != nil {
+		return err
+	}
+
+	return d.c.describePrinter.Print(lifecycle)
+}
+
+func (d *s3DataCmd) bucketLifecycleSet(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	lifecycle := &helper.S3LifecycleConfiguration{}
+	err = helper.ReadFrom(viper.GetString("file"), lifecycle, func(data any) {})
+	if err != nil {
+		return err
+	}
+
+	err = lifecycle.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid lifecycle rules: %w", err)
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.PutBucketLifecycle(context.Background(), bucket, lifecycle)
+	if err != nil {
+		return err
+	}
+
+	return d.c.describePrinter.Print(lifecycle)
+}
+
+func (d *s3DataCmd) bucketVersioning(args []string, status string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	err = client.PutBucketVersioning(context.Background(), bucket, status)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(d.c.out, "versioning of bucket %s is %s\n", bucket, strings.ToLower(status))
+	return nil
+}
+
+func (d *s3DataCmd) objectList(args []string) error {
+	bucket, err := s3BucketFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	objects, err := client.ListObjects(context.Background(), bucket, viper.GetString("prefix"), 0)
+	if err != nil {
+		return err
+	}
+
+	return d.c.listPrinter.Print(objects)
+}
+
+func (d *s3DataCmd) objectGet(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("bucket and key must be given")
+	}
+	bucket, key := args[0], args[1]
+
+	filename := viper.GetString("file")
+	if filename == "" {
+		var err error
+		filename, err = s3ObjectFileName(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	r, err := client.GetObject(context.Background(), bucket, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	if filename == "-" {
+		_, err = io.Copy(d.c.out, r)
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	// do not leave a truncated file behind if the download fails
+	n, err := io.Copy(f, r)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(filename)
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		_ = os.Remove(filename)
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "downloaded %s/%s to %s (%s)\n", bucket, key, filename, helper.HumanizeSize(n))
+	return nil
+}
+
+func (d *s3DataCmd) objectPut(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("bucket and file must be given")
+	}
+	bucket, filename := args[0], args[1]
+
+	key := viper.GetString("key")
+	if key == "" {
+		if filename == "-" {
+			return fmt.Errorf("--key must be given when reading from stdin")
+		}
+		key = filepath.Base(filename)
+	}
+
+	contentType := viper.GetString("content-type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	var (
+		r    io.Reader = os.Stdin
+		size int64     = -1
+	)
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		r = f
+		size = info.Size()
+	}
+
+	// the size of stdin is not known in advance and large files cannot be uploaded with a single request,
+	// so they are streamed in parts
+	if size < 0 || size > client.PartSize {
+		size, err = client.PutObjectMultipart(context.Background(), bucket, key, r, contentType)
+	} else {
+		err = client.PutObject(context.Background(), bucket, key, r, size, contentType)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "uploaded %s to %s/%s (%s)\n", filename, bucket, key, helper.HumanizeSize(size))
+	return nil
+}
+
+func (d *s3DataCmd) objectDelete(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("bucket and at least one key must be given")
+	}
+	bucket, keys := args[0], args[1:]
+
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = client.DeleteObject(context.Background(), bucket, key)
+		if err != nil {
+			return fmt.Errorf("unable to delete object %s: %w", key, err)
+		}
+		fmt.Fprintf(d.c.out, "deleted %s/%s\n", bucket, key)
+	}
+
+	return nil
+}
+
+// s3ObjectFileName returns the base name of the key as file name for a download. keys of directories
+// or whose base name does not denote a file in the working directory are rejected.
+func s3ObjectFileName(key string) (string, error) {
+	name := path.Base(key)
+	if strings.HasSuffix(key, "/") || name == "" || name == "." || name == ".." || name == "/" {
+		return "", fmt.Errorf("no file name can be derived from key %q, specify it with --file", key)
+	}
+	return name, nil
+}
+
+func s3BucketFromArgs(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("exactly one bucket must be given")
+	}
+	return args[0], nil
+}
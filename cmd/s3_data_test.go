@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/client/s3"
+	"github.com/fi-ts/cloud-go/api/models"
+	testclient "github.com/fi-ts/cloud-go/test/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_s3ObjectFileName(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "report.csv", want: "report.csv"},
+		{key: "reports/2024.csv", want: "2024.csv"},
+		{key: "reports/", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: "", wantErr: true},
+		{key: ".", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "reports/..", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := s3ObjectFileName(tt.key)
+			if tt.wantErr {
+				require.EqualError(t, err, "no file name can be derived from key \""+tt.key+"\", specify it with --file")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_s3DataCmd_objectGet(t *testing.T) {
+	const content = "id,name\n1,test\n"
+
+	tests := []struct {
+		name     string
+		key      string
+		file     string
+		handler  http.HandlerFunc
+		wantFile string
+		wantErr  bool
+	}{
+		{
+			name: "download to the base name of the key",
+			key:  "reports/2024.csv",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(content))
+			},
+			wantFile: "2024.csv",
+		},
+		{
+			name: "download to the given file",
+			key:  "reports/",
+			file: "reports.csv",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(content))
+			},
+			wantFile: "reports.csv",
+		},
+		{
+			name: "truncated download is removed",
+			key:  "reports/2024.csv",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// announce more than is sent, the client fails with an unexpected eof
+				w.Header().Set("Content-Length", "1024")
+				_, _ = w.Write([]byte(content))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "key of a directory",
+			key:     "reports/",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+
+			server := httptest.NewServer(tt.handler)
+			t.Cleanup(server.Close)
+
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("file", tt.file)
+
+			var out bytes.Buffer
+			d := s3DataCmd{c: &config{
+				cloud: testclient.NewCloudMockClient(t, &testclient.CloudMockFns{
+					S3: func(m *mock.Mock) {
+						m.On("Gets3", mock.Anything, nil).Return(&s3.Gets3OK{Payload: &models.V1S3CredentialsResponse{
+							ID:       new("user"),
+							Endpoint: new(server.URL),
+							Keys:     []*models.V1S3Key{{AccessKey: new("access"), SecretKey: new("secret")}},
+						}}, nil).Maybe()
+					},
+				}),
+				out: &out,
+			}}
+
+			err := d.objectGet([]string{"bucket", tt.key})
+			if tt.wantErr {
+				require.Error(t, err)
+
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Empty(t, entries, "no file must be left behind")
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := os.ReadFile(filepath.Join(dir, tt.wantFile))
+			require.NoError(t, err)
+			require.Equal(t, content, string(got))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/go-openapi/strfmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func Test_s3UserUsages(t *testing.T) {
+	var (
+		day     = int64(24 * time.Hour)
+		deleted = strfmt.DateTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+		gi      = int64(1 << 30)
+	)
+
+	user := func(id string, maxBuckets int64) *models.V1S3CredentialsResponse {
+		return &models.V1S3CredentialsResponse{
+			ID:         new(id),
+			Tenant:     new("fits"),
+			Project:    new("p1"),
+			Partition:  new("dc1"),
+			MaxBuckets: new(maxBuckets),
+		}
+	}
+	bucket := func(user, id string, objects, storedGi int64, end *strfmt.DateTime) *models.V1S3Usage {
+		return &models.V1S3Usage{
+			User:                   new(user),
+			Projectid:              new("p1"),
+			Partition:              new("dc1"),
+			Bucketid:               new(id),
+			Currentnumberofobjects: new(strconv.FormatInt(objects, 10)),
+			Storageseconds:         new(strconv.FormatInt(storedGi*gi*24*3600, 10)),
+			Lifetime:               new(day),
+			End:                    end,
+		}
+	}
+
+	users := []*models.V1S3CredentialsResponse{user("full", 2), user("near", 5), user("unlimited", 0)}
+	usage := []*models.V1S3Usage{
+		bucket("full", "a", 10, 1, nil),
+		bucket("full", "b", 5, 2, nil),
+		bucket("near", "c", 1, 1, nil),
+		bucket("near", "d", 1, 1, nil),
+		bucket("near", "e", 1, 1, nil),
+		bucket("near", "f", 1, 1, nil),
+		bucket("near", "g", 100, 10, &deleted),
+		bucket("unlimited", "h", 3, 4, nil),
+		bucket("unknown", "i", 3, 4, nil),
+	}
+
+	got := s3UserUsages(users, usage, 0.8, 0.01)
+
+	want := []*tableprinters.S3UserUsage{
+		{ID: "full", Tenant: "fits", ProjectID: "p1", Partition: "dc1", Buckets: 2, MaxBuckets: 2, Objects: 15, StoredGi: 3, Costs: new(3 * 24 * 0.01), NearQuota: true},
+		{ID: "near", Tenant: "fits", ProjectID: "p1", Partition: "dc1", Buckets: 4, MaxBuckets: 5, Objects: 4, StoredGi: 4, Costs: new(14 * 24 * 0.01), NearQuota: true},
+		{ID: "unlimited", Tenant: "fits", ProjectID: "p1", Partition: "dc1", Buckets: 1, Objects: 3, StoredGi: 4, Costs: new(4 * 24 * 0.01)},
+	}
+
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("diff (+got -want):\n %s", diff)
+	}
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/accounting"
+	"github.com/fi-ts/cloud-go/api/client/s3"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/tableprinters"
+	"github.com/go-openapi/strfmt"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+	"github.com/spf13/viper"
+)
+
+func (c *config) s3UserUsage() error {
+	from, err := eventuallyRelativeDateTime(viper.GetString("from"))
+	if err != nil {
+		return err
+	}
+
+	threshold := viper.GetFloat64("threshold")
+	if threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("the threshold must satisfy 0 < --threshold <= 1")
+	}
+
+	var (
+		partition = viper.GetString("partition")
+		project   = viper.GetString("project")
+	)
+
+	request := s3.NewLists3Params()
+	request.SetBody(&models.V1S3ListRequest{
+		Partition: &partition,
+	})
+	response, err := c.cloud.S3.Lists3(request, nil)
+	if err != nil {
+		return err
+	}
+
+	// the list does not contain the maximum number of buckets, so every user is described
+	var users []*models.V1S3CredentialsResponse
+	for _, u := range response.Payload {
+		if project != "" && pointer.SafeDeref(u.Project) != project {
+			continue
+		}
+
+		params := s3.NewGets3Params()
+		params.SetBody(&models.V1S3GetRequest{
+			ID:        u.ID,
+			Partition: u.Partition,
+			Tenant:    u.Tenant,
+			Project:   u.Project,
+		})
+		user, err := c.cloud.S3.Gets3(params, nil)
+		if err != nil {
+			return fmt.Errorf("unable to describe s3 user %s: %w", pointer.SafeDeref(u.ID), err)
+		}
+		users = append(users, user.Payload)
+	}
+
+	req := &models.V1S3UsageRequest{
+		From: &from,
+		To:   strfmt.DateTime(time.Now()),
+	}
+	if project != "" {
+		req.Projectid = project
+	}
+	usageRequest := accounting.NewS3UsageParams()
+	usageRequest.SetBody(req)
+	usage, err := c.cloud.Accounting.S3Usage(usageRequest, nil)
+	if err != nil {
+		return err
+	}
+
+	result := s3UserUsages(users, usage.Payload.Usage, threshold, viper.GetFloat64("costs-storage-gi-hour"))
+
+	err = c.listPrinter.Print(result)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range result {
+		if u.NearQuota {
+			fmt.Fprintf(os.Stderr, "s3 user %s uses %d of %d buckets\n", u.ID, u.Buckets, u.MaxBuckets)
+		}
+	}
+
+	return nil
+}
+
+// s3UserUsages joins the users with the accounted usage of their buckets. only buckets which were not deleted
+// count towards the number of buckets and objects, the storage is averaged over the lifetime of the buckets.
+func s3UserUsages(users []*models.V1S3CredentialsResponse, usage []*models.V1S3Usage, threshold, storagePerGiAndHour float64) []*tableprinters.S3UserUsage {
+	type userKey struct {
+		id, partition, project string
+	}
+
+	var (
+		result []*tableprinters.S3UserUsage
+		byUser = map[userKey]*tableprinters.S3UserUsage{}
+	)
+
+	for _, u := range users {
+		r := &tableprinters.S3UserUsage{
+			ID:         pointer.SafeDeref(u.ID),
+			Tenant:     pointer.SafeDeref(u.Tenant),
+			ProjectID:  pointer.SafeDeref(u.Project),
+			Partition:  pointer.SafeDeref(u.Partition),
+			MaxBuckets: pointer.SafeDeref(u.MaxBuckets),
+		}
+		if storagePerGiAndHour > 0 {
+			r.Costs = new(0.0)
+		}
+
+		byUser[userKey{id: r.ID, partition: r.Partition, project: r.ProjectID}] = r
+		result = append(result, r)
+	}
+
+	buckets := map[*tableprinters.S3UserUsage]map[string]bool{}
+	for _, u := range usage {
+		r, ok := byUser[userKey{id: pointer.SafeDeref(u.User), partition: pointer.SafeDeref(u.Partition), project: pointer.SafeDeref(u.Projectid)}]
+		if !ok {
+			continue
+		}
+
+		storageSeconds, _ := strconv.ParseFloat(pointer.SafeDeref(u.Storageseconds), 64)
+		if r.Costs != nil {
+			*r.Costs += storageSeconds / (1 << 30) / 3600 * storagePerGiAndHour
+		}
+
+		if u.End != nil && !time.Time(*u.End).IsZero() {
+			continue
+		}
+
+		bucket := pointer.SafeDeref(u.Bucketid)
+		if bucket == "" {
+			bucket = pointer.SafeDeref(u.Bucketname)
+		}
+		if buckets[r] == nil {
+			buckets[r] = map[string]bool{}
+		}
+		buckets[r][bucket] = true
+
+		objects, _ := strconv.ParseInt(pointer.SafeDeref(u.Currentnumberofobjects), 10, 64)
+		r.Objects += objects
+
+		if lifetime := time.Duration(pointer.SafeDeref(u.Lifetime)); lifetime > 0 {
+			r.StoredGi += storageSeconds / lifetime.Seconds() / (1 << 30)
+		}
+	}
+
+	for _, r := range result {
+		r.Buckets = int64(len(buckets[r]))
+		// users without a maximum number of buckets have no quota
+		r.NearQuota = r.MaxBuckets > 0 && float64(r.Buckets) >= threshold*float64(r.MaxBuckets)
+	}
+
+	return result
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/pkg/api"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// sessionRecordingMetadata is written to the header of a session recording.
+type sessionRecordingMetadata struct {
+	User      string    `json:"user"`
+	Context   string    `json:"context"`
+	Cluster   string    `json:"cluster"`
+	Machine   string    `json:"machine"`
+	Access    string    `json:"access"`
+	Reason    string    `json:"reason,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// sessionRecorder returns a recorder for a console or ssh session to a machine if recording is enabled
+// with --record or with the record_dir of the current context, otherwise nil is returned.
+func sessionRecorder(access, clusterID, machineID, reason string) (*helper.AsciicastRecorder, error) {
+	dir := viper.GetString("record")
+	if dir == "" {
+		dir = api.MustDefaultContext().RecordDir
+	}
+	if dir == "" {
+		return nil, nil
+	}
+
+	dir, err := helper.ExpandHomeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxs, err := api.GetContexts()
+	if err != nil {
+		return nil, err
+	}
+	authContext, err := api.GetAuthContext(viper.GetString("kubeconfig"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%s-%s-%s.cast", now.UTC().Format("20060102T150405Z"), clusterID, machineID, access))
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height, err := term.GetSize(int(os.Stdout.Fd())) //nolint:gosec
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	recorder, err := helper.NewAsciicastRecorder(f, helper.AsciicastHeader{
+		Width:  width,
+		Height: height,
+		Title:  fmt.Sprintf("%s access of %s to machine %s of cluster %s", access, authContext.User, machineID, clusterID),
+		Env: map[string]string{
+			"TERM":  os.Getenv("TERM"),
+			"SHELL": os.Getenv("SHELL"),
+		},
+		Metadata: sessionRecordingMetadata{
+			User:      authContext.User,
+			Context:   ctxs.CurrentContext,
+			Cluster:   clusterID,
+			Machine:   machineID,
+			Access:    access,
+			Reason:    reason,
+			StartedAt: now,
+		},
+	})
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "recording session to %s\n", filename)
+
+	return recorder, nil
+}
@@ -1,35 +1,361 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
 	metalssh "github.com/metal-stack/metal-lib/pkg/ssh"
 	metalvpn "github.com/metal-stack/metal-lib/pkg/vpn"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 )
 
-func (c *config) firewallSSHViaVPN(firewallID string, privateKey []byte, vpn *models.V1VPN) (err error) {
-	fmt.Printf("accessing firewall through vpn ")
-	ctx := context.Background()
-	v, err := metalvpn.Connect(ctx, firewallID, *vpn.Address, *vpn.AuthKey)
+// sshConnection is an ssh client to a machine of a cluster, which is possibly
+// tunneled through the vpn and the firewall of the cluster.
+type sshConnection struct {
+	*metalssh.Client
+	closers []func() error
+	// vrf is the vrf of the tenant network if the connection is to a firewall
+	vrf int64
+}
+
+// Close closes the client and all connections it was tunneled through.
+func (s *sshConnection) Close() error {
+	errs := []error{s.Client.Close()}
+	for _, closer := range slices.Backward(s.closers) {
+		errs = append(errs, closer())
+	}
+	return errors.Join(errs...)
+}
+
+// Run runs the command on the remote machine without allocating a terminal.
+func (s *sshConnection) Run(command []string, stdout, stderr io.Writer) error {
+	session, err := s.NewSession()
 	if err != nil {
 		return err
 	}
 	defer func() {
-		_ = v.Close()
+		_ = session.Close()
 	}()
 
-	opts := []metalssh.ConnectOpt{metalssh.ConnectOptOutputPrivateKey(privateKey)}
+	session.Stdout = stdout
+	session.Stderr = stderr
 
-	s, err := metalssh.NewClientWithConnection("metal", v.TargetIP, v.Conn, opts...)
+	return session.Run(strings.Join(command, " "))
+}
+
+// Shell starts an interactive shell on the remote machine like metalssh's Connect, but additionally writes the
+// output of the session to record.
+func (s *sshConnection) Shell(env map[string]string, record io.Writer) error {
+	session, err := s.NewSession()
 	if err != nil {
 		return err
 	}
-	return s.Connect(nil)
+	defer func() {
+		_ = session.Close()
+	}()
+
+	for key, value := range env {
+		err := session.Setenv(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	session.Stdin = os.Stdin
+	session.Stdout = io.MultiWriter(os.Stdout, record)
+	session.Stderr = io.MultiWriter(os.Stderr, record)
+
+	fd := int(os.Stdin.Fd()) //nolint:gosec
+
+	if term.IsTerminal(fd) {
+		originalState, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = term.Restore(fd, originalState)
+		}()
+
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			return err
+		}
+
+		err = session.RequestPty("xterm-256color", height, width, ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 115200,
+			ssh.TTY_OP_OSPEED: 115200,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = session.Shell()
+	if err != nil {
+		return err
+	}
+
+	return session.Wait()
+}
+
+// machineSSH connects to the given machine of the cluster. firewalls are accessed through the vpn or their public ip,
+// worker nodes are reached by hopping through a firewall of the cluster into the tenant network.
+func (c *config) machineSSH(ctx context.Context, shoot *models.V1ClusterResponse, m *models.ModelsV1MachineResponse, keypair *sshkeypair, out io.Writer) (*sshConnection, error) {
+	if m.Allocation == nil {
+		return nil, fmt.Errorf("machine:%s is not allocated", pointer.SafeDeref(m.ID))
+	}
+
+	switch role := pointer.SafeDeref(m.Allocation.Role); role {
+	case "firewall":
+		return c.firewallSSH(ctx, m, keypair, out)
+	case "machine":
+		ip, vrf := machinePrivateNetwork(m)
+		if ip == "" {
+			return nil, fmt.Errorf("machine:%s has no ip in the tenant network", pointer.SafeDeref(m.ID))
+		}
+
+		// a firewall which is reachable might still not be able to reach the machine, so the next one is tried
+		return clusterFirewallsConnect(shoot.Firewalls, func(fw *models.ModelsV1MachineResponse) (*sshConnection, error) {
+			s, err := c.firewallSSH(ctx, fw, keypair, out)
+			if err != nil {
+				return nil, err
+			}
+
+			_, _ = fmt.Fprintf(out, "ssh to metal@%s through the firewall\n", ip)
+
+			client, err := s.jump(ip, vrf, keypair, out)
+			if err != nil {
+				_ = s.Close()
+				return nil, fmt.Errorf("unable to reach machine:%s through the firewall %w", pointer.SafeDeref(m.ID), err)
+			}
+
+			client.closers = append(s.closers, s.Client.Close)
+
+			return client, nil
+		})
+	default:
+		return nil, fmt.Errorf("unknown machine role:%s", role)
+	}
+}
+
+// clusterFirewallSSH connects to the first reachable firewall of the cluster.
+func (c *config) clusterFirewallSSH(ctx context.Context, shoot *models.V1ClusterResponse, keypair *sshkeypair, out io.Writer) (*sshConnection, error) {
+	return clusterFirewallsConnect(shoot.Firewalls, func(fw *models.ModelsV1MachineResponse) (*sshConnection, error) {
+		return c.firewallSSH(ctx, fw, keypair, out)
+	})
+}
+
+// clusterFirewallsConnect calls connect for one firewall after another and returns the first successful connection.
+func clusterFirewallsConnect(firewalls []*models.ModelsV1MachineResponse, connect func(fw *models.ModelsV1MachineResponse) (*sshConnection, error)) (*sshConnection, error) {
+	var errs []error
+	for _, fw := range firewalls {
+		s, err := connect(fw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to connect through firewall:%s %w", pointer.SafeDeref(fw.ID), err))
+			continue
+		}
+		return s, nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("cluster has no firewall")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// jump connects to the machine with the given ip in the tenant network through this connection.
+// the returned connection does not close this one.
+func (s *sshConnection) jump(ip string, vrf int64, keypair *sshkeypair, out io.Writer) (*sshConnection, error) {
+	conn, err := dialTenant(s.Dial, s.dialVRF, vrf)("tcp", net.JoinHostPort(ip, "22"))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := metalssh.NewClientWithConnection("metal", ip, conn, metalssh.ConnectOptOutputPrivateKey(keypair.privatekey), metalssh.ConnectOptOutputWriter(out))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &sshConnection{Client: client}, nil
+}
+
+// tenantDial opens connections to addresses in the tenant network of the firewall this connection is to.
+func (s *sshConnection) tenantDial(network, address string) (net.Conn, error) {
+	return dialTenant(s.Dial, s.dialVRF, s.vrf)(network, address)
+}
+
+// dialTenant returns a dialer for the tenant network with the given vrf.
+// the ssh daemon of the firewall does not run in the tenant vrf, so forwarding a port directly only works
+// if the network is routed into its vrf. otherwise the connection is opened from within the tenant vrf on the firewall.
+func dialTenant(dial helper.DialFunc, dialVRF func(vrf int64, address string) (net.Conn, error), vrf int64) helper.DialFunc {
+	return func(network, address string) (net.Conn, error) {
+		conn, err := dial(network, address)
+		if err == nil {
+			return conn, nil
+		}
+		if network != "tcp" || vrf == 0 {
+			return nil, err
+		}
+
+		vrfConn, vrfErr := dialVRF(vrf, address)
+		if vrfErr != nil {
+			return nil, errors.Join(err, vrfErr)
+		}
+
+		return vrfConn, nil
+	}
+}
+
+// dialVRF connects to the address from within the given vrf of the remote machine by running netcat in a session.
+func (s *sshConnection) dialVRF(vrf int64, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	session.Stderr = stderr
+
+	err = session.Start(sshVRFDialCommand(vrf, host, port))
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+
+	return &sshSessionConn{session: session, Reader: stdout, WriteCloser: stdin, stderr: stderr}, nil
+}
+
+// sshVRFDialCommand returns the command which connects stdin and stdout to the given host in the vrf.
+// the vrfs of the tenant networks are named after their id on the firewall.
+func sshVRFDialCommand(vrf int64, host, port string) string {
+	return fmt.Sprintf("sudo --non-interactive ip vrf exec vrf%d nc %s %s", vrf, host, port)
+}
+
+// sshSessionConn is a net.Conn on top of the standard streams of a remote command.
+type sshSessionConn struct {
+	io.Reader
+	io.WriteCloser
+	session *ssh.Session
+	stderr  *bytes.Buffer
 }
 
-func (c *config) sshClient(user, host string, privateKey []byte, port int, idToken *string) error {
+// Read returns the error output of the remote command when it terminated, such that
+// a failing command does not just show up as an unexpected end of the connection.
+func (c *sshSessionConn) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return n, fmt.Errorf("%w: %s", err, msg)
+		}
+	}
+	return n, err
+}
+
+func (c *sshSessionConn) Close() error {
+	_ = c.WriteCloser.Close()
+	return c.session.Close()
+}
+
+func (c *sshSessionConn) LocalAddr() net.Addr                { return sshSessionAddr{} }
+func (c *sshSessionConn) RemoteAddr() net.Addr               { return sshSessionAddr{} }
+func (c *sshSessionConn) SetDeadline(t time.Time) error      { return nil }
+func (c *sshSessionConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *sshSessionConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type sshSessionAddr struct{}
+
+func (sshSessionAddr) Network() string { return "ssh-session" }
+func (sshSessionAddr) String() string  { return "ssh-session" }
+
+func (c *config) firewallSSH(ctx context.Context, fw *models.ModelsV1MachineResponse, keypair *sshkeypair, out io.Writer) (*sshConnection, error) {
+	opts := []metalssh.ConnectOpt{metalssh.ConnectOptOutputPrivateKey(keypair.privatekey), metalssh.ConnectOptOutputWriter(out)}
+
+	if keypair.vpn != nil {
+		_, _ = fmt.Fprintf(out, "accessing firewall through vpn ")
+		v, err := metalvpn.Connect(ctx, *fw.ID, *keypair.vpn.Address, *keypair.vpn.AuthKey, metalvpn.ConnectOptOutputWriter(out))
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := metalssh.NewClientWithConnection("metal", v.TargetIP, v.Conn, opts...)
+		if err != nil {
+			_ = v.Close()
+			return nil, err
+		}
+
+		return &sshConnection{Client: s, closers: []func() error{v.Close}, vrf: firewallTenantVRF(fw)}, nil
+	}
+
+	for _, nw := range fw.Allocation.Networks {
+		if pointer.SafeDeref(nw.Underlay) || pointer.SafeDeref(nw.Private) {
+			continue
+		}
+		for _, ip := range nw.Ips {
+			if !portOpen(ip, "22", time.Second) {
+				continue
+			}
+			s, err := metalssh.NewClient("metal", ip, 22, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &sshConnection{Client: s, vrf: firewallTenantVRF(fw)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no ip with a open ssh port found")
+}
+
+// firewallTenantVRF returns the vrf of the tenant network of the firewall, which is zero if it is unknown.
+func firewallTenantVRF(fw *models.ModelsV1MachineResponse) int64 {
+	if fw.Allocation == nil {
+		return 0
+	}
+	_, vrf := machinePrivateNetwork(fw)
+	return vrf
+}
+
+// machinePrivateNetwork returns the ip and the vrf of the machine in the tenant network of the cluster.
+func machinePrivateNetwork(m *models.ModelsV1MachineResponse) (string, int64) {
+	for _, nw := range m.Allocation.Networks {
+		if pointer.SafeDeref(nw.Underlay) || !pointer.SafeDeref(nw.Private) {
+			continue
+		}
+		if len(nw.Ips) > 0 {
+			return nw.Ips[0], pointer.SafeDeref(nw.Vrf)
+		}
+	}
+	return "", 0
+}
+
+func (c *config) sshClient(user, host string, privateKey []byte, port int, idToken *string, record io.Writer) error {
 	opts := []metalssh.ConnectOpt{metalssh.ConnectOptOutputPrivateKey(privateKey)}
 
 	s, err := metalssh.NewClient(user, host, port, opts...)
@@ -40,5 +366,8 @@ func (c *config) sshClient(user, host string, privateKey []byte, port int, idTok
 	if idToken != nil {
 		env = &metalssh.Env{"LC_METAL_STACK_OIDC_TOKEN": *idToken}
 	}
+	if record != nil {
+		return (&sshConnection{Client: s}).Shell(pointer.SafeDeref(env), record)
+	}
 	return s.Connect(env)
 }
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_clusterFirewallsConnect(t *testing.T) {
+	var (
+		fw1       = &models.ModelsV1MachineResponse{ID: new("fw1")}
+		fw2       = &models.ModelsV1MachineResponse{ID: new("fw2")}
+		connected = &sshConnection{}
+	)
+
+	tests := []struct {
+		name      string
+		firewalls []*models.ModelsV1MachineResponse
+		reachable map[string]bool
+		want      *sshConnection
+		wantTried []string
+		wantErr   string
+	}{
+		{
+			name:      "first firewall",
+			firewalls: []*models.ModelsV1MachineResponse{fw1, fw2},
+			reachable: map[string]bool{"fw1": true, "fw2": true},
+			want:      connected,
+			wantTried: []string{"fw1"},
+		},
+		{
+			name:      "next firewall is tried",
+			firewalls: []*models.ModelsV1MachineResponse{fw1, fw2},
+			reachable: map[string]bool{"fw2": true},
+			want:      connected,
+			wantTried: []string{"fw1", "fw2"},
+		},
+		{
+			name:      "no firewall reachable",
+			firewalls: []*models.ModelsV1MachineResponse{fw1, fw2},
+			wantTried: []string{"fw1", "fw2"},
+			wantErr:   "unable to connect through firewall:fw1 machine unreachable from fw1\nunable to connect through firewall:fw2 machine unreachable from fw2",
+		},
+		{
+			name:    "no firewalls",
+			wantErr: "cluster has no firewall",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tried []string
+
+			got, err := clusterFirewallsConnect(tt.firewalls, func(fw *models.ModelsV1MachineResponse) (*sshConnection, error) {
+				tried = append(tried, *fw.ID)
+				if !tt.reachable[*fw.ID] {
+					return nil, fmt.Errorf("machine unreachable from %s", *fw.ID)
+				}
+				return connected, nil
+			})
+
+			require.Equal(t, tt.wantTried, tried)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Same(t, tt.want, got)
+		})
+	}
+}
+
+func Test_machinePrivateNetwork(t *testing.T) {
+	m := &models.ModelsV1MachineResponse{
+		Allocation: &models.ModelsV1MachineAllocation{
+			Networks: []*models.ModelsV1MachineNetwork{
+				{Ips: []string{"10.1.0.1"}, Underlay: new(true), Private: new(false), Vrf: new(int64(0))},
+				{Ips: []string{"185.1.2.3"}, Underlay: new(false), Private: new(false), Vrf: new(int64(104009))},
+				{Ips: []string{"10.130.0.5"}, Underlay: new(false), Private: new(true), Vrf: new(int64(3981))},
+			},
+		},
+	}
+
+	ip, vrf := machinePrivateNetwork(m)
+	require.Equal(t, "10.130.0.5", ip)
+	require.Equal(t, int64(3981), vrf)
+
+	ip, vrf = machinePrivateNetwork(&models.ModelsV1MachineResponse{Allocation: &models.ModelsV1MachineAllocation{}})
+	require.Empty(t, ip)
+	require.Zero(t, vrf)
+}
+
+func Test_dialTenant(t *testing.T) {
+	var (
+		direct = &net.TCPConn{}
+		viaVRF = &net.TCPConn{}
+	)
+
+	tests := []struct {
+		name        string
+		network     string
+		vrf         int64
+		directErr   error
+		vrfErr      error
+		want        net.Conn
+		wantVRFDial string
+		wantErr     string
+	}{
+		{
+			name:    "direct connection",
+			network: "tcp",
+			vrf:     3981,
+			want:    direct,
+		},
+		{
+			name:        "fallback to the vrf",
+			network:     "tcp",
+			vrf:         3981,
+			directErr:   errors.New("ssh: rejected: connect failed (No route to host)"),
+			want:        viaVRF,
+			wantVRFDial: "vrf3981 10.130.0.5:10250",
+		},
+		{
+			name:        "fallback fails as well",
+			network:     "tcp",
+			vrf:         3981,
+			directErr:   errors.New("ssh: rejected: connect failed (No route to host)"),
+			vrfErr:      errors.New("EOF: sudo: a password is required"),
+			wantVRFDial: "vrf3981 10.130.0.5:10250",
+			wantErr:     "ssh: rejected: connect failed (No route to host)\nEOF: sudo: a password is required",
+		},
+		{
+			name:      "unknown vrf",
+			network:   "tcp",
+			directErr: errors.New("ssh: rejected: connect failed (No route to host)"),
+			wantErr:   "ssh: rejected: connect failed (No route to host)",
+		},
+		{
+			name:      "only tcp is dialed in the vrf",
+			network:   "udp",
+			vrf:       3981,
+			directErr: errors.New("ssh: unsupported network"),
+			wantErr:   "ssh: unsupported network",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vrfDial string
+
+			dial := dialTenant(
+				func(network, address string) (net.Conn, error) {
+					require.Equal(t, tt.network, network)
+					require.Equal(t, "10.130.0.5:10250", address)
+					if tt.directErr != nil {
+						return nil, tt.directErr
+					}
+					return direct, nil
+				},
+				func(vrf int64, address string) (net.Conn, error) {
+					vrfDial = fmt.Sprintf("vrf%d %s", vrf, address)
+					if tt.vrfErr != nil {
+						return nil, tt.vrfErr
+					}
+					return viaVRF, nil
+				},
+				tt.vrf,
+			)
+
+			got, err := dial(tt.network, "10.130.0.5:10250")
+			require.Equal(t, tt.wantVRFDial, vrfDial)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Same(t, tt.want, got)
+		})
+	}
+}
+
+func Test_firewallTenantVRF(t *testing.T) {
+	fw := &models.ModelsV1MachineResponse{
+		Allocation: &models.ModelsV1MachineAllocation{
+			Networks: []*models.ModelsV1MachineNetwork{
+				{Ips: []string{"185.1.2.3"}, Underlay: new(false), Private: new(false), Vrf: new(int64(104009))},
+				{Ips: []string{"10.130.0.1"}, Underlay: new(false), Private: new(true), Vrf: new(int64(3981))},
+			},
+		},
+	}
+
+	require.Equal(t, int64(3981), firewallTenantVRF(fw))
+	require.Zero(t, firewallTenantVRF(&models.ModelsV1MachineResponse{}))
+}
+
+func Test_sshVRFDialCommand(t *testing.T) {
+	require.Equal(t, "sudo --non-interactive ip vrf exec vrf3981 nc 10.130.0.5 22", sshVRFDialCommand(3981, "10.130.0.5", "22"))
+}
+
+func Test_sshSessionConn_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		stderr  string
+		wantErr string
+	}{
+		{
+			name:    "connection closed",
+			stdout:  "SSH-2.0-OpenSSH_9.6\r\n",
+			wantErr: "EOF",
+		},
+		{
+			name:    "command failed",
+			stderr:  "sudo: a password is required\n",
+			wantErr: "EOF: sudo: a password is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &sshSessionConn{Reader: strings.NewReader(tt.stdout), stderr: bytes.NewBufferString(tt.stderr)}
+
+			got, err := io.ReadAll(conn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, tt.stdout, string(got))
+
+			_, err = conn.Read(make([]byte, 1))
+			require.True(t, errors.Is(err, io.EOF))
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_exitCode(t *testing.T) {
+	require.Equal(t, 1, exitCode(errors.New("connection refused")))
+	require.Equal(t, 3, exitCode(fmt.Errorf("ssh: %w", &testExitError{status: 3})))
+	require.Equal(t, 1, exitCode(&testExitError{status: 0}))
+}
@@ -0,0 +1,42 @@
+package tableprinters
+
+import (
+	"github.com/fatih/color"
+)
+
+// ClusterBulkResult is the result of an operation on a single cluster during a bulk operation.
+type ClusterBulkResult struct {
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	Project   string `json:"project" yaml:"project"`
+	Partition string `json:"partition" yaml:"partition"`
+	Succeeded bool   `json:"succeeded" yaml:"succeeded"`
+	Message   string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+func (t *TablePrinter) ClusterBulkResultTable(data []*ClusterBulkResult, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"ID", "Name", "Project", "Partition", "Result", "Message"}
+		rows   [][]string
+	)
+
+	for _, r := range data {
+		result := color.GreenString("succeeded")
+		if !r.Succeeded {
+			result = color.RedString("failed")
+		}
+
+		rows = append(rows, []string{
+			r.ID,
+			r.Name,
+			r.Project,
+			r.Partition,
+			result,
+			r.Message,
+		})
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
@@ -0,0 +1,55 @@
+package tableprinters
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/metal-stack/metal-lib/pkg/genericcli"
+)
+
+// ClusterMachineExecResult is the result of a remote command on a single machine.
+type ClusterMachineExecResult struct {
+	MachineID string `json:"machine_id" yaml:"machine_id"`
+	Hostname  string `json:"hostname" yaml:"hostname"`
+	ExitCode  int    `json:"exit_code" yaml:"exit_code"`
+	Stdout    string `json:"stdout" yaml:"stdout"`
+	Stderr    string `json:"stderr" yaml:"stderr"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (t *TablePrinter) ClusterMachineExecResultTable(data []*ClusterMachineExecResult, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"ID", "Hostname", "Exit Code", "Output"}
+		rows   [][]string
+	)
+
+	for _, r := range data {
+		exitCode := color.GreenString(strconv.Itoa(r.ExitCode))
+		if r.ExitCode != 0 || r.Error != "" {
+			exitCode = color.RedString(strconv.Itoa(r.ExitCode))
+		}
+
+		output := strings.TrimSpace(r.Stdout)
+		if stderr := strings.TrimSpace(r.Stderr); stderr != "" {
+			output = strings.TrimSpace(output + "\n" + stderr)
+		}
+		if r.Error != "" {
+			output = r.Error
+		}
+		if !wide {
+			output = genericcli.TruncateEnd(strings.ReplaceAll(output, "\n", " "), 80)
+		}
+
+		rows = append(rows, []string{
+			r.MachineID,
+			r.Hostname,
+			exitCode,
+			output,
+		})
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
@@ -0,0 +1,36 @@
+package tableprinters
+
+// ClusterKubeconfigContext is a kubeconfig context merged by cloudctl.
+type ClusterKubeconfigContext struct {
+	Name      string `json:"name" yaml:"name"`
+	Current   bool   `json:"current" yaml:"current"`
+	ClusterID string `json:"cluster_id" yaml:"cluster_id"`
+	ProjectID string `json:"project_id" yaml:"project_id"`
+	Status    string `json:"status" yaml:"status"`
+}
+
+func (t *TablePrinter) ClusterKubeconfigContextTable(data []*ClusterKubeconfigContext, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"", "Context", "Cluster ID", "Project", "Status"}
+		rows   [][]string
+	)
+
+	for _, c := range data {
+		current := ""
+		if c.Current {
+			current = "*"
+		}
+
+		rows = append(rows, []string{
+			current,
+			c.Name,
+			c.ClusterID,
+			c.ProjectID,
+			c.Status,
+		})
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
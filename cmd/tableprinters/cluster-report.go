@@ -0,0 +1,86 @@
+package tableprinters
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ClusterVersionStatusExpired    = "expired"
+	ClusterVersionStatusExpiring   = "expiring"
+	ClusterVersionStatusDeprecated = "deprecated"
+)
+
+// ClusterVersionReport contains the version findings of all clusters of a project.
+type ClusterVersionReport struct {
+	ProjectID string                   `json:"project" yaml:"project"`
+	Tenant    string                   `json:"tenant" yaml:"tenant"`
+	Findings  []*ClusterVersionFinding `json:"findings" yaml:"findings"`
+}
+
+// ClusterVersionFinding is a cluster component running on an expiring or deprecated version.
+type ClusterVersionFinding struct {
+	ClusterID   string     `json:"cluster_id" yaml:"cluster_id"`
+	ClusterName string     `json:"cluster_name" yaml:"cluster_name"`
+	Partition   string     `json:"partition" yaml:"partition"`
+	Purpose     string     `json:"purpose" yaml:"purpose"`
+	Component   string     `json:"component" yaml:"component"`
+	WorkerGroup string     `json:"workergroup,omitempty" yaml:"workergroup,omitempty"`
+	Version     string     `json:"version" yaml:"version"`
+	Expires     *time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+	Status      []string   `json:"status" yaml:"status"`
+}
+
+// ClusterVersionReportHeader is the header used for the table and the csv output of the report.
+var ClusterVersionReportHeader = []string{"Project", "Tenant", "ID", "Name", "Partition", "Purpose", "Component", "Worker Group", "Version", "Expires", "Status"}
+
+// ClusterVersionReportRows returns one row per finding, ordered by project.
+func ClusterVersionReportRows(data []*ClusterVersionReport) [][]string {
+	var rows [][]string
+
+	for _, r := range data {
+		for _, f := range r.Findings {
+			expires := ""
+			if f.Expires != nil {
+				expires = f.Expires.Format(time.DateOnly)
+			}
+
+			rows = append(rows, []string{
+				r.ProjectID,
+				r.Tenant,
+				f.ClusterID,
+				f.ClusterName,
+				f.Partition,
+				f.Purpose,
+				f.Component,
+				f.WorkerGroup,
+				f.Version,
+				expires,
+				strings.Join(f.Status, ","),
+			})
+		}
+	}
+
+	return rows
+}
+
+func (t *TablePrinter) ClusterVersionReportTable(data []*ClusterVersionReport, wide bool) ([]string, [][]string, error) {
+	var (
+		header = ClusterVersionReportHeader
+		rows   = ClusterVersionReportRows(data)
+	)
+
+	if !wide {
+		// only print the project once for a better overview
+		for i := len(rows) - 1; i > 0; i-- {
+			if rows[i][0] == rows[i-1][0] {
+				rows[i][0] = ""
+				rows[i][1] = ""
+			}
+		}
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
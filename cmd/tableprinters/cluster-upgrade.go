@@ -0,0 +1,50 @@
+package tableprinters
+
+import (
+	"strconv"
+)
+
+// ClusterUpgradeStep is a single step of a cluster upgrade plan.
+type ClusterUpgradeStep struct {
+	Step        int    `json:"step" yaml:"step"`
+	Component   string `json:"component" yaml:"component"`
+	WorkerGroup string `json:"workergroup,omitempty" yaml:"workergroup,omitempty"`
+	Current     string `json:"current" yaml:"current"`
+	Next        string `json:"next,omitempty" yaml:"next,omitempty"`
+	Expires     string `json:"expires,omitempty" yaml:"expires,omitempty"`
+	ExpiresSoon bool   `json:"expires_soon" yaml:"expires_soon"`
+	Note        string `json:"note,omitempty" yaml:"note,omitempty"`
+}
+
+func (t *TablePrinter) ClusterUpgradeStepTable(data []*ClusterUpgradeStep, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"Step", "Component", "Worker Group", "Current", "Next", "Expires", "Note"}
+		rows   [][]string
+	)
+
+	for _, s := range data {
+		step := ""
+		if s.Step > 0 {
+			step = strconv.Itoa(s.Step)
+		}
+
+		expires := s.Expires
+		if s.ExpiresSoon {
+			expires = "⚠️ " + expires
+		}
+
+		rows = append(rows, []string{
+			step,
+			s.Component,
+			s.WorkerGroup,
+			s.Current,
+			s.Next,
+			expires,
+			s.Note,
+		})
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
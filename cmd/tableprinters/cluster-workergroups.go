@@ -0,0 +1,76 @@
+package tableprinters
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/metal-stack/metal-lib/pkg/pointer"
+)
+
+// ClusterWorkerGroup is a worker group of a cluster together with the amount of machines currently running in this group.
+type ClusterWorkerGroup struct {
+	ClusterID         string           `json:"clusterid" yaml:"clusterid"`
+	KubernetesVersion string           `json:"kubernetes_version" yaml:"kubernetes_version"`
+	Machines          int              `json:"machines" yaml:"machines"`
+	Worker            *models.V1Worker `json:"worker" yaml:"worker"`
+}
+
+func (t *TablePrinter) ClusterWorkerGroupTable(data []*ClusterWorkerGroup, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"Name", "Machine Type", "Image", "Kubernetes", "Current", "Desired"}
+		rows   [][]string
+	)
+
+	if wide {
+		header = append(header, "Max Surge", "Max Unavailable", "Labels", "Taints")
+	}
+
+	for _, wg := range data {
+		w := wg.Worker
+
+		image := ""
+		if w.MachineImage != nil {
+			image = pointer.SafeDeref(w.MachineImage.Name) + "-" + pointer.SafeDeref(w.MachineImage.Version)
+		}
+
+		minimum := pointer.SafeDeref(w.Minimum)
+		maximum := pointer.SafeDeref(w.Maximum)
+		desired := fmt.Sprintf("%d-%d", minimum, maximum)
+		if minimum == maximum {
+			desired = strconv.Itoa(int(minimum))
+		}
+
+		row := []string{
+			pointer.SafeDeref(w.Name),
+			pointer.SafeDeref(w.MachineType),
+			image,
+			wg.KubernetesVersion,
+			strconv.Itoa(wg.Machines),
+			desired,
+		}
+
+		if wide {
+			var labels []string
+			for k, v := range w.Labels {
+				labels = append(labels, k+"="+v)
+			}
+			sort.Strings(labels)
+
+			var taints []string
+			for _, taint := range w.Taints {
+				taints = append(taints, fmt.Sprintf("%s=%s:%s", pointer.SafeDeref(taint.Key), taint.Value, pointer.SafeDeref(taint.Effect)))
+			}
+
+			row = append(row, pointer.SafeDeref(w.MaxSurge), pointer.SafeDeref(w.MaxUnavailable), strings.Join(labels, "\n"), strings.Join(taints, "\n"))
+		}
+
+		rows = append(rows, row)
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
@@ -0,0 +1,49 @@
+package tableprinters
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// PostgresMaintenanceWindow is an upcoming maintenance window of a postgres database.
+type PostgresMaintenanceWindow struct {
+	ID          string    `json:"id" yaml:"id"`
+	Description string    `json:"description" yaml:"description"`
+	ProjectID   string    `json:"project" yaml:"project"`
+	Begin       time.Time `json:"begin" yaml:"begin"`
+	End         time.Time `json:"end" yaml:"end"`
+	// OverlappingClusters are the clusters of the same project, whose maintenance window overlaps with this window.
+	OverlappingClusters []string `json:"overlapping_clusters,omitempty" yaml:"overlapping_clusters,omitempty"`
+}
+
+func (t *TablePrinter) PostgresMaintenanceWindowTable(data []*PostgresMaintenanceWindow, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"ID", "Description", "Project", "Begin", "End", "Overlapping Clusters"}
+		rows   [][]string
+	)
+
+	for _, w := range data {
+		begin := w.Begin.Local()
+		end := w.End.Local()
+
+		endFormat := "15:04"
+		if end.YearDay() != begin.YearDay() {
+			endFormat = "Mon 2006-01-02 15:04"
+		}
+
+		rows = append(rows, []string{
+			w.ID,
+			w.Description,
+			w.ProjectID,
+			begin.Format("Mon 2006-01-02 15:04 MST"),
+			end.Format(endFormat),
+			color.RedString(strings.Join(w.OverlappingClusters, "\n")),
+		})
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
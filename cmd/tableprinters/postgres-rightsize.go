@@ -0,0 +1,91 @@
+package tableprinters
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+const (
+	PostgresRightsizeKeep     = "keep"
+	PostgresRightsizeDownsize = "downsize"
+	PostgresRightsizeUpsize   = "upsize"
+)
+
+// PostgresRightsizeRecommendation is the sizing recommendation for a postgres database based on its accounted usage.
+type PostgresRightsizeRecommendation struct {
+	ID                 string  `json:"id" yaml:"id"`
+	Description        string  `json:"description" yaml:"description"`
+	ProjectID          string  `json:"project" yaml:"project"`
+	CPU                string  `json:"cpu" yaml:"cpu"`
+	Buffer             string  `json:"buffer" yaml:"buffer"`
+	Memory             string  `json:"memory" yaml:"memory"`
+	Storage            string  `json:"storage" yaml:"storage"`
+	CPUUtilization     float64 `json:"cpu_utilization" yaml:"cpu_utilization"`
+	StorageUtilization float64 `json:"storage_utilization" yaml:"storage_utilization"`
+	RecommendedCPU     string  `json:"recommended_cpu" yaml:"recommended_cpu"`
+	RecommendedBuffer  string  `json:"recommended_buffer" yaml:"recommended_buffer"`
+	RecommendedMemory  string  `json:"recommended_memory" yaml:"recommended_memory"`
+	RecommendedStorage string  `json:"recommended_storage" yaml:"recommended_storage"`
+	Action             string  `json:"action" yaml:"action"`
+	// MonthlyCostDelta is only set if the prices are configured.
+	MonthlyCostDelta *float64 `json:"monthly_cost_delta,omitempty" yaml:"monthly_cost_delta,omitempty"`
+	Command          string   `json:"command,omitempty" yaml:"command,omitempty"`
+}
+
+func (t *TablePrinter) PostgresRightsizeRecommendationTable(data []*PostgresRightsizeRecommendation, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"ID", "Description", "Project", "CPU", "Buffer", "Storage", "Action", "Costs / Month"}
+		rows   [][]string
+	)
+
+	if wide {
+		header = append(header, "Memory", "Command")
+	}
+
+	change := func(current, recommended string, utilization *float64) string {
+		s := current
+		if utilization != nil {
+			s += fmt.Sprintf(" (%.0f%%)", *utilization*100)
+		}
+		if recommended != current {
+			s += " → " + recommended
+		}
+		return s
+	}
+
+	for _, r := range data {
+		action := r.Action
+		switch r.Action {
+		case PostgresRightsizeDownsize:
+			action = color.GreenString(r.Action)
+		case PostgresRightsizeUpsize:
+			action = color.YellowString(r.Action)
+		}
+
+		costs := ""
+		if r.MonthlyCostDelta != nil {
+			costs = fmt.Sprintf("%+.2f €", *r.MonthlyCostDelta)
+		}
+
+		row := []string{
+			r.ID,
+			r.Description,
+			r.ProjectID,
+			change(r.CPU, r.RecommendedCPU, &r.CPUUtilization),
+			change(r.Buffer, r.RecommendedBuffer, nil),
+			change(r.Storage, r.RecommendedStorage, &r.StorageUtilization),
+			action,
+			costs,
+		}
+		if wide {
+			row = append(row, change(r.Memory, r.RecommendedMemory, nil), r.Command)
+		}
+
+		rows = append(rows, row)
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
@@ -0,0 +1,72 @@
+package tableprinters
+
+const (
+	PostgresRolePrimary    = "primary"
+	PostgresRoleStandby    = "standby"
+	PostgresRoleStandalone = "standalone"
+)
+
+// PostgresTopologyNode is a database in the replication graph, standbys are attached to the primary they replicate from.
+type PostgresTopologyNode struct {
+	ID          string `json:"id" yaml:"id"`
+	Description string `json:"description" yaml:"description"`
+	ProjectID   string `json:"project" yaml:"project"`
+	Partition   string `json:"partition" yaml:"partition"`
+	Role        string `json:"role" yaml:"role"`
+	Synchronous bool   `json:"synchronous" yaml:"synchronous"`
+	// Upstream is the database a standby replicates from, it is set as well if the upstream is not part of the topology.
+	Upstream string                  `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Status   string                  `json:"status" yaml:"status"`
+	Address  string                  `json:"address,omitempty" yaml:"address,omitempty"`
+	Standbys []*PostgresTopologyNode `json:"standbys,omitempty" yaml:"standbys,omitempty"`
+}
+
+func (t *TablePrinter) PostgresTopologyTable(data []*PostgresTopologyNode, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"ID", "Description", "Project", "Partition", "Role", "Replication", "Status"}
+		rows   [][]string
+	)
+
+	if wide {
+		header = append(header, "Address")
+	}
+
+	var add func(n *PostgresTopologyNode, prefix, childPrefix string)
+	add = func(n *PostgresTopologyNode, prefix, childPrefix string) {
+		replication := ""
+		switch n.Role {
+		case PostgresRolePrimary:
+			replication = "async"
+			if n.Synchronous {
+				replication = "sync"
+			}
+		case PostgresRoleStandby:
+			// the upstream is only shown if it is not the parent in the tree
+			if prefix == "" && n.Upstream != "" {
+				replication = "from " + n.Upstream
+			}
+		}
+
+		row := []string{prefix + n.ID, n.Description, n.ProjectID, n.Partition, n.Role, replication, n.Status}
+		if wide {
+			row = append(row, n.Address)
+		}
+		rows = append(rows, row)
+
+		for i, s := range n.Standbys {
+			if i == len(n.Standbys)-1 {
+				add(s, childPrefix+"└─", childPrefix+"  ")
+			} else {
+				add(s, childPrefix+"├─", childPrefix+"│ ")
+			}
+		}
+	}
+
+	for _, n := range data {
+		add(n, "", "")
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
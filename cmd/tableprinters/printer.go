@@ -4,6 +4,7 @@ import (
 	"io"
 
 	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
 	"github.com/fi-ts/cloudctl/cmd/output"
 	"github.com/metal-stack/metal-lib/pkg/genericcli/printers"
 	"github.com/metal-stack/metal-lib/pkg/pointer"
@@ -46,6 +47,53 @@ func (t *TablePrinter) ToHeaderAndRows(data any, wide bool) ([]string, [][]strin
 	case *models.V1MachineReservationBillingUsageResponse:
 		return t.MachineReservationsBillingTable(d, wide)
 
+	// cluster worker groups
+	case *ClusterWorkerGroup:
+		return t.ClusterWorkerGroupTable(pointer.WrapInSlice(d), wide)
+	case []*ClusterWorkerGroup:
+		return t.ClusterWorkerGroupTable(d, wide)
+
+	// cluster upgrade plan
+	case []*ClusterUpgradeStep:
+		return t.ClusterUpgradeStepTable(d, wide)
+
+	// cluster kubeconfig contexts
+	case []*ClusterKubeconfigContext:
+		return t.ClusterKubeconfigContextTable(d, wide)
+
+	// cluster bulk operations
+	case []*ClusterBulkResult:
+		return t.ClusterBulkResultTable(d, wide)
+
+	// cluster machine exec
+	case []*ClusterMachineExecResult:
+		return t.ClusterMachineExecResultTable(d, wide)
+
+	// cluster reports
+	case []*ClusterVersionReport:
+		return t.ClusterVersionReportTable(d, wide)
+
+	// postgres topology
+	case []*PostgresTopologyNode:
+		return t.PostgresTopologyTable(d, wide)
+
+	// postgres maintenance windows
+	case []*PostgresMaintenanceWindow:
+		return t.PostgresMaintenanceWindowTable(d, wide)
+
+	// postgres rightsizing
+	case []*PostgresRightsizeRecommendation:
+		return t.PostgresRightsizeRecommendationTable(d, wide)
+
+	// s3 buckets and objects
+	case []*helper.S3Bucket:
+		return t.S3BucketTable(d, wide)
+	case []*helper.S3Object:
+		return t.S3ObjectTable(d, wide)
+	// s3 usage
+	case []*S3UserUsage:
+		return t.S3UserUsageTable(d, wide)
+
 	default:
 		// fallback to old printer for as long as the migration takes:
 		t.t.WithOut(io.Discard)
@@ -0,0 +1,104 @@
+package tableprinters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+)
+
+func (t *TablePrinter) S3BucketTable(data []*helper.S3Bucket, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"Name", "Created"}
+		rows   [][]string
+	)
+
+	for _, b := range data {
+		rows = append(rows, []string{b.Name, b.CreationDate.Format(time.RFC3339)})
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
+
+func (t *TablePrinter) S3ObjectTable(data []*helper.S3Object, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"Key", "Size", "Last Modified"}
+		rows   [][]string
+	)
+
+	if wide {
+		header = append(header, "ETag")
+	}
+
+	for _, o := range data {
+		row := []string{o.Key, helper.HumanizeSize(o.Size), o.LastModified.Format(time.RFC3339)}
+		if wide {
+			row = append(row, o.ETag)
+		}
+		rows = append(rows, row)
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
+
+// S3UserUsage is the number of buckets, objects and the stored data of an s3 user in comparison with its quota.
+type S3UserUsage struct {
+	ID         string `json:"id" yaml:"id"`
+	Tenant     string `json:"tenant" yaml:"tenant"`
+	ProjectID  string `json:"project" yaml:"project"`
+	Partition  string `json:"partition" yaml:"partition"`
+	Buckets    int64  `json:"buckets" yaml:"buckets"`
+	MaxBuckets int64  `json:"max_buckets" yaml:"max_buckets"`
+	Objects    int64  `json:"objects" yaml:"objects"`
+	// StoredGi is the average amount of stored data in gibibytes.
+	StoredGi float64 `json:"stored_gi" yaml:"stored_gi"`
+	// Costs are only set if the price for storage is configured.
+	Costs     *float64 `json:"costs,omitempty" yaml:"costs,omitempty"`
+	NearQuota bool     `json:"near_quota" yaml:"near_quota"`
+}
+
+func (t *TablePrinter) S3UserUsageTable(data []*S3UserUsage, wide bool) ([]string, [][]string, error) {
+	var (
+		header = []string{"ID", "Project", "Partition", "Buckets", "Objects", "Stored (Gi)", "Costs"}
+		rows   [][]string
+	)
+
+	if wide {
+		header = append([]string{"ID", "Tenant"}, header[1:]...)
+	}
+
+	for _, u := range data {
+		buckets := fmt.Sprintf("%d", u.Buckets)
+		if u.MaxBuckets > 0 {
+			buckets = fmt.Sprintf("%d/%d", u.Buckets, u.MaxBuckets)
+		}
+		switch {
+		case u.MaxBuckets > 0 && u.Buckets >= u.MaxBuckets:
+			buckets = color.RedString(buckets)
+		case u.NearQuota:
+			buckets = color.YellowString(buckets)
+		}
+
+		costs := ""
+		if u.Costs != nil {
+			costs = fmt.Sprintf("%.2f €", *u.Costs)
+		}
+
+		row := []string{u.ID}
+		if wide {
+			row = append(row, u.Tenant)
+		}
+		row = append(row, u.ProjectID, u.Partition, buckets, fmt.Sprintf("%d", u.Objects), fmt.Sprintf("%.2f", u.StoredGi), costs)
+
+		rows = append(rows, row)
+	}
+
+	t.t.DisableAutoWrap(true)
+
+	return header, rows, nil
+}
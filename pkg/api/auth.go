@@ -14,7 +14,12 @@ func GetAuthContext(kubeconfig string) (*auth.AuthContext, error) {
 	if err != nil {
 		return nil, err
 	}
-	authContext, err := auth.GetAuthContext(kubeconfig, FormatContextName(CloudContext, cs.CurrentContext))
+	return GetAuthContextByName(kubeconfig, cs.CurrentContext)
+}
+
+// GetAuthContextByName reads the AuthContext of the given cloudctl context from given kubeconfig
+func GetAuthContextByName(kubeconfig string, contextName string) (*auth.AuthContext, error) {
+	authContext, err := auth.GetAuthContext(kubeconfig, FormatContextName(CloudContext, contextName))
 	if err != nil {
 		return nil, err
 	}
@@ -25,6 +25,7 @@ type Context struct {
 	ClientID     string  `yaml:"client_id"`
 	ClientSecret string  `yaml:"client_secret"`
 	HMAC         *string `yaml:"hmac"`
+	RecordDir    string  `yaml:"record_dir"`
 }
 
 var defaultCtx = Context{